@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/audit"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testApp(t *testing.T) (*app, *audit.Store) {
+	t.Helper()
+	cfg := config.Default()
+	cfg.Auth.BcryptCost = bcrypt.MinCost
+	db := dbtest.SQLite(t)
+	log := audit.NewStore(db)
+	return &app{cfg: cfg, store: store.New(db), audit: log, json: true, now: time.Now}, log
+}
+
+// exec runs a command line and decodes its JSON output into out
+func exec(t *testing.T, a *app, out any, args ...string) error {
+	t.Helper()
+	var buf bytes.Buffer
+	a.out = &buf
+	err := a.run(context.Background(), args)
+	if err == nil && out != nil {
+		if jerr := json.Unmarshal(buf.Bytes(), out); jerr != nil {
+			t.Fatalf("%v: invalid JSON %q: %v", args, buf.String(), jerr)
+		}
+	}
+	return err
+}
+
+func TestUsers(t *testing.T) {
+	a, log := testApp(t)
+	ctx := context.Background()
+
+	var created userResult
+	if err := exec(t, a, &created, "users", "create", "Ada@Example.com", "-role", "user,admin"); err != nil {
+		t.Fatalf("users create failed: %v", err)
+	}
+	if created.User.Email != "ada@example.com" || created.User.Name != "ada" || !created.User.HasRole(auth.RoleAdmin) || len(created.Password) < minPasswordLength {
+		t.Fatalf("Expected a new admin with a generated password, got %+v", created)
+	}
+	stored, _ := a.store.GetUser(ctx, created.User.ID)
+	if auth.CheckPassword(stored.PasswordHash, created.Password) != nil {
+		t.Error("Expected the generated password to sign in")
+	}
+
+	tests := []struct {
+		name  string
+		args  []string
+		check func(u *models.User) bool
+	}{
+		{"revoke", []string{"users", "revoke", "ada@example.com", "admin"}, func(u *models.User) bool { return !u.HasRole(auth.RoleAdmin) }},
+		{"grant by id", []string{"users", "grant", "1", "admin"}, func(u *models.User) bool { return u.HasRole(auth.RoleAdmin) }},
+		{"disable", []string{"users", "disable", "1"}, func(u *models.User) bool { return u.Disabled() }},
+		{"enable", []string{"users", "enable", "ada@example.com"}, func(u *models.User) bool { return !u.Disabled() }},
+		{"reset password", []string{"users", "reset-password", "1", "-password", "new password"}, func(u *models.User) bool {
+			return auth.CheckPassword(u.PasswordHash, "new password") == nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := exec(t, a, nil, tt.args...); err != nil {
+				t.Fatalf("%v failed: %v", tt.args, err)
+			}
+			u, err := a.store.GetUser(ctx, created.User.ID)
+			if err != nil || !tt.check(u) {
+				t.Errorf("Unexpected account after %v: %+v %v", tt.args, u, err)
+			}
+		})
+	}
+
+	var users []*models.User
+	if err := exec(t, a, &users, "users", "list"); err != nil || len(users) != 1 {
+		t.Errorf("Expected one listed user, got %v %v", users, err)
+	}
+
+	events, _ := log.Query(ctx, audit.Filter{TargetType: audit.TargetUser, TargetID: "1"})
+	var actions []string
+	for _, e := range events {
+		actions = append(actions, e.Action)
+	}
+	want := []string{audit.ActionPasswordChanged, audit.ActionAccountEnabled, audit.ActionAccountDisabled,
+		audit.ActionRoleChanged, audit.ActionRoleChanged, audit.ActionAccountCreated}
+	if strings.Join(actions, " ") != strings.Join(want, " ") {
+		t.Errorf("Expected audit events %v, got %v", want, actions)
+	}
+	if !strings.HasPrefix(events[0].UserAgent, "cmd/admin") {
+		t.Errorf("Expected events attributed to the CLI, got %q", events[0].UserAgent)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	a, _ := testApp(t)
+	exec(t, a, nil, "users", "create", "-password", "long enough", "ada@example.com")
+
+	tests := []struct {
+		name  string
+		args  []string
+		usage bool
+	}{
+		{"unknown command", []string{"bogus"}, true},
+		{"unknown subcommand", []string{"users", "remove", "1"}, true},
+		{"unknown flag", []string{"users", "create", "-admin", "bob@example.com"}, true},
+		{"missing email", []string{"users", "create"}, true},
+		{"not an email", []string{"users", "create", "bob"}, false},
+		{"duplicate email", []string{"users", "create", "ada@example.com"}, false},
+		{"short password", []string{"users", "reset-password", "-password", "short", "1"}, false},
+		{"unknown role", []string{"users", "grant", "1", "root"}, false},
+		{"unknown user", []string{"users", "disable", "nobody@example.com"}, false},
+		{"long token", []string{"token", "-ttl", "2h", "1"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := exec(t, a, nil, tt.args...)
+			if err == nil {
+				t.Fatalf("Expected %v to fail", tt.args)
+			}
+			if errors.Is(err, errUsage) != tt.usage {
+				t.Errorf("Expected usage error %v, got %v", tt.usage, err)
+			}
+		})
+	}
+}
+
+func TestToken(t *testing.T) {
+	a, _ := testApp(t)
+	exec(t, a, nil, "users", "create", "ada@example.com")
+
+	var result tokenResult
+	if err := exec(t, a, &result, "token", "-ttl", "5m", "-scope", "habits:read", "ada@example.com"); err != nil {
+		t.Fatalf("token failed: %v", err)
+	}
+	tokens, _ := auth.NewTokenService(a.cfg.Auth)
+	claims, err := tokens.Parse(result.AccessToken)
+	if err != nil || claims.UserID != 1 || !claims.HasScope("habits:read") {
+		t.Fatalf("Expected a token for user 1, got %+v %v", claims, err)
+	}
+	if ttl := time.Until(result.ExpiresAt); ttl > 5*time.Minute || ttl < 4*time.Minute {
+		t.Errorf("Expected the token to expire in 5m, got %v", ttl)
+	}
+
+	exec(t, a, nil, "users", "disable", "1")
+	if err := exec(t, a, nil, "token", "1"); err == nil {
+		t.Error("Expected no token for a disabled account")
+	}
+}
+
+func TestSeed(t *testing.T) {
+	a, _ := testApp(t)
+	var result seedResult
+	if err := exec(t, a, &result, "seed", "-password", "demo password"); err != nil {
+		t.Fatalf("seed failed: %v", err)
+	}
+	if result.Habits != len(demoHabits) || result.CheckIns == 0 || result.Measurements == 0 || result.Password != "" {
+		t.Errorf("Expected demo data, got %+v", result)
+	}
+	habits, _ := a.store.ListHabits(context.Background(), result.User.ID, false)
+	if len(habits) != len(demoHabits) {
+		t.Errorf("Expected %d habits, got %d", len(demoHabits), len(habits))
+	}
+	if err := exec(t, a, nil, "seed"); err == nil {
+		t.Error("Expected seeding the same account twice to fail")
+	}
+}
+
+// failingStore fails check-ins after the first few
+type failingStore struct {
+	store.Store
+	checkIns int
+}
+
+func (s *failingStore) CreateCheckIn(ctx context.Context, c *models.CheckIn) error {
+	if s.checkIns++; s.checkIns > 3 {
+		return errors.New("disk full")
+	}
+	return s.Store.CreateCheckIn(ctx, c)
+}
+
+func TestSeedFailureLeavesNoAccount(t *testing.T) {
+	a, _ := testApp(t)
+	backing := a.store
+	a.store = &failingStore{Store: backing}
+	if err := exec(t, a, nil, "seed"); err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Fatalf("Expected seed to fail, got %v", err)
+	}
+	if _, err := backing.GetUserByEmail(context.Background(), "demo@example.com"); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("Expected the partial account to be removed, got %v", err)
+	}
+
+	a.store = backing
+	if err := exec(t, a, nil, "seed"); err != nil {
+		t.Errorf("Expected seeding to succeed after a failed attempt, got %v", err)
+	}
+}
+
+func TestPrintConfig(t *testing.T) {
+	a, _ := testApp(t)
+	a.cfg.Database.URL = "postgres://app:s3cret@db/app"
+	a.cfg.Auth.JWTSecret = "a-very-secret-signing-key"
+
+	for _, asJSON := range []bool{false, true} {
+		a.json = asJSON
+		var buf bytes.Buffer
+		a.out = &buf
+		if err := a.run(context.Background(), []string{"config"}); err != nil {
+			t.Fatalf("config failed: %v", err)
+		}
+		out := buf.String()
+		if strings.Contains(out, "s3cret") || strings.Contains(out, "a-very-secret") || !strings.Contains(out, "REDACTED") {
+			t.Errorf("Expected secrets to be redacted, got:\n%s", out)
+		}
+		if !strings.Contains(out, "15m0s") {
+			t.Errorf("Expected durations in config file notation, got:\n%s", out)
+		}
+	}
+}
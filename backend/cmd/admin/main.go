@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"os/user"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/audit"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+const usage = `Usage: go run ./cmd/admin [flags] COMMAND [ARGS]
+
+Commands:
+  users list                          list every account
+  users create [-name N] [-password P] [-role R] EMAIL
+                                      create an account; a password is generated unless given
+  users disable USER                  block sign-in and revoke the account's sessions
+  users enable USER                   allow a disabled account to sign in again
+  users grant USER ROLE...            add roles to an account
+  users revoke USER ROLE...           remove roles from an account
+  users reset-password [-password P] USER
+                                      set a new password and revoke the account's sessions
+  token [-ttl D] [-scope S] USER      mint an access token for debugging (at most 1h)
+  seed [-email E] [-password P]       create a demo account with habits and measurements
+  config                              print the effective configuration with secrets redacted
+
+USER is an account ID or email. Changes to accounts are recorded in the audit log.
+
+Flags:
+`
+
+// errUsage marks an error caused by invalid arguments
+var errUsage = errors.New("usage")
+
+// app runs commands against the configured database
+type app struct {
+	cfg   *config.Config
+	store store.Store
+	audit audit.Recorder
+	out   io.Writer
+	json  bool
+	now   func() time.Time
+}
+
+func main() {
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	config.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(os.Args[1:])
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadFrom(fs)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	a := &app{cfg: cfg, out: os.Stdout, json: *asJSON, now: time.Now}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Printing the configuration must work even when the database does not
+	if fs.Arg(0) != "config" {
+		db, err := database.Open(cfg.Database)
+		if err != nil {
+			log.Fatalf("Failed to connect to database: %v", err)
+		}
+		defer db.Close()
+		a.store, a.audit = store.New(db), audit.NewStore(db)
+	}
+
+	if err := a.run(ctx, fs.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// run dispatches a command line without the global flags
+func (a *app) run(ctx context.Context, args []string) error {
+	// Actions are attributed to the operator in the audit log
+	ctx = audit.WithRecorder(ctx, a.audit)
+	ctx = audit.WithSource(ctx, audit.Source{UserAgent: "cmd/admin (" + operator() + ")"})
+
+	switch args[0] {
+	case "users":
+		if len(args) < 2 {
+			return usageError("users needs a subcommand: list, create, disable, enable, grant, revoke or reset-password")
+		}
+		switch args[1] {
+		case "list":
+			return a.listUsers(ctx)
+		case "create":
+			return a.createUser(ctx, args[2:])
+		case "disable":
+			return a.setDisabled(ctx, args[2:], true)
+		case "enable":
+			return a.setDisabled(ctx, args[2:], false)
+		case "grant":
+			return a.changeRoles(ctx, args[2:], true)
+		case "revoke":
+			return a.changeRoles(ctx, args[2:], false)
+		case "reset-password":
+			return a.resetPassword(ctx, args[2:])
+		}
+		return usageError("unknown users subcommand %q", args[1])
+	case "token":
+		return a.mintToken(ctx, args[1:])
+	case "seed":
+		return a.seed(ctx, args[1:])
+	case "config":
+		return a.printConfig()
+	}
+	return usageError("unknown command %q", args[0])
+}
+
+// printConfig prints the effective configuration with secrets redacted
+func (a *app) printConfig() error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(a.cfg.Redacted()); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	b := buf.Bytes()
+	if a.json {
+		// Going through YAML keeps the config file's keys and durations
+		var doc map[string]any
+		if err := yaml.Unmarshal(b, &doc); err != nil {
+			return fmt.Errorf("encode config: %w", err)
+		}
+		doc["env"], doc["file"] = a.cfg.Env, a.cfg.File
+		return a.printJSON(doc)
+	}
+	file := a.cfg.File
+	if file == "" {
+		file = "none, defaults and environment only"
+	}
+	fmt.Fprintf(a.out, "# env: %s\n# file: %s\n%s", a.cfg.Env, file, b)
+	return nil
+}
+
+// newFlagSet returns a flag set for a subcommand that reports errors instead
+// of exiting
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseArgs parses flags given before, between or after the positional
+// arguments and returns the latter
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, usageError("%s: %v", fs.Name(), err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// printJSON writes v as indented JSON
+func (a *app) printJSON(v any) error {
+	enc := json.NewEncoder(a.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// printTable writes rows under a header as aligned columns
+func (a *app) printTable(header []string, rows [][]string) {
+	w := tabwriter.NewWriter(a.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+}
+
+// usageError returns an error that makes the command exit with status 2
+func usageError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, args...))
+}
+
+// operator names the OS user running the command
+func operator() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return "unknown"
+}
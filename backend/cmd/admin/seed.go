@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/audit"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+)
+
+// seedDays is how much history the demo account gets
+const seedDays = 28
+
+// seedResult is printed by seed
+type seedResult struct {
+	userResult
+	Habits       int `json:"habits"`
+	CheckIns     int `json:"check_ins"`
+	Measurements int `json:"measurements"`
+}
+
+// demoHabits are created for the demo account
+var demoHabits = []models.Habit{
+	{Name: "Drink water", Description: "Eight glasses a day", Schedule: models.HabitSchedule{Kind: models.ScheduleDaily}},
+	{Name: "Meditate", Description: "Ten minutes after waking up", Schedule: models.HabitSchedule{Kind: models.ScheduleWeekdays, Weekdays: []string{"mon", "wed", "fri"}}},
+	{Name: "Run", Description: "At least 5 km", Schedule: models.HabitSchedule{Kind: models.ScheduleWeekly, TimesPerWeek: 3}},
+}
+
+// seed creates a demo account with four weeks of habits, check-ins and
+// measurements. The values are fixed so every seeded database looks alike.
+// It fails when the account already exists, and removes the account again
+// when seeding it fails part way.
+func (a *app) seed(ctx context.Context, args []string) error {
+	fs := newFlagSet("seed")
+	email := fs.String("email", "demo@example.com", "email of the demo account")
+	password := fs.String("password", "", "password of the demo account (default: generated)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 0 {
+		return usageError("seed takes no arguments")
+	}
+
+	if _, err := a.store.GetUserByEmail(ctx, normalizeEmail(*email)); !errors.Is(err, store.ErrNotFound) {
+		if err == nil {
+			return fmt.Errorf("account %s already exists; seed another -email or delete it first", *email)
+		}
+		return err
+	}
+	u := &models.User{Email: normalizeEmail(*email), Name: "Demo User", Roles: []string{auth.RoleUser}, TimeZone: "Europe/Moscow"}
+	result := seedResult{userResult: userResult{User: u}}
+	if u.PasswordHash, result.Password, err = a.hashPassword(*password); err != nil {
+		return err
+	}
+	if err := a.store.CreateUser(ctx, u); err != nil {
+		return err
+	}
+	if err := a.seedData(ctx, u, &result); err != nil {
+		// Leave nothing half-seeded behind so the command can be run again;
+		// the habits, check-ins and measurements cascade with the account
+		if derr := a.store.DeleteUser(context.WithoutCancel(ctx), u.ID); derr != nil {
+			return errors.Join(err, fmt.Errorf("remove partial account %s: %w", u.Email, derr))
+		}
+		return err
+	}
+	a.record(ctx, audit.ActionAccountCreated, u, map[string]any{"roles": u.Roles, "seed": true})
+
+	if a.json {
+		return a.printJSON(result)
+	}
+	if err := a.printUser(result.userResult); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "🌱 Seeded %d habits, %d check-ins and %d measurements\n", result.Habits, result.CheckIns, result.Measurements)
+	return nil
+}
+
+// seedData gives the new demo account u its habits, check-ins and
+// measurements, counting them in result
+func (a *app) seedData(ctx context.Context, u *models.User, result *seedResult) error {
+	today := models.DateOf(a.now().In(u.Location()))
+	start := today.AddDays(-seedDays)
+	for i, demo := range demoHabits {
+		habit := demo
+		habit.UserID, habit.StartDate = u.ID, start
+		if err := a.store.CreateHabit(ctx, &habit); err != nil {
+			return err
+		}
+		result.Habits++
+		for day := start; day.Before(today); day = day.AddDays(1) {
+			if !seedCheckIn(i, day, today.DaysSince(day)) {
+				continue
+			}
+			if err := a.store.CreateCheckIn(ctx, &models.CheckIn{HabitID: habit.ID, Date: day}); err != nil {
+				return err
+			}
+			result.CheckIns++
+		}
+	}
+
+	var ms []*models.Measurement
+	for n := 1; n <= seedDays; n++ {
+		day := today.AddDays(-n).In(u.Location())
+		add := func(metric string, hour int, value float64) {
+			ms = append(ms, &models.Measurement{
+				ClientID:   fmt.Sprintf("seed-%s-%d-%d", metric, n, hour),
+				Metric:     metric,
+				Value:      value,
+				RecordedAt: day.Add(time.Duration(hour) * time.Hour),
+			})
+		}
+		add(models.MetricSleep, 7, float64(390+n%4*25))
+		for hour := 9; hour <= 18; hour += 3 {
+			add(models.MetricWater, hour, 500)
+		}
+		add(models.MetricHeartRate, 12, float64(62+n%7))
+		add(models.MetricMood, 20, float64(5+n%5))
+		add(models.MetricSteps, 22, float64(6000+n%6*900))
+	}
+	var err error
+	result.Measurements, err = a.store.AddMeasurements(ctx, u.ID, ms)
+	return err
+}
+
+// seedCheckIn decides whether the demo habit with the given index was done
+// on day, ago days before today. The pattern leaves streaks and gaps to show.
+func seedCheckIn(habit int, day models.Date, ago int) bool {
+	switch habit {
+	case 0:
+		return ago%6 != 0 || ago < 6
+	case 1:
+		wd := day.Weekday()
+		return (wd == time.Monday || wd == time.Wednesday || wd == time.Friday) && ago != 12
+	default:
+		wd := day.Weekday()
+		return wd == time.Tuesday || wd == time.Thursday || (wd == time.Saturday && ago > 7)
+	}
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/audit"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+)
+
+// Limits of operator-supplied values, matching the API
+const (
+	minPasswordLength = 8
+	maxTokenTTL       = time.Hour
+)
+
+// knownRoles are the roles the API checks for
+var knownRoles = []string{auth.RoleUser, auth.RoleAdmin}
+
+// userResult is printed after a command changed an account. Password is only
+// set when it was generated, since it is shown this once.
+type userResult struct {
+	User     *models.User `json:"user"`
+	Password string       `json:"password,omitempty"`
+}
+
+// tokenResult is printed by mintToken
+type tokenResult struct {
+	AccessToken string    `json:"access_token"`
+	TokenType   string    `json:"token_type"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// listUsers prints every account
+func (a *app) listUsers(ctx context.Context) error {
+	users, err := a.store.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+	if a.json {
+		if users == nil {
+			users = []*models.User{}
+		}
+		return a.printJSON(users)
+	}
+	rows := make([][]string, 0, len(users))
+	for _, u := range users {
+		rows = append(rows, userRow(u))
+	}
+	a.printTable(userHeader, rows)
+	return nil
+}
+
+// createUser creates an account
+func (a *app) createUser(ctx context.Context, args []string) error {
+	fs := newFlagSet("users create")
+	name := fs.String("name", "", "display name (default: the part of the email before @)")
+	password := fs.String("password", "", "initial password (default: generated)")
+	roles := fs.String("role", auth.RoleUser, "comma-separated roles")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError("users create needs exactly one EMAIL")
+	}
+
+	u := &models.User{Email: normalizeEmail(args[0]), Name: *name, Roles: splitRoles(*roles)}
+	local, domain, ok := strings.Cut(u.Email, "@")
+	if !ok || local == "" || domain == "" {
+		return fmt.Errorf("%q is not an email address", args[0])
+	}
+	if u.Name == "" {
+		u.Name = local
+	}
+	if err := checkRoles(u.Roles); err != nil {
+		return err
+	}
+	result := userResult{User: u}
+	if u.PasswordHash, result.Password, err = a.hashPassword(*password); err != nil {
+		return err
+	}
+	if err := a.store.CreateUser(ctx, u); err != nil {
+		return err
+	}
+	a.record(ctx, audit.ActionAccountCreated, u, map[string]any{"roles": u.Roles})
+	return a.printUser(result)
+}
+
+// setDisabled disables or re-enables an account. Disabling revokes the
+// refresh tokens; access tokens already issued expire on their own.
+func (a *app) setDisabled(ctx context.Context, args []string, disable bool) error {
+	if len(args) != 1 {
+		return usageError("expected exactly one USER")
+	}
+	u, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if u.Disabled() == disable {
+		return a.printUser(userResult{User: u})
+	}
+
+	action := audit.ActionAccountEnabled
+	u.DisabledAt = nil
+	if disable {
+		now := a.now().UTC()
+		action, u.DisabledAt = audit.ActionAccountDisabled, &now
+	}
+	if err := a.store.UpdateUser(ctx, u); err != nil {
+		return err
+	}
+	if disable {
+		if err := a.store.RevokeUserRefreshTokens(ctx, u.ID, a.now()); err != nil {
+			return err
+		}
+	}
+	a.record(ctx, action, u, nil)
+	return a.printUser(userResult{User: u})
+}
+
+// changeRoles grants or revokes roles
+func (a *app) changeRoles(ctx context.Context, args []string, grant bool) error {
+	if len(args) < 2 {
+		return usageError("expected USER and at least one ROLE")
+	}
+	roles := args[1:]
+	if err := checkRoles(roles); err != nil {
+		return err
+	}
+	u, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	before := slices.Clone(u.Roles)
+	for _, role := range roles {
+		has := u.HasRole(role)
+		switch {
+		case grant && !has:
+			u.Roles = append(u.Roles, role)
+		case !grant && has:
+			u.Roles = slices.DeleteFunc(u.Roles, func(r string) bool { return r == role })
+		}
+	}
+	if slices.Equal(before, u.Roles) {
+		return a.printUser(userResult{User: u})
+	}
+	if err := a.store.UpdateUser(ctx, u); err != nil {
+		return err
+	}
+	a.record(ctx, audit.ActionRoleChanged, u, map[string]any{"from": before, "to": u.Roles})
+	return a.printUser(userResult{User: u})
+}
+
+// resetPassword sets a new password and signs out every session
+func (a *app) resetPassword(ctx context.Context, args []string) error {
+	fs := newFlagSet("users reset-password")
+	password := fs.String("password", "", "new password (default: generated)")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError("users reset-password needs exactly one USER")
+	}
+	u, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	result := userResult{User: u}
+	if u.PasswordHash, result.Password, err = a.hashPassword(*password); err != nil {
+		return err
+	}
+	if err := a.store.UpdateUser(ctx, u); err != nil {
+		return err
+	}
+	if err := a.store.RevokeUserRefreshTokens(ctx, u.ID, a.now()); err != nil {
+		return err
+	}
+	a.record(ctx, audit.ActionPasswordChanged, u, map[string]any{"reset": true})
+	return a.printUser(result)
+}
+
+// mintToken issues a short-lived access token for an account
+func (a *app) mintToken(ctx context.Context, args []string) error {
+	fs := newFlagSet("token")
+	ttl := fs.Duration("ttl", 15*time.Minute, "token lifetime, at most 1h")
+	scope := fs.String("scope", "", "space-separated scopes to restrict the token to")
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 {
+		return usageError("token needs exactly one USER")
+	}
+	if *ttl <= 0 || *ttl > maxTokenTTL {
+		return usageError("-ttl must be positive and at most %s", maxTokenTTL)
+	}
+	u, err := a.findUser(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if u.Disabled() {
+		return fmt.Errorf("account %d is disabled", u.ID)
+	}
+
+	tokens, err := auth.NewTokenService(a.cfg.Auth)
+	if err != nil {
+		return err
+	}
+	token, expiresAt, err := tokens.IssueWithTTL(auth.Claims{UserID: u.ID, Email: u.Email, Roles: u.Roles, Scope: *scope}, *ttl)
+	if err != nil {
+		return err
+	}
+	a.record(ctx, audit.ActionTokenIssued, u, map[string]any{"ttl": ttl.String(), "scope": *scope})
+
+	result := tokenResult{AccessToken: token, TokenType: "Bearer", ExpiresAt: expiresAt}
+	if a.json {
+		return a.printJSON(result)
+	}
+	fmt.Fprintln(a.out, result.AccessToken)
+	return nil
+}
+
+// findUser looks up an account by ID or email
+func (a *app) findUser(ctx context.Context, ref string) (*models.User, error) {
+	var u *models.User
+	var err error
+	if strings.Contains(ref, "@") {
+		u, err = a.store.GetUserByEmail(ctx, normalizeEmail(ref))
+	} else {
+		id, perr := strconv.ParseInt(ref, 10, 64)
+		if perr != nil {
+			return nil, usageError("%q is neither an account ID nor an email", ref)
+		}
+		u, err = a.store.GetUser(ctx, id)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("no account %q", ref)
+	}
+	return u, err
+}
+
+// hashPassword hashes password, generating one when it is empty. The
+// generated password is returned so it can be shown to the operator.
+func (a *app) hashPassword(password string) (hash, generated string, err error) {
+	if password == "" {
+		b := make([]byte, 18)
+		if _, err := rand.Read(b); err != nil {
+			return "", "", fmt.Errorf("generate password: %w", err)
+		}
+		password = base64.RawURLEncoding.EncodeToString(b)
+		generated = password
+	}
+	if len(password) < minPasswordLength {
+		return "", "", fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	hash, err = auth.HashPassword(password, a.cfg.Auth.BcryptCost)
+	return hash, generated, err
+}
+
+// record appends an audit event about u. A failure is reported but does not
+// undo the change.
+func (a *app) record(ctx context.Context, action string, u *models.User, details map[string]any) {
+	e := audit.Event{Action: action, TargetType: audit.TargetUser, TargetID: strconv.FormatInt(u.ID, 10), Details: details}
+	if err := audit.Record(ctx, e); err != nil {
+		log.Printf("⚠️  audit record failed: %v", err)
+	}
+}
+
+// printUser prints the outcome of a command on an account
+func (a *app) printUser(r userResult) error {
+	if a.json {
+		return a.printJSON(r)
+	}
+	a.printTable(userHeader, [][]string{userRow(r.User)})
+	if r.Password != "" {
+		fmt.Fprintf(a.out, "\n🔑 Generated password: %s\n", r.Password)
+	}
+	return nil
+}
+
+var userHeader = []string{"ID", "EMAIL", "NAME", "ROLES", "STATUS", "CREATED AT"}
+
+// userRow returns the table cells of an account
+func userRow(u *models.User) []string {
+	status := "active"
+	if u.Disabled() {
+		status = "disabled since " + u.DisabledAt.Format("2006-01-02 15:04")
+	}
+	return []string{
+		strconv.FormatInt(u.ID, 10), u.Email, u.Name, strings.Join(u.Roles, ","), status,
+		u.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
+
+// checkRoles rejects roles the API does not know
+func checkRoles(roles []string) error {
+	for _, role := range roles {
+		if !slices.Contains(knownRoles, role) {
+			return fmt.Errorf("unknown role %q, expected one of %s", role, strings.Join(knownRoles, ", "))
+		}
+	}
+	return nil
+}
+
+// splitRoles splits a comma-separated list of roles
+func splitRoles(s string) []string {
+	var roles []string
+	for _, role := range strings.Split(s, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// normalizeEmail lowercases and trims an email the way the API does
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -1,41 +1,152 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 
 	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/migrate"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/migrations"
 )
 
+const usage = `Usage: go run cmd/migrate/main.go [flags] COMMAND
+
+Commands:
+  up              apply all pending migrations
+  down [N]        roll back the last N migrations (default 1)
+  status          list migrations and whether they are applied
+  redo            roll back and re-apply the latest migration
+  goto VERSION    migrate up or down to VERSION (0 rolls back everything)
+  unlock          release the lock left behind by a crashed migrator
+  create NAME     write a new empty migration pair to -dir
+
+Flags:
+`
+
 func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("Usage: go run cmd/migrate/main.go [up|down]")
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	dir := fs.String("dir", "migrations", "directory new migrations are created in")
+	config.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), usage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(os.Args[1:])
+
+	args := fs.Args()
+	if len(args) < 1 {
+		fs.Usage()
+		os.Exit(2)
 	}
+	command := args[0]
 
-	cfg := config.Load()
-	direction := os.Args[1]
+	if command == "create" {
+		if len(args) != 2 {
+			log.Fatal("Usage: go run cmd/migrate/main.go create NAME")
+		}
+		up, down, err := migrate.Create(*dir, args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("📝 Created %s\n📝 Created %s\n", up, down)
+		return
+	}
+
+	cfg, err := config.LoadFrom(fs)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
 
-	switch direction {
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var steps []migrate.Step
+	switch command {
 	case "up":
-		runMigrationsUp(cfg)
+		steps, err = m.Up(ctx)
 	case "down":
-		runMigrationsDown(cfg)
+		n := 1
+		if len(args) > 1 {
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				log.Fatalf("Invalid count %q: must be a positive integer", args[1])
+			}
+		}
+		steps, err = m.Down(ctx, n)
+	case "redo":
+		steps, err = m.Redo(ctx)
+	case "goto":
+		if len(args) != 2 {
+			log.Fatal("Usage: go run cmd/migrate/main.go goto VERSION")
+		}
+		version, perr := strconv.ParseInt(args[1], 10, 64)
+		if perr != nil || version < 0 {
+			log.Fatalf("Invalid version %q", args[1])
+		}
+		steps, err = m.Goto(ctx, version)
+	case "status":
+		printStatus(ctx, m)
+		return
+	case "unlock":
+		released, err := m.Unlock(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if released {
+			fmt.Println("🔓 Migration lock released")
+		} else {
+			fmt.Println("✅ No migration lock was held")
+		}
+		return
 	default:
-		log.Fatal("Invalid direction. Use 'up' or 'down'")
+		log.Fatalf("Unknown command %q. Use up, down, status, redo, goto, unlock or create", command)
 	}
-}
 
-func runMigrationsUp(cfg *config.Config) {
-	fmt.Printf("🔄 Running migrations UP against %s\n", cfg.DatabaseURL)
-	// TODO: Implement actual migration logic
-	// This would typically use a migration library like golang-migrate
-	fmt.Println("✅ Migrations completed successfully")
+	for _, step := range steps {
+		arrow := "⬆️ "
+		if step.Direction == migrate.Down {
+			arrow = "⬇️ "
+		}
+		fmt.Printf("%s %04d_%s\n", arrow, step.Version, step.Name)
+	}
+	if err != nil {
+		log.Fatalf("❌ Migration failed: %v", err)
+	}
+	if len(steps) == 0 {
+		fmt.Println("✅ Nothing to migrate")
+		return
+	}
+	fmt.Printf("✅ %d migration(s) completed successfully\n", len(steps))
 }
 
-func runMigrationsDown(cfg *config.Config) {
-	fmt.Printf("🔄 Running migrations DOWN against %s\n", cfg.DatabaseURL)
-	// TODO: Implement actual migration logic
-	// This would typically use a migration library like golang-migrate
-	fmt.Println("✅ Migrations rollback completed successfully")
+func printStatus(ctx context.Context, m *migrate.Migrator) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%-8s %-40s %-9s %s\n", "VERSION", "NAME", "STATE", "APPLIED AT")
+	for _, s := range statuses {
+		appliedAt := "-"
+		if !s.AppliedAt.IsZero() {
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%-8d %-40s %-9s %s\n", s.Version, s.Name, s.State, appliedAt)
+	}
 }
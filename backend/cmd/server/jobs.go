@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/idempotency"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/jobs"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+)
+
+// Built-in job kinds
+const (
+	jobPurgeRefreshTokens   = "refresh_tokens.purge"
+	jobPurgeIdempotencyKeys = "idempotency_keys.purge"
+)
+
+// newScheduler creates the job scheduler with the built-in handlers
+func newScheduler(cfg *config.Config, db *database.DB, st store.Store, logger *slog.Logger) *jobs.Scheduler {
+	scheduler := jobs.New(db, cfg.Jobs, logger, nil)
+	scheduler.Handle(jobPurgeRefreshTokens, func(ctx context.Context, j *jobs.Job) error {
+		n, err := st.DeleteExpiredRefreshTokens(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		logger.Info("purged expired refresh tokens", "count", n)
+		return nil
+	})
+	replays := idempotency.NewSQLStore(db)
+	scheduler.Handle(jobPurgeIdempotencyKeys, func(ctx context.Context, j *jobs.Job) error {
+		n, err := replays.DeleteExpired(ctx, time.Now())
+		if err != nil {
+			return err
+		}
+		logger.Info("purged expired idempotency keys", "count", n)
+		return nil
+	})
+	return scheduler
+}
+
+// builtinJobs are the recurring jobs every deployment runs. They are
+// enqueued at startup with EnqueueOnce, so a deploy neither duplicates them
+// nor resets their next run.
+var builtinJobs = []jobs.Spec{
+	{Kind: jobPurgeRefreshTokens, Key: jobPurgeRefreshTokens, Schedule: "0 3 * * *"},
+	{Kind: jobPurgeIdempotencyKeys, Key: jobPurgeIdempotencyKeys, Schedule: "30 * * * *"},
+}
+
+// startScheduler schedules the built-in jobs and starts polling
+func startScheduler(scheduler *jobs.Scheduler) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for _, spec := range builtinJobs {
+			if err := scheduler.EnqueueOnce(ctx, spec); err != nil {
+				return err
+			}
+		}
+		return scheduler.Start(ctx)
+	}
+}
@@ -2,72 +2,167 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/audit"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
 	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
-	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/handlers"
-	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/features"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/health"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/idempotency"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/lifecycle"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/logging"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/metrics"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/migrate"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/migrations"
 )
 
 func main() {
 	// Load configuration
-	cfg := config.Load()
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	checkOpenAPI := fs.Bool("check-openapi", false, "verify every registered route is in the OpenAPI spec and exit")
+	config.RegisterFlags(fs)
+	fs.Parse(os.Args[1:])
 
-	// Initialize Gin router
-	if cfg.Env == "production" {
+	cfg, err := config.LoadFrom(fs)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	if *checkOpenAPI {
 		gin.SetMode(gin.ReleaseMode)
+		router, spec := newRouter(cfg, dependencies{metrics: metrics.New()})
+		if err := spec.Check(router.Routes()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		fmt.Printf("✅ All %d routes are documented\n", len(router.Routes()))
+		return
 	}
 
-	router := gin.New()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
 
-	// Add middleware
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
-	router.Use(middleware.CORS())
+	// Initialize structured logging
+	logger, err := logging.New(cfg.Logging, os.Stdout)
+	if err != nil {
+		log.Fatalf("Failed to configure logging: %v", err)
+	}
+	slog.SetDefault(logger)
 
-	// Health check endpoint
-	router.GET("/health", handlers.HealthCheck)
+	// Connect to the database
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
 
-	// API routes
-	api := router.Group("/api/v1")
-	{
-		api.GET("/ping", handlers.Ping)
-		// Add more routes as needed
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		log.Fatalf("Failed to load migrations: %v", err)
 	}
 
-	// Create HTTP server
-	server := &http.Server{
-		Addr:    ":" + cfg.Port,
-		Handler: router,
+	// Register readiness checks
+	checks := health.NewRegistry(cfg.Health.CheckTimeout)
+	checks.Register("database", health.DatabaseChecker(db.DB))
+	checks.Register("migrations", health.MigrationChecker(migrator))
+	checks.Register("disk", health.DiskSpaceChecker(cfg.Health.DiskPath, uint64(cfg.Health.MinFreeDisk)<<20))
+	for name, addr := range cfg.Health.GRPCServices {
+		checks.Register("grpc:"+name, health.GRPCChecker(addr, ""))
+	}
+
+	// Access token verification
+	tokens, err := auth.NewTokenService(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Failed to configure authentication: %v", err)
 	}
 
-	// Start server in a goroutine
-	go func() {
-		log.Printf("🚀 Server starting on port %s", cfg.Port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+	// Initialize Gin router
+	if cfg.IsProduction() {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	st := store.New(db)
+	m := metrics.New()
+	var replays idempotency.Store = idempotency.NewMemoryStore()
+	if cfg.Idempotency.Store == config.IdempotencyStoreSQL {
+		replays = idempotency.NewSQLStore(db)
+	}
+	// Feature flags are re-read from the config file without a restart
+	flags := features.New(cfg.Features, func() (config.FeaturesConfig, error) {
+		next, err := config.LoadFrom(fs)
+		if err == nil {
+			err = next.Validate()
 		}
-	}()
+		if err != nil {
+			return config.FeaturesConfig{}, err
+		}
+		return next.Features, nil
+	}, logger)
+	router, _ := newRouter(cfg, dependencies{
+		logger:  logger,
+		metrics: m,
+		checks:  checks,
+		tokens:  tokens,
+		replays: replays,
+		audit:   audit.NewStore(db),
+		flags:   flags,
+		store:   st,
+	})
+
+	// Components are started in dependency order and stopped in reverse
+	manager := lifecycle.New(logger, cfg.Server.DrainDelay)
+	checks.Register("lifecycle", manager)
+	manager.Register(lifecycle.Component{
+		Name: "database",
+		Stop: func(context.Context) error { return db.Close() },
+	})
+
+	// Background jobs, leased so each run happens on one replica
+	if cfg.Jobs.Enabled {
+		scheduler := newScheduler(cfg, db, st, logger)
+		component := scheduler.Component("jobs", "database")
+		component.Start = startScheduler(scheduler)
+		manager.Register(component)
+	}
 
-	// Wait for interrupt signal to gracefully shutdown the server
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	log.Println("🛑 Shutting down server...")
+	// Reload feature flags on SIGHUP and when the config file changes
+	manager.Register(flags.Component("features", cfg.File, cfg.Features.WatchInterval))
 
-	// Give outstanding requests 10 seconds to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// Create HTTP server
+	server := &http.Server{
+		Addr:         ":" + cfg.Server.Port,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+	}
+	manager.Register(manager.HTTPServer("http", server, "database"))
 
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+	// Metrics on a separate admin port, when configured
+	if cfg.Metrics.Enabled && cfg.Metrics.AdminPort != "" {
+		mux := http.NewServeMux()
+		mux.Handle(cfg.Metrics.Path, m.Handler())
+		adminServer := &http.Server{Addr: ":" + cfg.Metrics.AdminPort, Handler: mux}
+		manager.Register(manager.HTTPServer("admin-http", adminServer))
 	}
 
+	// Run until SIGINT/SIGTERM, then drain and stop within the shutdown timeout
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("🚀 Server starting on port %s", cfg.Server.Port)
+	if err := manager.Run(ctx, cfg.Server.ShutdownTimeout); err != nil {
+		log.Fatalf("❌ Server stopped with error: %v", err)
+	}
 	log.Println("✅ Server exited")
 }
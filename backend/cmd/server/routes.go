@@ -0,0 +1,369 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/audit"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/features"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/handlers"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/health"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/idempotency"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/metrics"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/openapi"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/ratelimit"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/version"
+)
+
+// dependencies bundles the services the HTTP layer is built from
+type dependencies struct {
+	logger  *slog.Logger
+	metrics *metrics.Metrics
+	checks  *health.Registry
+	tokens  *auth.TokenService
+	limits  ratelimit.Store
+	replays idempotency.Store
+	audit   *audit.Store
+	flags   *features.Flags
+	store   store.Store
+}
+
+// newRouter registers every route together with its OpenAPI documentation
+func newRouter(cfg *config.Config, deps dependencies) (*gin.Engine, *openapi.Spec) {
+	if deps.logger == nil {
+		deps.logger = slog.Default()
+	}
+	if deps.limits == nil {
+		deps.limits = ratelimit.NewMemoryStore(cfg.RateLimit.IdleTimeout)
+	}
+	rateLimit := func(policy string) gin.HandlerFunc {
+		p, ok := cfg.RateLimit.Policies[policy]
+		if !cfg.RateLimit.Enabled || !ok {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return middleware.RateLimit(deps.limits, policy, p)
+	}
+	if deps.replays == nil {
+		deps.replays = idempotency.NewMemoryStore()
+	}
+	if deps.flags == nil {
+		deps.flags = features.New(cfg.Features, nil, deps.logger)
+	}
+	idempotent := func(c *gin.Context) { c.Next() }
+	if cfg.Idempotency.Enabled {
+		idempotent = middleware.Idempotency(deps.replays, cfg.Idempotency)
+	}
+
+	router := gin.New()
+	// Gin trusts every proxy by default, which would let any client pick its
+	// IP for rate limiting and the logs through X-Forwarded-For
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		deps.logger.Error("Ignoring invalid trusted proxies", slog.Any("error", err))
+		router.SetTrustedProxies(nil)
+	}
+	spec := openapi.New(openapi.Info{
+		Title:       "Course Backend API",
+		Description: "REST API of the sum25 Go + Flutter course backend.",
+		Version:     version.Get().Version,
+	})
+	routes := spec.Wrap(&router.RouterGroup)
+	router.NoRoute(func(c *gin.Context) {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "no route matches "+c.Request.URL.Path))
+	})
+
+	// Add middleware
+	router.Use(middleware.RequestID())
+	if cfg.Metrics.Enabled {
+		router.Use(middleware.Metrics(deps.metrics))
+	}
+	router.Use(middleware.RequestLogger(deps.logger))
+	if deps.audit != nil {
+		router.Use(middleware.Audit(deps.audit))
+	}
+	router.Use(gin.Recovery())
+	if cfg.Responses.Compress {
+		router.Use(middleware.Compress(cfg.Responses.CompressMinSize))
+	}
+	if cfg.Responses.ETags {
+		router.Use(middleware.Conditional())
+	}
+	router.Use(middleware.Errors(!cfg.IsProduction()))
+	router.Use(middleware.CORS(cfg.CORS))
+
+	// Health check endpoints
+	healthRoutes := routes.WithTags("health")
+	live := openapi.Operation{
+		Summary:   "Liveness probe",
+		Responses: map[int]any{http.StatusOK: handlers.LivenessResponse{}},
+	}
+	healthRoutes.GET("/health", openapi.Operation{Hidden: true}, handlers.Liveness)
+	healthRoutes.GET("/health/live", live, handlers.Liveness)
+	healthRoutes.GET("/health/ready", openapi.Operation{
+		Summary:     "Readiness probe",
+		Description: "Runs every dependency check concurrently; answers 503 when any check fails.",
+		Responses: map[int]any{
+			http.StatusOK:                 handlers.ReadinessResponse{},
+			http.StatusServiceUnavailable: handlers.ReadinessResponse{},
+		},
+	}, handlers.Readiness(deps.checks))
+
+	// Metrics endpoint, unless it is served on the admin port
+	if cfg.Metrics.Enabled && cfg.Metrics.AdminPort == "" {
+		routes.GET(cfg.Metrics.Path, openapi.Operation{Hidden: true}, gin.WrapH(deps.metrics.Handler()))
+	}
+
+	// API documentation
+	routes.GET("/openapi.json", openapi.Operation{Hidden: true}, spec.JSONHandler())
+	routes.GET("/docs", openapi.Operation{Hidden: true}, openapi.UIHandler("Course Backend API", "/openapi.json", "/docs/assets"))
+	routes.GET("/docs/assets/:file", openapi.Operation{Hidden: true}, openapi.AssetsHandler())
+
+	// API routes
+	api := routes.Group("/api/v1", rateLimit("api"))
+	{
+		api.GET("/ping", openapi.Operation{
+			Summary:   "Connectivity check",
+			Tags:      []string{"system"},
+			Responses: map[int]any{http.StatusOK: handlers.PingResponse{}},
+		}, handlers.Ping)
+	}
+
+	accounts := handlers.NewAccountHandler(deps.store, deps.store, deps.tokens, cfg.Auth)
+	habits := handlers.NewHabitHandler(deps.store, deps.store)
+	measurements := handlers.NewMeasurementHandler(deps.store, deps.store)
+	auditLog := handlers.NewAuditHandler(deps.audit)
+	authRoutes := api.Group("/auth", rateLimit("auth")).WithTags("auth")
+	{
+		authRoutes.POST("/register", openapi.Operation{
+			Summary: "Create an account",
+			Request: handlers.RegisterRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             handlers.TokenResponse{},
+				http.StatusBadRequest:          apperr.Problem{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+				http.StatusConflict:            apperr.Problem{},
+			},
+		}, accounts.Register)
+		authRoutes.POST("/login", openapi.Operation{
+			Summary: "Sign in with email and password",
+			Request: handlers.LoginRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  handlers.TokenResponse{},
+				http.StatusUnauthorized:        apperr.Problem{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+			},
+		}, accounts.Login)
+		authRoutes.POST("/refresh", openapi.Operation{
+			Summary:     "Rotate a refresh token",
+			Description: "Returns a new token pair. Reusing a rotated refresh token revokes all sessions of the account.",
+			Request:     handlers.RefreshRequest{},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.TokenResponse{},
+				http.StatusUnauthorized: apperr.Problem{},
+			},
+		}, accounts.Refresh)
+		authRoutes.POST("/logout", openapi.Operation{
+			Summary:   "Revoke a refresh token",
+			Request:   handlers.RefreshRequest{},
+			Responses: map[int]any{http.StatusNoContent: nil},
+		}, accounts.Logout)
+	}
+
+	// Authenticated API routes; guard sub-groups with middleware.RequireRole,
+	// middleware.RequireScope or middleware.RequireFlag as needed. POST and PATCH requests may send
+	// an Idempotency-Key to be retried safely.
+	protected := api.Group("", middleware.Auth(deps.tokens), rateLimit("user"), idempotent).WithAuth()
+	{
+		me := protected.WithTags("users")
+		me.GET("/users/me", openapi.Operation{
+			Summary: "Get the current user's profile",
+			Responses: map[int]any{
+				http.StatusOK:           models.User{},
+				http.StatusUnauthorized: apperr.Problem{},
+				http.StatusNotFound:     apperr.Problem{},
+			},
+		}, accounts.Me)
+		me.PATCH("/users/me", openapi.Operation{
+			Summary:     "Update the current user's profile",
+			Description: "Changing the password revokes every refresh token of the user, including the caller's; log in again for a new pair.",
+			Request:     handlers.UpdateProfileRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.User{},
+				http.StatusBadRequest:          apperr.Problem{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+				http.StatusUnauthorized:        apperr.Problem{},
+				http.StatusConflict:            apperr.Problem{},
+			},
+		}, accounts.UpdateMe)
+		me.DELETE("/users/me", openapi.Operation{
+			Summary: "Delete the current user's account",
+			Responses: map[int]any{
+				http.StatusNoContent:    nil,
+				http.StatusUnauthorized: apperr.Problem{},
+			},
+		}, accounts.DeleteMe)
+
+		h := protected.Group("/habits").WithTags("habits")
+		h.GET("", openapi.Operation{
+			Summary: "List the current user's habits",
+			Query:   handlers.ListHabitsQuery{},
+			Responses: map[int]any{
+				http.StatusOK:           []models.Habit{},
+				http.StatusUnauthorized: apperr.Problem{},
+			},
+		}, habits.ListHabits)
+		h.POST("", openapi.Operation{
+			Summary: "Create a habit",
+			Request: handlers.CreateHabitRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.Habit{},
+				http.StatusBadRequest:          apperr.Problem{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+				http.StatusUnauthorized:        apperr.Problem{},
+			},
+		}, habits.CreateHabit)
+		h.GET("/:id", openapi.Operation{
+			Summary: "Get a habit",
+			Responses: map[int]any{
+				http.StatusOK:           models.Habit{},
+				http.StatusUnauthorized: apperr.Problem{},
+				http.StatusNotFound:     apperr.Problem{},
+			},
+		}, habits.GetHabit)
+		h.PATCH("/:id", openapi.Operation{
+			Summary: "Update or archive a habit",
+			Request: handlers.UpdateHabitRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  models.Habit{},
+				http.StatusBadRequest:          apperr.Problem{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+				http.StatusUnauthorized:        apperr.Problem{},
+				http.StatusNotFound:            apperr.Problem{},
+			},
+		}, habits.UpdateHabit)
+		h.DELETE("/:id", openapi.Operation{
+			Summary: "Delete a habit and its check-ins",
+			Responses: map[int]any{
+				http.StatusNoContent:    nil,
+				http.StatusUnauthorized: apperr.Problem{},
+				http.StatusNotFound:     apperr.Problem{},
+			},
+		}, habits.DeleteHabit)
+		h.GET("/:id/stats", openapi.Operation{
+			Summary:     "Get streaks and completion rate of a habit",
+			Description: "Streaks count scheduled days, or weeks for weekly habits, in the user's time zone. Days the schedule skips do not break a streak, and today only counts once checked in.",
+			Query:       handlers.DateRangeQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  handlers.HabitStatsResponse{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+				http.StatusUnauthorized:        apperr.Problem{},
+				http.StatusNotFound:            apperr.Problem{},
+			},
+		}, habits.HabitStats)
+		h.GET("/:id/checkins", openapi.Operation{
+			Summary: "List check-ins of a habit",
+			Query:   handlers.DateRangeQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  []models.CheckIn{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+				http.StatusUnauthorized:        apperr.Problem{},
+				http.StatusNotFound:            apperr.Problem{},
+			},
+		}, habits.ListCheckIns)
+		h.POST("/:id/checkins", openapi.Operation{
+			Summary: "Check in to a habit",
+			Request: handlers.CheckInRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             models.CheckIn{},
+				http.StatusBadRequest:          apperr.Problem{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+				http.StatusUnauthorized:        apperr.Problem{},
+				http.StatusNotFound:            apperr.Problem{},
+				http.StatusConflict:            apperr.Problem{},
+			},
+		}, habits.CheckIn)
+		h.DELETE("/:id/checkins/:date", openapi.Operation{
+			Summary: "Remove the check-in of a day",
+			Responses: map[int]any{
+				http.StatusNoContent:    nil,
+				http.StatusUnauthorized: apperr.Problem{},
+				http.StatusNotFound:     apperr.Problem{},
+			},
+		}, habits.DeleteCheckIn)
+
+		wellness := protected.WithTags("measurements")
+		wellness.POST("/measurements", openapi.Operation{
+			Summary:     "Ingest a batch of wellness measurements",
+			Description: "Measurements whose client_id was already sent are skipped, so failed uploads can be retried as a whole.",
+			Request:     handlers.IngestMeasurementsRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  handlers.IngestMeasurementsResponse{},
+				http.StatusBadRequest:          apperr.Problem{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+				http.StatusUnauthorized:        apperr.Problem{},
+			},
+		}, measurements.IngestMeasurements)
+		wellness.GET("/measurements/:type/series", openapi.Operation{
+			Summary:     "Get a metric aggregated by hour, day, week or month",
+			Description: "Buckets follow the user's time zone; weeks start on Monday.",
+			Query:       handlers.SeriesQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  handlers.SeriesResponse{},
+				http.StatusBadRequest:          apperr.Problem{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+				http.StatusUnauthorized:        apperr.Problem{},
+				http.StatusNotFound:            apperr.Problem{},
+			},
+		}, measurements.Series)
+
+		admin := protected.Group("/admin", middleware.RequireRole(auth.RoleAdmin)).WithTags("admin")
+		admin.GET("/audit", openapi.Operation{
+			Summary:     "Query the security audit log",
+			Description: "Requires the admin role. Events are returned newest first; page with the before parameter.",
+			Query:       handlers.AuditQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  handlers.AuditEventsResponse{},
+				http.StatusBadRequest:          apperr.Problem{},
+				http.StatusUnprocessableEntity: apperr.Problem{},
+				http.StatusUnauthorized:        apperr.Problem{},
+				http.StatusForbidden:           apperr.Problem{},
+			},
+		}, auditLog.ListAuditEvents)
+		admin.GET("/audit/verify", openapi.Operation{
+			Summary:     "Verify the hash chain of the audit log",
+			Description: "Requires the admin role. Reports the first event that was altered or removed.",
+			Responses: map[int]any{
+				http.StatusOK:           audit.Verification{},
+				http.StatusUnauthorized: apperr.Problem{},
+				http.StatusForbidden:    apperr.Problem{},
+			},
+		}, auditLog.VerifyAuditLog)
+
+		protected.GET("/flags", openapi.Operation{
+			Summary:     "Get the feature flags for the current user",
+			Description: "Lists every flag defined on the server with whether it is on for the caller. Flags change without a deploy; clients should refresh them periodically.",
+			Tags:        []string{"features"},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.FlagsResponse{},
+				http.StatusUnauthorized: apperr.Problem{},
+			},
+		}, handlers.Flags(deps.flags))
+
+		protected.GET("/auth/session", openapi.Operation{
+			Summary: "Describe the current access token",
+			Tags:    []string{"auth"},
+			Responses: map[int]any{
+				http.StatusOK:           handlers.SessionResponse{},
+				http.StatusUnauthorized: apperr.Problem{},
+			},
+		}, handlers.Session)
+	}
+
+	return router, spec
+}
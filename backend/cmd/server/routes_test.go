@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/metrics"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	cfg := config.Default()
+	router, spec := newRouter(cfg, dependencies{metrics: metrics.New()})
+
+	if err := spec.Check(router.Routes()); err != nil {
+		t.Error(err)
+	}
+	if len(spec.Document().Paths) == 0 {
+		t.Error("Expected documented paths")
+	}
+}
+
+func TestUnknownRouteIsProblem(t *testing.T) {
+	router, _ := newRouter(config.Default(), dependencies{metrics: metrics.New()})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != apperr.ContentType {
+		t.Errorf("Expected %s, got %q", apperr.ContentType, ct)
+	}
+}
+
+func TestForwardedForNeedsTrustedProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		want    []int
+	}{
+		// Without trusted proxies a spoofed header cannot reset the bucket
+		{name: "no trusted proxies", want: []int{http.StatusOK, http.StatusTooManyRequests}},
+		{name: "trusted proxy", proxies: []string{"10.0.0.0/8"}, want: []int{http.StatusOK, http.StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Server.TrustedProxies = tt.proxies
+			cfg.RateLimit.Policies["api"] = config.RateLimitPolicy{Requests: 1, Per: time.Minute, Burst: 1, Key: config.RateLimitByIP}
+			router, _ := newRouter(cfg, dependencies{metrics: metrics.New()})
+
+			for i, forwarded := range []string{"203.0.113.1", "203.0.113.2"} {
+				req := httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil)
+				req.RemoteAddr = "10.0.0.1:1234"
+				req.Header.Set("X-Forwarded-For", forwarded)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				if w.Code != tt.want[i] {
+					t.Errorf("Request %d: expected %d, got %d", i, tt.want[i], w.Code)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+// Package apperr defines the catalogue of API errors and renders them as
+// RFC 7807 problem details. Codes are stable identifiers clients may switch
+// on; messages are English defaults for humans.
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Code is a stable machine-readable error identifier
+type Code string
+
+// Error catalogue
+const (
+	CodeInvalidRequest       Code = "invalid_request"
+	CodeValidationFailed     Code = "validation_failed"
+	CodeUnauthorized         Code = "unauthorized"
+	CodeInvalidToken         Code = "invalid_token"
+	CodeInvalidCredentials   Code = "invalid_credentials"
+	CodeForbidden            Code = "forbidden"
+	CodeNotFound             Code = "not_found"
+	CodeMethodNotAllowed     Code = "method_not_allowed"
+	CodeConflict             Code = "conflict"
+	CodeEmailTaken           Code = "email_taken"
+	CodeRateLimited          Code = "rate_limited"
+	CodeIdempotencyKeyReused Code = "idempotency_key_reused"
+	CodeIdempotencyKeyInUse  Code = "idempotency_key_in_use"
+	CodeInternal             Code = "internal_error"
+	CodeUnavailable          Code = "service_unavailable"
+)
+
+// entry is the catalogue definition of a code
+type entry struct {
+	status  int
+	message string
+}
+
+var catalogue = map[Code]entry{
+	CodeInvalidRequest:       {http.StatusBadRequest, "the request could not be parsed"},
+	CodeValidationFailed:     {http.StatusUnprocessableEntity, "the request failed validation"},
+	CodeUnauthorized:         {http.StatusUnauthorized, "authentication required"},
+	CodeInvalidToken:         {http.StatusUnauthorized, "the token is invalid or expired"},
+	CodeInvalidCredentials:   {http.StatusUnauthorized, "invalid email or password"},
+	CodeForbidden:            {http.StatusForbidden, "you are not allowed to perform this action"},
+	CodeNotFound:             {http.StatusNotFound, "the resource was not found"},
+	CodeMethodNotAllowed:     {http.StatusMethodNotAllowed, "the method is not allowed on this resource"},
+	CodeConflict:             {http.StatusConflict, "the request conflicts with the current state"},
+	CodeEmailTaken:           {http.StatusConflict, "email is already registered"},
+	CodeRateLimited:          {http.StatusTooManyRequests, "too many requests, retry later"},
+	CodeIdempotencyKeyReused: {http.StatusUnprocessableEntity, "the idempotency key was already used for a different request"},
+	CodeIdempotencyKeyInUse:  {http.StatusConflict, "a request with this idempotency key is still in progress"},
+	CodeInternal:             {http.StatusInternalServerError, "internal server error"},
+	CodeUnavailable:          {http.StatusServiceUnavailable, "the service is temporarily unavailable"},
+}
+
+// Codes returns every catalogued code
+func Codes() []Code {
+	codes := make([]Code, 0, len(catalogue))
+	for code := range catalogue {
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// Status returns the HTTP status of code; unknown codes are internal errors
+func (c Code) Status() int {
+	if e, ok := catalogue[c]; ok {
+		return e.status
+	}
+	return http.StatusInternalServerError
+}
+
+// Error is an API error with a catalogued code. Cause is logged but never
+// sent to clients.
+type Error struct {
+	Code    Code
+	Status  int
+	Message string
+	Fields  []FieldError
+	Cause   error
+}
+
+// FieldError describes why one request field was rejected
+type FieldError struct {
+	Field   string `json:"field" example:"email"`
+	Code    string `json:"code" doc:"validation rule that failed" example:"required"`
+	Message string `json:"message" example:"is required"`
+}
+
+// New returns an error for code; an empty message uses the catalogue default
+func New(code Code, message string) *Error {
+	if message == "" {
+		message = catalogue[code].message
+	}
+	return &Error{Code: code, Status: code.Status(), Message: message}
+}
+
+// Newf returns an error for code with a formatted message
+func Newf(code Code, format string, args ...any) *Error {
+	return New(code, fmt.Sprintf(format, args...))
+}
+
+// Wrap returns an error for code caused by err
+func Wrap(err error, code Code, message string) *Error {
+	e := New(code, message)
+	e.Cause = err
+	return e
+}
+
+// Internal wraps an unexpected error as a 500 with a generic message
+func Internal(err error) *Error {
+	return Wrap(err, CodeInternal, "")
+}
+
+// Error implements error
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Cause)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// As returns the *Error in err's chain, if any
+func As(err error) (*Error, bool) {
+	var e *Error
+	ok := errors.As(err, &e)
+	return e, ok
+}
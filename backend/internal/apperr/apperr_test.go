@@ -0,0 +1,130 @@
+package apperr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+func TestCatalogue(t *testing.T) {
+	for _, code := range Codes() {
+		e := New(code, "")
+		if e.Status < 400 || e.Message == "" {
+			t.Errorf("Code %s: expected an error status and default message, got %d %q", code, e.Status, e.Message)
+		}
+	}
+	if got := Code("made_up").Status(); got != http.StatusInternalServerError {
+		t.Errorf("Expected unknown codes to be internal errors, got %d", got)
+	}
+}
+
+func TestErrorWrapping(t *testing.T) {
+	cause := errors.New("disk full")
+	err := fmt.Errorf("saving: %w", Wrap(cause, CodeConflict, "already exists"))
+
+	e, ok := As(err)
+	if !ok || e.Code != CodeConflict {
+		t.Fatalf("Expected to find the conflict error, got %v", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected the cause to be unwrappable")
+	}
+}
+
+func TestNewProblem(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		expose     bool
+		wantStatus int
+		wantCode   Code
+		wantDetail string
+	}{
+		{"catalogued", New(CodeNotFound, "habit not found"), false, 404, CodeNotFound, "habit not found"},
+		{"uncatalogued in production", errors.New("pq: connection refused"), false, 500, CodeInternal, "internal server error"},
+		{"uncatalogued in development", errors.New("pq: connection refused"), true, 500, CodeInternal, "pq: connection refused"},
+		{"internal cause in production", Internal(errors.New("secret")), false, 500, CodeInternal, "internal server error"},
+		{"internal cause in development", Internal(errors.New("secret")), true, 500, CodeInternal, "internal server error: secret"},
+		{"client error cause never shown", Wrap(errors.New("secret"), CodeConflict, "taken"), true, 409, CodeConflict, "taken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProblem(tt.err, tt.expose)
+			if p.Status != tt.wantStatus || p.Code != tt.wantCode || p.Detail != tt.wantDetail {
+				t.Errorf("Expected %d %s %q, got %d %s %q", tt.wantStatus, tt.wantCode, tt.wantDetail, p.Status, p.Code, p.Detail)
+			}
+			if p.Title != http.StatusText(tt.wantStatus) || p.Type != "about:blank" {
+				t.Errorf("Unexpected type/title: %q %q", p.Type, p.Title)
+			}
+		})
+	}
+}
+
+type bindTarget struct {
+	Email string  `json:"email" binding:"required,email"`
+	Name  string  `json:"name" binding:"min=2"`
+	Age   int     `json:"age" binding:"max=150"`
+	Items []item  `json:"items" binding:"dive"`
+	Role  *string `json:"role" binding:"omitempty,oneof=user admin"`
+}
+
+type item struct {
+	Title string `json:"title" binding:"required"`
+}
+
+func TestBinding(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantCode   Code
+		wantFields map[string]string
+	}{
+		{"empty body", "", CodeInvalidRequest, nil},
+		{"malformed JSON", `{"email":`, CodeInvalidRequest, nil},
+		{"wrong type", `{"email":"a@b.co","name":"ab","age":"old"}`, CodeValidationFailed, map[string]string{"age": "type"}},
+		{
+			"rule violations",
+			`{"email":"nope","name":"a","age":200,"items":[{"title":""}],"role":"root"}`,
+			CodeValidationFailed,
+			map[string]string{"email": "email", "name": "min", "age": "max", "items[0].title": "required", "role": "oneof"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var target bindTarget
+			err := binding.JSON.BindBody([]byte(tt.body), &target)
+			if tt.body == "" {
+				err = binding.JSON.Bind(&http.Request{Body: http.NoBody}, &target)
+			}
+			e := Binding(err)
+			if e.Code != tt.wantCode {
+				t.Fatalf("Expected %s, got %s (%v)", tt.wantCode, e.Code, err)
+			}
+			got := make(map[string]string)
+			for _, fe := range e.Fields {
+				got[fe.Field] = fe.Code
+				if fe.Message == "" {
+					t.Errorf("Expected a message for %s", fe.Field)
+				}
+			}
+			for field, rule := range tt.wantFields {
+				if got[field] != rule {
+					t.Errorf("Expected %s to fail %q, got %v", field, rule, got)
+				}
+			}
+		})
+	}
+}
+
+func TestAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(nil)
+	Abort(c, New(CodeRateLimited, ""))
+	if !c.IsAborted() || c.Writer.Status() != http.StatusTooManyRequests || len(c.Errors) != 1 {
+		t.Errorf("Expected aborted 429 with the error recorded, got %d %v", c.Writer.Status(), c.Errors)
+	}
+}
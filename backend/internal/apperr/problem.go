@@ -0,0 +1,62 @@
+package apperr
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem details (RFC 7807)
+const ContentType = "application/problem+json"
+
+// Problem is the RFC 7807 body of every API error. Code and the field codes
+// are stable; Detail is meant for humans and may change.
+type Problem struct {
+	Type      string       `json:"type" example:"about:blank"`
+	Title     string       `json:"title" example:"Unprocessable Entity"`
+	Status    int          `json:"status" example:"422"`
+	Detail    string       `json:"detail,omitempty" example:"the request failed validation"`
+	Instance  string       `json:"instance,omitempty" doc:"request path" example:"/api/v1/auth/register"`
+	Code      Code         `json:"code" example:"validation_failed"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty" doc:"per-field validation failures"`
+}
+
+// MediaType reports the content type problems are served with
+func (Problem) MediaType() string {
+	return ContentType
+}
+
+// NewProblem builds the problem document for err. Errors outside the
+// catalogue are internal; their text is only exposed when expose is set.
+func NewProblem(err error, expose bool) Problem {
+	e, ok := As(err)
+	if !ok {
+		e = Internal(err)
+		if expose {
+			e.Message = err.Error()
+		}
+	} else if expose && e.Status >= 500 && e.Cause != nil {
+		e = &Error{Code: e.Code, Status: e.Status, Message: e.Message + ": " + e.Cause.Error()}
+	}
+	return Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(e.Status),
+		Status: e.Status,
+		Detail: e.Message,
+		Code:   e.Code,
+		Errors: e.Fields,
+	}
+}
+
+// Abort records err on the request and stops the handler chain. The status
+// is set immediately; the body is written by the error middleware.
+func Abort(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if e, ok := As(err); ok {
+		status = e.Status
+	}
+	c.Status(status)
+	c.Error(err)
+	c.Abort()
+}
@@ -0,0 +1,129 @@
+package apperr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report JSON field names instead of Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form", "uri"} {
+				name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// Binding converts an error from c.ShouldBind* into an API error: rule
+// violations become 422 with per-field details, malformed bodies 400
+func Binding(err error) *Error {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		e := New(CodeValidationFailed, "")
+		e.Cause = err
+		for _, fe := range verrs {
+			e.Fields = append(e.Fields, FieldError{
+				Field:   fieldPath(fe),
+				Code:    fe.Tag(),
+				Message: ruleMessage(fe),
+			})
+		}
+		return e
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, io.EOF):
+		return Wrap(err, CodeInvalidRequest, "request body is empty")
+	case errors.As(err, &syntaxErr):
+		return Wrap(err, CodeInvalidRequest, fmt.Sprintf("malformed JSON at offset %d", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		e := Wrap(err, CodeValidationFailed, "")
+		e.Fields = []FieldError{{
+			Field:   typeErr.Field,
+			Code:    "type",
+			Message: "must be of type " + jsonType(typeErr.Type),
+		}}
+		return e
+	}
+	return Wrap(err, CodeInvalidRequest, "")
+}
+
+// fieldPath drops the top-level struct name from the validator namespace
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
+	}
+	return path
+}
+
+// ruleMessage describes a failed validation rule in English
+func ruleMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	stringLike := fe.Kind() == reflect.String
+	switch fe.Tag() {
+	case "required", "required_if":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url", "uri":
+		return "must be a valid URL"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(param, " ", ", ")
+	case "min", "gte":
+		if stringLike {
+			return fmt.Sprintf("must be at least %s characters", param)
+		}
+		return "must be at least " + param
+	case "max", "lte":
+		if stringLike {
+			return fmt.Sprintf("must be at most %s characters", param)
+		}
+		return "must be at most " + param
+	case "len":
+		return "must have length " + param
+	case "gt":
+		return "must be greater than " + param
+	case "lt":
+		return "must be less than " + param
+	case "timezone":
+		return "must be an IANA time zone such as Europe/Moscow"
+	}
+	return fmt.Sprintf("failed the %q rule", fe.Tag())
+}
+
+// jsonType names a Go type the way API clients see it
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	default:
+		return "integer"
+	}
+}
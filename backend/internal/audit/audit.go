@@ -0,0 +1,95 @@
+// Package audit records security-relevant actions in a tamper-evident,
+// append-only log. Handlers call Record with the request context; the
+// recorder and the request's origin are attached to it by middleware, so
+// callers never deal with storage.
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+)
+
+// Recorded actions
+const (
+	ActionLoginSucceeded    = "login.succeeded"
+	ActionLoginFailed       = "login.failed"
+	ActionPasswordChanged   = "password.changed"
+	ActionRoleChanged       = "role.changed"
+	ActionAccountDeleted    = "account.deleted"
+	ActionRefreshTokenReuse = "refresh_token.reused"
+	ActionAccountCreated    = "account.created"
+	ActionAccountDisabled   = "account.disabled"
+	ActionAccountEnabled    = "account.enabled"
+	ActionTokenIssued       = "token.issued"
+)
+
+// TargetUser is the target type of actions on accounts
+const TargetUser = "user"
+
+// Event is one entry of the audit log. ID, Hash and PrevHash are assigned
+// when the event is appended.
+type Event struct {
+	ID         int64          `json:"id"`
+	Time       time.Time      `json:"time"`
+	Action     string         `json:"action" example:"login.failed"`
+	ActorID    *int64         `json:"actor_id,omitempty" doc:"user who performed the action; absent for anonymous requests"`
+	TargetType string         `json:"target_type,omitempty" example:"user"`
+	TargetID   string         `json:"target_id,omitempty" example:"42"`
+	IP         string         `json:"ip,omitempty" example:"203.0.113.7"`
+	UserAgent  string         `json:"user_agent,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+	Details    map[string]any `json:"details,omitempty"`
+	PrevHash   string         `json:"prev_hash" doc:"hash of the previous event; empty for the first"`
+	Hash       string         `json:"hash" doc:"SHA-256 over this event and prev_hash"`
+}
+
+// Source describes where a request came from
+type Source struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+// Recorder appends events to a log
+type Recorder interface {
+	Append(ctx context.Context, e *Event) error
+}
+
+type contextKey int
+
+const (
+	recorderContextKey contextKey = iota
+	sourceContextKey
+)
+
+// WithRecorder returns a copy of ctx whose events go to r
+func WithRecorder(ctx context.Context, r Recorder) context.Context {
+	return context.WithValue(ctx, recorderContextKey, r)
+}
+
+// WithSource returns a copy of ctx carrying the origin of its events
+func WithSource(ctx context.Context, s Source) context.Context {
+	return context.WithValue(ctx, sourceContextKey, s)
+}
+
+// Record appends e to the recorder in ctx, filling in the request origin and,
+// unless set, the authenticated user as actor. Without a recorder it does
+// nothing.
+func Record(ctx context.Context, e Event) error {
+	r, ok := ctx.Value(recorderContextKey).(Recorder)
+	if !ok {
+		return nil
+	}
+	if s, ok := ctx.Value(sourceContextKey).(Source); ok {
+		e.IP, e.UserAgent, e.RequestID = s.IP, s.UserAgent, s.RequestID
+	}
+	if e.ActorID == nil {
+		if claims, ok := auth.ClaimsFromContext(ctx); ok {
+			id := claims.UserID
+			e.ActorID = &id
+		}
+	}
+	return r.Append(ctx, &e)
+}
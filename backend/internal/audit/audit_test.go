@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+)
+
+func TestRecord(t *testing.T) {
+	if err := Record(context.Background(), Event{Action: ActionLoginFailed}); err != nil {
+		t.Errorf("Expected Record without a recorder to do nothing, got %v", err)
+	}
+
+	s := NewStore(dbtest.SQLite(t))
+	ctx := WithRecorder(context.Background(), s)
+	ctx = WithSource(ctx, Source{IP: "203.0.113.7", UserAgent: "app/1.0", RequestID: "req-1"})
+	ctx = auth.WithClaims(ctx, &auth.Claims{UserID: 42})
+
+	if err := Record(ctx, Event{Action: ActionPasswordChanged, TargetType: TargetUser, TargetID: "42"}); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+	events, err := s.Query(context.Background(), Filter{})
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Expected one event, got %v %v", events, err)
+	}
+	e := events[0]
+	if e.ActorID == nil || *e.ActorID != 42 || e.IP != "203.0.113.7" || e.UserAgent != "app/1.0" || e.RequestID != "req-1" {
+		t.Errorf("Expected actor and source from the context, got %+v", e)
+	}
+}
+
+func TestStore(t *testing.T) {
+	for name, open := range map[string]func(testing.TB) *database.DB{
+		"sqlite":   dbtest.SQLite,
+		"postgres": dbtest.Postgres,
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := open(t)
+			s := NewStore(db)
+			ctx := context.Background()
+			start := time.Date(2025, 7, 1, 12, 0, 0, 123456789, time.UTC)
+			now := start
+			s.now = func() time.Time { return now }
+			if v, _ := s.Verify(ctx); v.Checked > 0 {
+				t.Skip("audit_events is not empty and cannot be cleared")
+			}
+
+			alice, bob := int64(1), int64(2)
+			events := []*Event{
+				{Action: ActionLoginSucceeded, ActorID: &alice, TargetType: TargetUser, TargetID: "1"},
+				{Action: ActionLoginFailed, TargetType: TargetUser, TargetID: "2", Details: map[string]any{"email": "bob@example.com"}},
+				{Action: ActionLoginSucceeded, ActorID: &bob, TargetType: TargetUser, TargetID: "2"},
+				{Action: ActionAccountDeleted, ActorID: &alice, TargetType: TargetUser, TargetID: "1"},
+			}
+			for _, e := range events {
+				if err := s.Append(ctx, e); err != nil {
+					t.Fatalf("Append() failed: %v", err)
+				}
+				now = now.Add(time.Minute)
+			}
+			if events[0].PrevHash != "" || events[1].PrevHash != events[0].Hash || events[0].ID >= events[1].ID {
+				t.Errorf("Expected events to be chained, got %+v and %+v", events[0], events[1])
+			}
+			if err := s.Append(ctx, &Event{}); err == nil {
+				t.Error("Expected an event without action to be rejected")
+			}
+
+			tests := []struct {
+				name   string
+				filter Filter
+				want   []int
+			}{
+				{"all", Filter{}, []int{3, 2, 1, 0}},
+				{"action", Filter{Action: ActionLoginSucceeded}, []int{2, 0}},
+				{"actor", Filter{ActorID: &alice}, []int{3, 0}},
+				{"target", Filter{TargetType: TargetUser, TargetID: "2"}, []int{2, 1}},
+				{"time range", Filter{From: start.Add(30 * time.Second), To: start.Add(150 * time.Second)}, []int{2, 1}},
+				{"page", Filter{Before: events[2].ID, Limit: 1}, []int{1}},
+			}
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					got, err := s.Query(ctx, tt.filter)
+					if err != nil {
+						t.Fatalf("Query() failed: %v", err)
+					}
+					if len(got) != len(tt.want) {
+						t.Fatalf("Expected %d events, got %d", len(tt.want), len(got))
+					}
+					for i, idx := range tt.want {
+						if got[i].ID != events[idx].ID || got[i].Hash != events[idx].Hash {
+							t.Errorf("Event %d: expected %+v, got %+v", i, events[idx], got[i])
+						}
+					}
+				})
+			}
+			got, _ := s.Query(ctx, Filter{Action: ActionLoginFailed})
+			if len(got) != 1 || got[0].Details["email"] != "bob@example.com" || got[0].ActorID != nil {
+				t.Errorf("Expected details and no actor, got %+v", got)
+			}
+
+			v, err := s.Verify(ctx)
+			if err != nil || !v.Valid || v.Checked != len(events) {
+				t.Errorf("Expected an intact chain of %d, got %+v %v", len(events), v, err)
+			}
+
+			if _, err := db.ExecContext(ctx, db.Rebind("UPDATE audit_events SET action = ? WHERE id = ?"), "login.failed", events[0].ID); err == nil {
+				t.Error("Expected updates to be rejected")
+			}
+			if _, err := db.ExecContext(ctx, db.Rebind("DELETE FROM audit_events WHERE id = ?"), events[1].ID); err == nil {
+				t.Error("Expected deletes to be rejected")
+			}
+		})
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	db := dbtest.SQLite(t)
+	s := NewStore(db)
+	ctx := context.Background()
+	var ids []int64
+	for _, action := range []string{ActionLoginSucceeded, ActionPasswordChanged, ActionLoginSucceeded} {
+		e := &Event{Action: action, TargetType: TargetUser, TargetID: "1"}
+		if err := s.Append(ctx, e); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+		ids = append(ids, e.ID)
+	}
+
+	// Someone with direct database access bypasses the triggers
+	for _, stmt := range []string{"DROP TRIGGER audit_events_no_update", "DROP TRIGGER audit_events_no_delete"} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			t.Fatalf("%s: %v", stmt, err)
+		}
+	}
+
+	db.ExecContext(ctx, "UPDATE audit_events SET target_id = '2' WHERE id = ?", ids[1])
+	if v, _ := s.Verify(ctx); v.Valid || v.BrokenAt != ids[1] || v.Checked != 1 {
+		t.Errorf("Expected the edited event to break the chain, got %+v", v)
+	}
+
+	db.ExecContext(ctx, "UPDATE audit_events SET target_id = '1' WHERE id = ?", ids[1])
+	db.ExecContext(ctx, "DELETE FROM audit_events WHERE id = ?", ids[1])
+	if v, _ := s.Verify(ctx); v.Valid || v.BrokenAt != ids[2] {
+		t.Errorf("Expected the deletion to break the chain, got %+v", v)
+	}
+}
+
+func TestConcurrentAppends(t *testing.T) {
+	s := NewStore(dbtest.SQLite(t))
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.Append(ctx, &Event{Action: ActionLoginSucceeded})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+	if v, err := s.Verify(ctx); err != nil || !v.Valid || v.Checked != 20 {
+		t.Errorf("Expected a single chain of 20 events, got %+v %v", v, err)
+	}
+}
@@ -0,0 +1,259 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// appendLockID is the Postgres advisory lock serialising appends across replicas
+const appendLockID = 0x61756469
+
+const eventColumns = "id, occurred_at, action, actor_id, target_type, target_id, ip, user_agent, request_id, details, prev_hash, hash"
+
+// Filter selects events, newest first. Zero fields match everything.
+type Filter struct {
+	Action     string
+	ActorID    *int64
+	TargetType string
+	TargetID   string
+	From, To   time.Time
+	// Before pages through the log: only events with a lower ID match
+	Before int64
+	Limit  int
+}
+
+// Verification is the outcome of checking the hash chain
+type Verification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int    `json:"checked" doc:"events verified before the first broken one"`
+	BrokenAt int64  `json:"broken_at,omitempty" doc:"ID of the first event whose hash does not match"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Store keeps the log in the audit_events table. Appends are serialised so
+// every event links to the one before it.
+type Store struct {
+	db  *database.DB
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// NewStore creates a store on a migrated database
+func NewStore(db *database.DB) *Store {
+	return &Store{db: db, now: time.Now}
+}
+
+// Append links e to the last event and inserts it
+func (s *Store) Append(ctx context.Context, e *Event) error {
+	if e.Action == "" {
+		return errors.New("audit: event without action")
+	}
+	details := ""
+	if len(e.Details) > 0 {
+		b, err := json.Marshal(e.Details)
+		if err != nil {
+			return fmt.Errorf("audit: encode details: %w", err)
+		}
+		details = string(b)
+	}
+	if e.Time.IsZero() {
+		e.Time = s.now()
+	}
+	// Postgres keeps microseconds; hash what is stored
+	e.Time = e.Time.UTC().Truncate(time.Microsecond)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inTx(ctx, func(conn *sql.Conn) error {
+		var prev string
+		err := conn.QueryRowContext(ctx, "SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1").Scan(&prev)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("audit: last hash: %w", err)
+		}
+		e.PrevHash = prev
+		e.Hash = hash(e, details)
+
+		query := `INSERT INTO audit_events (occurred_at, action, actor_id, target_type, target_id, ip, user_agent, request_id, details, prev_hash, hash)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		args := []any{e.Time, e.Action, e.ActorID, e.TargetType, e.TargetID, e.IP, e.UserAgent, e.RequestID, details, e.PrevHash, e.Hash}
+		if s.db.Dialect == database.Postgres {
+			err = conn.QueryRowContext(ctx, s.db.Rebind(query)+" RETURNING id", args...).Scan(&e.ID)
+		} else {
+			var res sql.Result
+			if res, err = conn.ExecContext(ctx, query, args...); err == nil {
+				e.ID, err = res.LastInsertId()
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("audit: insert: %w", err)
+		}
+		return nil
+	})
+}
+
+// inTx runs fn in a transaction that holds the append lock. SQLite takes the
+// write lock up front so reading the last hash cannot race another writer.
+func (s *Store) inTx(ctx context.Context, fn func(*sql.Conn) error) error {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("audit: connect: %w", err)
+	}
+	defer conn.Close()
+
+	begin := "BEGIN"
+	if s.db.Dialect == database.SQLite {
+		begin = "BEGIN IMMEDIATE"
+	}
+	if _, err := conn.ExecContext(ctx, begin); err != nil {
+		return fmt.Errorf("audit: begin: %w", err)
+	}
+	if s.db.Dialect == database.Postgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", appendLockID); err != nil {
+			conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+			return fmt.Errorf("audit: lock: %w", err)
+		}
+	}
+	if err := fn(conn); err != nil {
+		conn.ExecContext(context.WithoutCancel(ctx), "ROLLBACK")
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "COMMIT"); err != nil {
+		return fmt.Errorf("audit: commit: %w", err)
+	}
+	return nil
+}
+
+// Query returns the events matching f, newest first
+func (s *Store) Query(ctx context.Context, f Filter) ([]Event, error) {
+	var where []string
+	var args []any
+	add := func(cond string, arg any) {
+		where = append(where, cond)
+		args = append(args, arg)
+	}
+	if f.Action != "" {
+		add("action = ?", f.Action)
+	}
+	if f.ActorID != nil {
+		add("actor_id = ?", *f.ActorID)
+	}
+	if f.TargetType != "" {
+		add("target_type = ?", f.TargetType)
+	}
+	if f.TargetID != "" {
+		add("target_id = ?", f.TargetID)
+	}
+	if !f.From.IsZero() {
+		add("occurred_at >= ?", f.From.UTC())
+	}
+	if !f.To.IsZero() {
+		add("occurred_at < ?", f.To.UTC())
+	}
+	if f.Before > 0 {
+		add("id < ?", f.Before)
+	}
+
+	query := "SELECT " + eventColumns + " FROM audit_events"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("audit: query: %w", err)
+	}
+	defer rows.Close()
+	events := []Event{}
+	for rows.Next() {
+		e, _, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+// Verify walks the log from the first event and checks that every hash
+// matches its event and links to its predecessor
+func (s *Store) Verify(ctx context.Context) (Verification, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+eventColumns+" FROM audit_events ORDER BY id")
+	if err != nil {
+		return Verification{}, fmt.Errorf("audit: verify: %w", err)
+	}
+	defer rows.Close()
+
+	v := Verification{Valid: true}
+	prev := ""
+	for rows.Next() {
+		e, details, err := scanEvent(rows)
+		if err != nil {
+			return Verification{}, err
+		}
+		switch {
+		case e.PrevHash != prev:
+			v.Reason = "does not link to the previous event"
+		case hash(e, details) != e.Hash:
+			v.Reason = "content does not match its hash"
+		}
+		if v.Reason != "" {
+			v.Valid, v.BrokenAt = false, e.ID
+			return v, nil
+		}
+		prev = e.Hash
+		v.Checked++
+	}
+	return v, rows.Err()
+}
+
+// scanEvent reads a row selected with eventColumns, returning the event and
+// its details as stored
+func scanEvent(rows *sql.Rows) (*Event, string, error) {
+	var e Event
+	var actorID sql.NullInt64
+	var details string
+	if err := rows.Scan(&e.ID, &e.Time, &e.Action, &actorID, &e.TargetType, &e.TargetID,
+		&e.IP, &e.UserAgent, &e.RequestID, &details, &e.PrevHash, &e.Hash); err != nil {
+		return nil, "", fmt.Errorf("audit: scan: %w", err)
+	}
+	e.Time = e.Time.UTC()
+	if actorID.Valid {
+		e.ActorID = &actorID.Int64
+	}
+	if details != "" {
+		if err := json.Unmarshal([]byte(details), &e.Details); err != nil {
+			return nil, "", fmt.Errorf("audit: decode details of event %d: %w", e.ID, err)
+		}
+	}
+	return &e, details, nil
+}
+
+// hash returns the chain hash of e given its encoded details
+func hash(e *Event, details string) string {
+	var actor any
+	if e.ActorID != nil {
+		actor = *e.ActorID
+	}
+	// A JSON array keeps field boundaries unambiguous
+	content, _ := json.Marshal([]any{
+		e.PrevHash, e.Time.Format(time.RFC3339Nano), e.Action, actor, e.TargetType, e.TargetID,
+		e.IP, e.UserAgent, e.RequestID, details,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
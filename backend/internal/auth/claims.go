@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Well-known roles
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// Claims represents JWT token claims
+type Claims struct {
+	UserID int64    `json:"user_id"`
+	Email  string   `json:"email"`
+	Roles  []string `json:"roles,omitempty"`
+	// Scope is a space-delimited list of granted scopes (RFC 8693)
+	Scope string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// HasRole reports whether the claims grant role
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// Scopes returns the granted scopes
+func (c *Claims) Scopes() []string {
+	return strings.Fields(c.Scope)
+}
+
+// HasScope reports whether the claims grant scope
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes(), scope)
+}
+
+type contextKey struct{}
+
+// WithClaims returns a copy of ctx carrying the authenticated claims
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// ClaimsFromContext returns the authenticated claims stored in ctx, if any
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(*Claims)
+	return claims, ok
+}
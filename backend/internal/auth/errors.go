@@ -0,0 +1,14 @@
+package auth
+
+import "errors"
+
+// Predefined errors
+var (
+	ErrEmptySecret  = errors.New("auth: JWT secret cannot be empty")
+	ErrEmptyToken   = errors.New("auth: token string cannot be empty")
+	ErrInvalidToken = errors.New("auth: invalid token")
+	ErrTokenExpired = errors.New("auth: token expired")
+
+	ErrInvalidCredentials = errors.New("auth: invalid email or password")
+	ErrPasswordTooLong    = errors.New("auth: password exceeds 72 bytes")
+)
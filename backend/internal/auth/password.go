@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxPasswordLength is the longest password bcrypt can hash without truncation
+const MaxPasswordLength = 72
+
+// dummyHashes caches by cost the hashes CheckLogin compares against when a
+// login names an unknown account
+var dummyHashes sync.Map
+
+// dummyHash returns a hash at cost, generating it on first use. Checking a
+// password against it takes as long as against a real hash at that cost.
+func dummyHash(cost int) []byte {
+	if hash, ok := dummyHashes.Load(cost); ok {
+		return hash.([]byte)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte("dummy password"), cost)
+	if err != nil {
+		// Only an invalid cost fails, which real hashes would hit too
+		hash, _ = bcrypt.GenerateFromPassword([]byte("dummy password"), bcrypt.DefaultCost)
+	}
+	actual, _ := dummyHashes.LoadOrStore(cost, hash)
+	return actual.([]byte)
+}
+
+// HashPassword hashes password with bcrypt at the given cost
+func HashPassword(password string, cost int) (string, error) {
+	if len(password) > MaxPasswordLength {
+		return "", ErrPasswordTooLong
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", fmt.Errorf("auth: hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether password matches hash. An empty hash never
+// matches.
+func CheckPassword(hash, password string) error {
+	if hash == "" {
+		return ErrInvalidCredentials
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return ErrInvalidCredentials
+	}
+	if err != nil {
+		return fmt.Errorf("auth: check password: %w", err)
+	}
+	return nil
+}
+
+// CheckLogin is CheckPassword for a login, where hash is empty when no
+// account has the email given. Such logins are checked against a dummy hash
+// at cost, the cost passwords are hashed with, so the response time does not
+// reveal which emails are registered.
+func CheckLogin(hash, password string, cost int) error {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyHash(cost), []byte(password))
+		return ErrInvalidCredentials
+	}
+	return CheckPassword(hash, password)
+}
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswords(t *testing.T) {
+	hash, err := HashPassword("correct horse", bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("HashPassword() failed: %v", err)
+	}
+	if hash == "correct horse" {
+		t.Fatal("Expected password to be hashed")
+	}
+
+	if err := CheckPassword(hash, "correct horse"); err != nil {
+		t.Errorf("Expected matching password, got %v", err)
+	}
+	if err := CheckPassword(hash, "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if err := CheckPassword("", "anything"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected empty hash to never match, got %v", err)
+	}
+	if _, err := HashPassword(strings.Repeat("x", 73), bcrypt.MinCost); !errors.Is(err, ErrPasswordTooLong) {
+		t.Errorf("Expected ErrPasswordTooLong, got %v", err)
+	}
+}
+
+func TestCheckLoginUnknownAccount(t *testing.T) {
+	const cost = bcrypt.MinCost + 2
+	if err := CheckLogin("", "dummy password", cost); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected unknown accounts to never match, got %v", err)
+	}
+	// The dummy hash costs as much to check as a real one
+	if got, err := bcrypt.Cost(dummyHash(cost)); err != nil || got != cost {
+		t.Errorf("Expected dummy hash at cost %d, got %d (%v)", cost, got, err)
+	}
+
+	hash, _ := HashPassword("correct horse", bcrypt.MinCost)
+	if err := CheckLogin(hash, "correct horse", cost); err != nil {
+		t.Errorf("Expected matching password, got %v", err)
+	}
+}
+
+func TestRefreshTokens(t *testing.T) {
+	token, hash, err := NewRefreshToken()
+	if err != nil {
+		t.Fatalf("NewRefreshToken() failed: %v", err)
+	}
+	if HashRefreshToken(token) != hash {
+		t.Error("Expected hash to be derived from the token")
+	}
+	other, _, _ := NewRefreshToken()
+	if other == token {
+		t.Error("Expected tokens to be random")
+	}
+}
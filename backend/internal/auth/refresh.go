@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// refreshTokenBytes is the entropy of generated refresh tokens
+const refreshTokenBytes = 32
+
+// NewRefreshToken returns a random opaque refresh token and the hash to store
+func NewRefreshToken() (token, hash string, err error) {
+	b := make([]byte, refreshTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// HashRefreshToken returns the stored form of a refresh token. Tokens carry
+// enough entropy that an unsalted SHA-256 is sufficient.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+)
+
+// TokenService issues and verifies HS256-signed access tokens
+type TokenService struct {
+	secret []byte
+	issuer string
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// NewTokenService creates a token service from the auth configuration
+func NewTokenService(cfg config.AuthConfig) (*TokenService, error) {
+	if cfg.JWTSecret == "" {
+		return nil, ErrEmptySecret
+	}
+	return &TokenService{
+		secret: []byte(cfg.JWTSecret),
+		issuer: cfg.Issuer,
+		ttl:    cfg.AccessTokenTTL,
+		now:    time.Now,
+	}, nil
+}
+
+// TTL returns how long issued tokens stay valid
+func (s *TokenService) TTL() time.Duration {
+	return s.ttl
+}
+
+// Issue signs an access token for the given claims, filling in the subject,
+// issuer, issue time and expiry
+func (s *TokenService) Issue(claims Claims) (string, time.Time, error) {
+	return s.IssueWithTTL(claims, s.ttl)
+}
+
+// IssueWithTTL is like Issue with a custom lifetime
+func (s *TokenService) IssueWithTTL(claims Claims, ttl time.Duration) (string, time.Time, error) {
+	now := s.now()
+	expiresAt := now.Add(ttl)
+	claims.Subject = strconv.FormatInt(claims.UserID, 10)
+	claims.Issuer = s.issuer
+	claims.IssuedAt = jwt.NewNumericDate(now)
+	claims.NotBefore = jwt.NewNumericDate(now)
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &claims).SignedString(s.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("auth: sign token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// Parse verifies the token's signature, algorithm, issuer and lifetime and
+// returns its claims
+func (s *TokenService) Parse(tokenString string) (*Claims, error) {
+	if tokenString == "" {
+		return nil, ErrEmptyToken
+	}
+
+	claims := &Claims{}
+	parser := jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}}
+	_, err := parser.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	})
+	if err != nil {
+		var verr *jwt.ValidationError
+		if errors.As(err, &verr) && verr.Errors&jwt.ValidationErrorExpired != 0 {
+			return nil, ErrTokenExpired
+		}
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if s.issuer != "" && !claims.VerifyIssuer(s.issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if claims.UserID <= 0 {
+		return nil, fmt.Errorf("%w: missing user_id", ErrInvalidToken)
+	}
+	return claims, nil
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+)
+
+func testTokenService(t *testing.T) *TokenService {
+	t.Helper()
+	s, err := NewTokenService(config.AuthConfig{
+		JWTSecret:      "test-secret",
+		Issuer:         "test",
+		AccessTokenTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("NewTokenService() failed: %v", err)
+	}
+	return s
+}
+
+func TestNewTokenService(t *testing.T) {
+	if _, err := NewTokenService(config.AuthConfig{}); !errors.Is(err, ErrEmptySecret) {
+		t.Errorf("Expected ErrEmptySecret, got %v", err)
+	}
+}
+
+func TestIssueAndParse(t *testing.T) {
+	s := testTokenService(t)
+
+	token, expiresAt, err := s.Issue(Claims{UserID: 7, Email: "a@b.c", Roles: []string{RoleAdmin}, Scope: "habits:read habits:write"})
+	if err != nil {
+		t.Fatalf("Issue() failed: %v", err)
+	}
+	if time.Until(expiresAt) > time.Minute {
+		t.Errorf("Expected expiry within the TTL, got %v", expiresAt)
+	}
+
+	claims, err := s.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if claims.UserID != 7 || claims.Email != "a@b.c" || claims.Subject != "7" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+	if !claims.HasRole(RoleAdmin) || claims.HasRole(RoleUser) {
+		t.Errorf("Unexpected roles: %v", claims.Roles)
+	}
+	if !claims.HasScope("habits:write") || claims.HasScope("habits") {
+		t.Errorf("Unexpected scopes: %v", claims.Scopes())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	s := testTokenService(t)
+
+	expired := testTokenService(t)
+	expired.now = func() time.Time { return time.Now().Add(-time.Hour) }
+	expiredToken, _, _ := expired.Issue(Claims{UserID: 1})
+
+	other, _ := NewTokenService(config.AuthConfig{JWTSecret: "other-secret", Issuer: "test", AccessTokenTTL: time.Minute})
+	forgedToken, _, _ := other.Issue(Claims{UserID: 1})
+
+	foreign, _ := NewTokenService(config.AuthConfig{JWTSecret: "test-secret", Issuer: "elsewhere", AccessTokenTTL: time.Minute})
+	foreignToken, _, _ := foreign.Issue(Claims{UserID: 1})
+
+	noneToken, _ := jwt.NewWithClaims(jwt.SigningMethodNone, &Claims{UserID: 1}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "empty", token: "", want: ErrEmptyToken},
+		{name: "garbage", token: "not.a.jwt", want: ErrInvalidToken},
+		{name: "expired", token: expiredToken, want: ErrTokenExpired},
+		{name: "wrong secret", token: forgedToken, want: ErrInvalidToken},
+		{name: "wrong issuer", token: foreignToken, want: ErrInvalidToken},
+		{name: "alg none", token: noneToken, want: ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Parse(tt.token); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -1,24 +1,31 @@
 package config
 
 import (
+	"flag"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
 	// Test default values
-	cfg := Load()
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
 
 	if cfg.Env != "development" {
 		t.Errorf("Expected default env to be 'development', got '%s'", cfg.Env)
 	}
 
-	if cfg.Port != "8080" {
-		t.Errorf("Expected default port to be '8080', got '%s'", cfg.Port)
+	if cfg.Server.Port != "8080" {
+		t.Errorf("Expected default port to be '8080', got '%s'", cfg.Server.Port)
 	}
 
-	if cfg.JWTSecret != "your-jwt-secret-key" {
-		t.Errorf("Expected default JWT secret to be 'your-jwt-secret-key', got '%s'", cfg.JWTSecret)
+	if cfg.Auth.JWTSecret != "your-jwt-secret-key" {
+		t.Errorf("Expected default JWT secret to be 'your-jwt-secret-key', got '%s'", cfg.Auth.JWTSecret)
 	}
 }
 
@@ -35,18 +42,21 @@ func TestLoadWithEnvVars(t *testing.T) {
 		os.Unsetenv("JWT_SECRET")
 	}()
 
-	cfg := Load()
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
 
 	if cfg.Env != "test" {
 		t.Errorf("Expected env to be 'test', got '%s'", cfg.Env)
 	}
 
-	if cfg.Port != "9000" {
-		t.Errorf("Expected port to be '9000', got '%s'", cfg.Port)
+	if cfg.Server.Port != "9000" {
+		t.Errorf("Expected port to be '9000', got '%s'", cfg.Server.Port)
 	}
 
-	if cfg.JWTSecret != "test-secret" {
-		t.Errorf("Expected JWT secret to be 'test-secret', got '%s'", cfg.JWTSecret)
+	if cfg.Auth.JWTSecret != "test-secret" {
+		t.Errorf("Expected JWT secret to be 'test-secret', got '%s'", cfg.Auth.JWTSecret)
 	}
 }
 
@@ -83,3 +93,391 @@ func TestGetEnvAsBool(t *testing.T) {
 		t.Errorf("Expected fallback value false, got %v", result)
 	}
 }
+
+func TestLoadFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.yaml")
+	content := `
+server:
+  port: "7000"
+  shutdown_timeout: 3s
+database:
+  url: sqlite:app.db
+cors:
+  origins: [https://example.com]
+logging:
+  format: json
+features:
+  flags:
+    new_dashboard:
+      enabled: true
+      rollout: 25
+      users: [7]
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("PORT", "7001")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	// Environment variables win over the file
+	if cfg.Server.Port != "7001" {
+		t.Errorf("Expected port '7001', got '%s'", cfg.Server.Port)
+	}
+	if cfg.Server.ShutdownTimeout != 3*time.Second {
+		t.Errorf("Expected shutdown timeout 3s, got %v", cfg.Server.ShutdownTimeout)
+	}
+	if cfg.Database.URL != "sqlite:app.db" {
+		t.Errorf("Expected database URL 'sqlite:app.db', got '%s'", cfg.Database.URL)
+	}
+	if len(cfg.CORS.Origins) != 1 || cfg.CORS.Origins[0] != "https://example.com" {
+		t.Errorf("Expected CORS origins [https://example.com], got %v", cfg.CORS.Origins)
+	}
+	if cfg.Logging.Format != "json" {
+		t.Errorf("Expected log format 'json', got '%s'", cfg.Logging.Format)
+	}
+	if cfg.File != path {
+		t.Errorf("Expected the loaded file '%s', got '%s'", path, cfg.File)
+	}
+	if f := cfg.Features.Flags["new_dashboard"]; !f.Enabled || f.Rollout == nil || *f.Rollout != 25 || len(f.Users) != 1 {
+		t.Errorf("Expected the new_dashboard flag from the file, got %+v", f)
+	}
+	// Values absent from the file keep their defaults
+	if cfg.Database.MaxOpenConns != 25 {
+		t.Errorf("Expected default max open conns 25, got %d", cfg.Database.MaxOpenConns)
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("missing explicit file", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", filepath.Join(dir, "missing.yaml"))
+		if _, err := Load(); err == nil {
+			t.Error("Expected error for missing config file, got none")
+		}
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		path := filepath.Join(dir, "typo.yaml")
+		os.WriteFile(path, []byte("server:\n  prot: \"9000\"\n"), 0o644)
+		t.Setenv("CONFIG_FILE", path)
+		if _, err := Load(); err == nil {
+			t.Error("Expected error for unknown field, got none")
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		path := filepath.Join(dir, "app.toml")
+		os.WriteFile(path, []byte("[server]\nport = \"9000\"\n"), 0o644)
+		t.Setenv("CONFIG_FILE", path)
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "YAML") {
+			t.Errorf("Expected error naming YAML as the supported format, got %v", err)
+		}
+	})
+}
+
+func TestLoadMalformedEnv(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TOKEN_TTL", "900")
+	t.Setenv("DATABASE_MAX_OPEN_CONNS", "ten")
+	t.Setenv("METRICS_ENABLED", "yes please")
+	// Empty values count as unset
+	t.Setenv("BCRYPT_COST", "")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("Expected error for malformed environment variables, got none")
+	}
+	for _, want := range []string{`JWT_ACCESS_TOKEN_TTL: "900"`, `DATABASE_MAX_OPEN_CONNS: "ten"`, `METRICS_ENABLED: "yes please"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %s, got %v", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "BCRYPT_COST") {
+		t.Errorf("Expected empty BCRYPT_COST to be ignored, got %v", err)
+	}
+}
+
+func TestLoadFromFlags(t *testing.T) {
+	t.Setenv("PORT", "9000")
+	t.Setenv("CORS_ORIGINS", "http://a.example.com")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	RegisterFlags(fs)
+	if err := fs.Parse([]string{"--port", "9100", "--cors-origins", "https://x.dev, https://y.dev"}); err != nil {
+		t.Fatalf("Failed to parse flags: %v", err)
+	}
+
+	cfg, err := LoadFrom(fs)
+	if err != nil {
+		t.Fatalf("LoadFrom() failed: %v", err)
+	}
+
+	if cfg.Server.Port != "9100" {
+		t.Errorf("Expected flag to override port, got '%s'", cfg.Server.Port)
+	}
+	if len(cfg.CORS.Origins) != 2 || cfg.CORS.Origins[1] != "https://y.dev" {
+		t.Errorf("Expected CORS origins from flag, got %v", cfg.CORS.Origins)
+	}
+	// Unset flags must not clobber lower layers
+	if cfg.Auth.JWTSecret != DefaultJWTSecret {
+		t.Errorf("Expected default JWT secret, got '%s'", cfg.Auth.JWTSecret)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(*Config)
+		wantErr string
+	}{
+		{
+			name:   "development defaults",
+			modify: func(c *Config) {},
+		},
+		{
+			name: "production with default secret",
+			modify: func(c *Config) {
+				c.Env = EnvProduction
+			},
+			wantErr: "auth.jwt_secret",
+		},
+		{
+			name: "production with short secret",
+			modify: func(c *Config) {
+				c.Env = EnvProduction
+				c.Auth.JWTSecret = "short"
+			},
+			wantErr: "at least 32 characters",
+		},
+		{
+			name: "production with strong secret",
+			modify: func(c *Config) {
+				c.Env = EnvProduction
+				c.Auth.JWTSecret = strings.Repeat("s", 32)
+			},
+		},
+		{
+			name: "malformed database URL",
+			modify: func(c *Config) {
+				c.Database.URL = "://nope"
+			},
+			wantErr: "database.url",
+		},
+		{
+			name: "unsupported database scheme",
+			modify: func(c *Config) {
+				c.Database.URL = "mysql://localhost/db"
+			},
+			wantErr: "unsupported scheme",
+		},
+		{
+			name: "origin with path",
+			modify: func(c *Config) {
+				c.CORS.Origins = []string{"https://example.com/app"}
+			},
+			wantErr: "cors.origins",
+		},
+		{
+			name: "wildcard subdomain origin",
+			modify: func(c *Config) {
+				c.CORS.Origins = []string{"https://*.github.io"}
+			},
+		},
+		{
+			name: "misplaced wildcard origin",
+			modify: func(c *Config) {
+				c.CORS.Origins = []string{"https://app.*.io"}
+			},
+			wantErr: "leftmost label",
+		},
+		{
+			name: "any origin with credentials",
+			modify: func(c *Config) {
+				c.CORS.Origins = []string{"*"}
+			},
+			wantErr: "allow_credentials",
+		},
+		{
+			name: "route override without leading slash",
+			modify: func(c *Config) {
+				c.CORS.Routes = []CORSRoute{{Path: "api/v1/public"}}
+			},
+			wantErr: "cors.routes[0].path",
+		},
+		{
+			name: "metrics admin port clashes with server port",
+			modify: func(c *Config) {
+				c.Metrics.AdminPort = c.Server.Port
+			},
+			wantErr: "metrics.admin_port",
+		},
+		{
+			name: "rate limit policy with unknown key",
+			modify: func(c *Config) {
+				c.RateLimit.Policies["login"] = RateLimitPolicy{Requests: 5, Per: time.Minute, Key: "email"}
+			},
+			wantErr: "rate_limit.policies.login.key",
+		},
+		{
+			name: "rate limit policy without interval",
+			modify: func(c *Config) {
+				c.RateLimit.Policies["login"] = RateLimitPolicy{Requests: 5, Key: RateLimitByIP}
+			},
+			wantErr: "requests and per must be positive",
+		},
+		{
+			name: "invalid rate limit ignored when disabled",
+			modify: func(c *Config) {
+				c.RateLimit.Enabled = false
+				c.RateLimit.Policies["login"] = RateLimitPolicy{}
+			},
+		},
+		{
+			name: "jobs without lease duration",
+			modify: func(c *Config) {
+				c.Jobs.LeaseDuration = 0
+			},
+			wantErr: "jobs: poll_interval, lease_duration and retry_backoff must be positive",
+		},
+		{
+			name: "jobs with zero max attempts",
+			modify: func(c *Config) {
+				c.Jobs.MaxAttempts = 0
+			},
+			wantErr: "jobs.max_attempts",
+		},
+		{
+			name: "unknown idempotency store",
+			modify: func(c *Config) {
+				c.Idempotency.Store = "redis"
+			},
+			wantErr: "idempotency.store",
+		},
+		{
+			name: "idempotency without ttl",
+			modify: func(c *Config) {
+				c.Idempotency.TTL = 0
+			},
+			wantErr: "idempotency: ttl and lock_timeout must be positive",
+		},
+		{
+			name: "negative compression threshold",
+			modify: func(c *Config) {
+				c.Responses.CompressMinSize = -1
+			},
+			wantErr: "responses.compress_min_size",
+		},
+		{
+			name: "invalid flag name",
+			modify: func(c *Config) {
+				c.Features.Flags = map[string]FlagConfig{"New Dashboard": {Enabled: true}}
+			},
+			wantErr: "features.flags: invalid name",
+		},
+		{
+			name: "rollout above 100",
+			modify: func(c *Config) {
+				rollout := 150
+				c.Features.Flags = map[string]FlagConfig{"new_dashboard": {Enabled: true, Rollout: &rollout}}
+			},
+			wantErr: "features.flags.new_dashboard.rollout",
+		},
+		{
+			name: "drain delay exceeds shutdown timeout",
+			modify: func(c *Config) {
+				c.Server.DrainDelay = c.Server.ShutdownTimeout
+			},
+			wantErr: "server.drain_delay",
+		},
+		{
+			name: "trusted proxy CIDRs",
+			modify: func(c *Config) {
+				c.Server.TrustedProxies = []string{"10.0.0.0/8", "::1"}
+			},
+		},
+		{
+			name: "malformed trusted proxy",
+			modify: func(c *Config) {
+				c.Server.TrustedProxies = []string{"10.0.0.0/33"}
+			},
+			wantErr: "server.trusted_proxies",
+		},
+		{
+			name: "invalid port",
+			modify: func(c *Config) {
+				c.Server.Port = "http"
+			},
+			wantErr: "server.port",
+		},
+		{
+			name: "unknown environment",
+			modify: func(c *Config) {
+				c.Env = "staging"
+			},
+			wantErr: "unknown environment",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			tt.modify(cfg)
+			err := cfg.Validate()
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestShippedConfigFiles(t *testing.T) {
+	for _, env := range []string{EnvDevelopment, EnvTest, EnvProduction} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv("ENV", env)
+			t.Setenv("CONFIG_FILE", filepath.Join("..", "..", DefaultConfigDir, env+".yaml"))
+			if _, err := Load(); err != nil {
+				t.Errorf("Failed to load %s config: %v", env, err)
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{"password in user info", "postgres://app:s3cret@db:5432/app?sslmode=disable", "postgres://app:REDACTED@db:5432/app?sslmode=disable"},
+		{"password parameter", "postgres://db/app?password=s3cret&user=app", "postgres://db/app?password=REDACTED&user=app"},
+		{"no password", "sqlite:app.db", "sqlite:app.db"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Database.URL = tt.url
+			got := cfg.Redacted()
+			if got.Database.URL != tt.want {
+				t.Errorf("Expected '%s', got '%s'", tt.want, got.Database.URL)
+			}
+			if got.Auth.JWTSecret != "REDACTED" {
+				t.Errorf("Expected the JWT secret to be redacted, got '%s'", got.Auth.JWTSecret)
+			}
+			if cfg.Database.URL != tt.url || cfg.Auth.JWTSecret != DefaultJWTSecret {
+				t.Error("Expected the original config to be unchanged")
+			}
+		})
+	}
+}
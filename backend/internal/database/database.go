@@ -0,0 +1,126 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+)
+
+// Dialect identifies the SQL flavour spoken by a connection
+type Dialect string
+
+// Supported dialects
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// DB wraps a connection pool together with its dialect
+type DB struct {
+	*sql.DB
+	Dialect Dialect
+}
+
+// Open connects to the database described by cfg and verifies the connection
+func Open(cfg config.DatabaseConfig) (*DB, error) {
+	dialect, dsn, err := ParseURL(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	driver := "postgres"
+	if dialect == SQLite {
+		driver = "sqlite3"
+	}
+	sqlDB, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("database: open: %w", err)
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	if err := sqlDB.PingContext(context.Background()); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("database: ping: %w", err)
+	}
+	return &DB{DB: sqlDB, Dialect: dialect}, nil
+}
+
+// ParseURL maps a configuration URL to a dialect and driver DSN.
+// Postgres URLs are passed through; sqlite:PATH (or sqlite://PATH) becomes a
+// go-sqlite3 DSN with foreign keys and a busy timeout enabled.
+func ParseURL(raw string) (Dialect, string, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", "", fmt.Errorf("database: malformed URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "postgres", "postgresql":
+		return Postgres, raw, nil
+	case "sqlite":
+		dsn := strings.TrimPrefix(strings.TrimPrefix(raw, "sqlite:"), "//")
+		if dsn == "" {
+			return "", "", fmt.Errorf("database: missing sqlite path in %q", raw)
+		}
+		return SQLite, withSQLiteDefaults(dsn), nil
+	default:
+		return "", "", fmt.Errorf("database: unsupported scheme %q", u.Scheme)
+	}
+}
+
+// withSQLiteDefaults adds connection options the application relies on
+func withSQLiteDefaults(dsn string) string {
+	path, query, _ := strings.Cut(dsn, "?")
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return dsn
+	}
+	if params.Get("_foreign_keys") == "" {
+		params.Set("_foreign_keys", "on")
+	}
+	if params.Get("_busy_timeout") == "" {
+		params.Set("_busy_timeout", "5000")
+	}
+	return path + "?" + params.Encode()
+}
+
+// Rebind rewrites ? placeholders into the dialect's native form
+func (db *DB) Rebind(query string) string {
+	return db.Dialect.Rebind(query)
+}
+
+// Rebind rewrites ? placeholders into the dialect's native form.
+// Placeholders inside single-quoted literals are left untouched.
+func (d Dialect) Rebind(query string) string {
+	if d != Postgres {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n, quoted := 0, false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+// Package dbtest provides migrated databases for tests.
+package dbtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/migrate"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/migrations"
+)
+
+// PostgresURLEnv names the variable holding a Postgres URL for tests; the
+// database it points at is migrated and shared, so use a disposable one
+const PostgresURLEnv = "TEST_POSTGRES_URL"
+
+// SQLite returns a fully migrated SQLite database in a temporary directory
+func SQLite(t testing.TB) *database.DB {
+	t.Helper()
+	return open(t, "sqlite:"+filepath.Join(t.TempDir(), "test.db"))
+}
+
+// Postgres returns the migrated database named by TEST_POSTGRES_URL, or
+// skips the test when it is unset
+func Postgres(t testing.TB) *database.DB {
+	t.Helper()
+	url := os.Getenv(PostgresURLEnv)
+	if url == "" {
+		t.Skipf("%s not set", PostgresURLEnv)
+	}
+	return open(t, url)
+}
+
+func open(t testing.TB, url string) *database.DB {
+	t.Helper()
+	db, err := database.Open(config.DatabaseConfig{URL: url, MaxOpenConns: 4})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("Failed to load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+	return db
+}
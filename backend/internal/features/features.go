@@ -0,0 +1,207 @@
+// Package features evaluates the feature flags defined in the config file.
+// Definitions are replaced atomically on reload, so a request sees either
+// the old or the new set, never a mix.
+package features
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/lifecycle"
+)
+
+// Loader returns the current flag definitions, typically by re-reading and
+// validating the config file
+type Loader func() (config.FeaturesConfig, error)
+
+// Flags holds the active flag definitions
+type Flags struct {
+	defs   atomic.Pointer[map[string]config.FlagConfig]
+	load   Loader
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates flags from cfg. load is called by Reload; it may be nil when
+// the flags never change.
+func New(cfg config.FeaturesConfig, load Loader, logger *slog.Logger) *Flags {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	f := &Flags{load: load, logger: logger}
+	f.set(cfg.Flags)
+	return f
+}
+
+// set makes defs the active definitions
+func (f *Flags) set(defs map[string]config.FlagConfig) {
+	if defs == nil {
+		defs = map[string]config.FlagConfig{}
+	}
+	f.defs.Store(&defs)
+}
+
+// Enabled reports whether the flag is on for the user. Unknown flags are
+// off; userID 0 stands for an anonymous request.
+func (f *Flags) Enabled(name string, userID int64) bool {
+	def, ok := (*f.defs.Load())[name]
+	return ok && evaluate(name, def, userID)
+}
+
+// For returns every flag with its state for the user
+func (f *Flags) For(userID int64) map[string]bool {
+	defs := *f.defs.Load()
+	states := make(map[string]bool, len(defs))
+	for name, def := range defs {
+		states[name] = evaluate(name, def, userID)
+	}
+	return states
+}
+
+// Reload replaces the definitions with those returned by the loader. On
+// error the current definitions stay active.
+func (f *Flags) Reload() error {
+	if f.load == nil {
+		return errors.New("features: no loader")
+	}
+	cfg, err := f.load()
+	if err != nil {
+		return fmt.Errorf("features: reload: %w", err)
+	}
+	f.set(cfg.Flags)
+	f.logger.Info("feature flags reloaded", "flags", len(cfg.Flags))
+	return nil
+}
+
+// Start reloads the flags on SIGHUP and, when interval is positive, whenever
+// the file at path changes. Failed reloads are logged.
+func (f *Flags) Start(path string, interval time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.cancel != nil {
+		return errors.New("features: already started")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	f.cancel, f.done = cancel, make(chan struct{})
+
+	last := digest(path)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		defer close(f.done)
+		defer signal.Stop(hup)
+		var tick <-chan time.Time
+		if path != "" && interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				last = digest(path)
+				f.reload("signal")
+			case <-tick:
+				// Comparing content picks up edits that keep the size and
+				// land within the file system's timestamp granularity
+				if current := digest(path); current != last {
+					last = current
+					f.reload("file changed")
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// reload reloads and logs a failure
+func (f *Flags) reload(reason string) {
+	if err := f.Reload(); err != nil {
+		f.logger.Error("feature flag reload failed, keeping the current flags", "reason", reason, "error", err)
+	}
+}
+
+// Stop stops watching for changes
+func (f *Flags) Stop(ctx context.Context) error {
+	f.mu.Lock()
+	cancel, done := f.cancel, f.done
+	f.cancel = nil
+	f.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Component returns a lifecycle component watching the file at path
+func (f *Flags) Component(name, path string, interval time.Duration) lifecycle.Component {
+	return lifecycle.Component{
+		Name:  name,
+		Start: func(context.Context) error { return f.Start(path, interval) },
+		Stop:  f.Stop,
+	}
+}
+
+// digest returns the SHA-256 of the file at path; zero when it cannot be
+// read or path is empty
+func digest(path string) [sha256.Size]byte {
+	if path == "" {
+		return [sha256.Size]byte{}
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return [sha256.Size]byte{}
+	}
+	return sha256.Sum256(b)
+}
+
+// evaluate applies def to the user: listed users always get an enabled flag,
+// a rollout covers a stable percentage of the others and a flag with neither
+// is on for everyone
+func evaluate(name string, def config.FlagConfig, userID int64) bool {
+	switch {
+	case !def.Enabled:
+		return false
+	case userID != 0 && slices.Contains(def.Users, userID):
+		return true
+	case def.Rollout != nil:
+		return userID != 0 && bucket(name, userID) < *def.Rollout
+	default:
+		return len(def.Users) == 0
+	}
+}
+
+// bucket places the user in 0..99 for the flag. Hashing the flag name too
+// gives each flag its own sample of users, and raising a rollout only adds
+// users.
+func bucket(name string, userID int64) int {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write(strconv.AppendInt(nil, userID, 10))
+	return int(h.Sum32() % 100)
+}
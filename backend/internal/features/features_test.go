@@ -0,0 +1,174 @@
+package features
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+)
+
+func rollout(p int) *int {
+	return &p
+}
+
+func TestEnabled(t *testing.T) {
+	flags := New(config.FeaturesConfig{Flags: map[string]config.FlagConfig{
+		"on":         {Enabled: true},
+		"off":        {Enabled: false, Users: []int64{1}},
+		"allow_list": {Enabled: true, Users: []int64{1, 2}},
+		"everyone":   {Enabled: true, Rollout: rollout(100)},
+		"nobody":     {Enabled: true, Rollout: rollout(0), Users: []int64{3}},
+	}}, nil, nil)
+
+	tests := []struct {
+		name   string
+		flag   string
+		userID int64
+		want   bool
+	}{
+		{"boolean on", "on", 5, true},
+		{"boolean on anonymous", "on", 0, true},
+		{"disabled wins over the allow list", "off", 1, false},
+		{"listed user", "allow_list", 2, true},
+		{"unlisted user", "allow_list", 5, false},
+		{"full rollout", "everyone", 5, true},
+		{"rollout skips anonymous", "everyone", 0, false},
+		{"zero rollout", "nobody", 5, false},
+		{"allow list beats rollout", "nobody", 3, true},
+		{"unknown flag", "missing", 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := flags.Enabled(tt.flag, tt.userID); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	states := flags.For(2)
+	if len(states) != 5 || !states["allow_list"] || states["off"] {
+		t.Errorf("Expected every flag evaluated for user 2, got %v", states)
+	}
+}
+
+func TestRollout(t *testing.T) {
+	const users = 10000
+	on := func(flag string, percent int) map[int64]bool {
+		flags := New(config.FeaturesConfig{Flags: map[string]config.FlagConfig{
+			flag: {Enabled: true, Rollout: rollout(percent)},
+		}}, nil, nil)
+		enabled := make(map[int64]bool)
+		for id := int64(1); id <= users; id++ {
+			if flags.Enabled(flag, id) {
+				enabled[id] = true
+			}
+		}
+		return enabled
+	}
+
+	quarter := on("new_dashboard", 25)
+	if n := len(quarter); n < users*22/100 || n > users*28/100 {
+		t.Errorf("Expected about 25%% of users, got %d of %d", n, users)
+	}
+	half := on("new_dashboard", 50)
+	for id := range quarter {
+		if !half[id] {
+			t.Fatalf("Expected user %d to stay enabled when the rollout grows", id)
+		}
+	}
+
+	same := 0
+	other := on("dark_mode", 25)
+	for id := range quarter {
+		if other[id] {
+			same++
+		}
+	}
+	if same > len(quarter)/2 {
+		t.Errorf("Expected flags to sample different users, %d of %d overlap", same, len(quarter))
+	}
+}
+
+func TestReload(t *testing.T) {
+	var next config.FeaturesConfig
+	var loadErr error
+	flags := New(config.FeaturesConfig{Flags: map[string]config.FlagConfig{"beta": {Enabled: true}}},
+		func() (config.FeaturesConfig, error) { return next, loadErr }, nil)
+
+	next = config.FeaturesConfig{Flags: map[string]config.FlagConfig{"beta": {Enabled: false}}}
+	if err := flags.Reload(); err != nil {
+		t.Fatalf("Reload() failed: %v", err)
+	}
+	if flags.Enabled("beta", 1) {
+		t.Error("Expected beta to be off after the reload")
+	}
+
+	next, loadErr = config.FeaturesConfig{Flags: map[string]config.FlagConfig{"beta": {Enabled: true}}}, errors.New("bad file")
+	if err := flags.Reload(); err == nil {
+		t.Error("Expected the loader error")
+	}
+	if flags.Enabled("beta", 1) {
+		t.Error("Expected a failed reload to keep the current flags")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+	write("features:\n  flags:\n    beta:\n      enabled: false\n")
+	t.Setenv("CONFIG_FILE", path)
+	load := func() (config.FeaturesConfig, error) {
+		cfg, err := config.Load()
+		if err != nil {
+			return config.FeaturesConfig{}, err
+		}
+		return cfg.Features, nil
+	}
+	initial, _ := load()
+	flags := New(initial, load, nil)
+	if err := flags.Start(path, 10*time.Millisecond); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	t.Cleanup(func() { flags.Stop(context.Background()) })
+
+	waitFor := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for flags.Enabled("beta", 1) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected beta to become %v", want)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	write("features:\n  flags:\n    beta:\n      enabled: true\n")
+	waitFor(true)
+
+	// An invalid file keeps the flags that are active
+	write("features:\n  flags:\n    beta:\n      enabeld: false\n")
+	time.Sleep(50 * time.Millisecond)
+	waitFor(true)
+
+	// SIGHUP reloads even when the file looks unchanged
+	if err := flags.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+	write("features:\n  flags:\n    beta:\n      enabled: false\n")
+	if err := flags.Start(path, 0); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("Failed to send SIGHUP: %v", err)
+	}
+	waitFor(false)
+}
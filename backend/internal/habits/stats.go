@@ -0,0 +1,140 @@
+// Package habits computes streaks and completion rates of habits from their
+// check-ins. All dates are calendar days in the owner's time zone.
+package habits
+
+import (
+	"math"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+)
+
+// Units in which streaks are counted
+const (
+	UnitDay  = "day"
+	UnitWeek = "week"
+)
+
+// Stats summarises how well a habit was kept
+type Stats struct {
+	From           models.Date `json:"from" format:"date"`
+	To             models.Date `json:"to" format:"date"`
+	Unit           string      `json:"unit" enum:"day,week" doc:"unit of the streaks: scheduled days, or weeks for weekly habits"`
+	CurrentStreak  int         `json:"current_streak" example:"4"`
+	LongestStreak  int         `json:"longest_streak" example:"12"`
+	Due            int         `json:"due" doc:"check-ins expected in the range"`
+	Completed      int         `json:"completed" doc:"expected check-ins that were made"`
+	CompletionRate float64     `json:"completion_rate" doc:"completed / due, 0 when nothing was due" example:"0.75"`
+}
+
+// period is a stretch of days in which target check-ins are due: a single
+// scheduled day, or a Monday-to-Sunday week for weekly habits
+type period struct {
+	first, last models.Date
+	target      int
+}
+
+// count returns the check-ins made within the period
+func (p period) count(done map[models.Date]bool) int {
+	n := 0
+	for d := p.first; !d.After(p.last); d = d.AddDays(1) {
+		if done[d] {
+			n++
+		}
+	}
+	return n
+}
+
+// contains reports whether d falls within the period
+func (p period) contains(d models.Date) bool {
+	return !d.Before(p.first) && !d.After(p.last)
+}
+
+// Compute returns the stats of a habit following schedule since start, with
+// check-ins on the given days. Streaks cover the whole history up to today;
+// Due, Completed and CompletionRate cover from..to, capped at today.
+//
+// Days the schedule does not include neither extend nor break a streak, and
+// the period containing today only counts once it is complete, so a habit
+// not yet done today keeps its streak.
+func Compute(schedule models.HabitSchedule, start models.Date, checkIns []models.Date, from, to, today models.Date) Stats {
+	done := make(map[models.Date]bool, len(checkIns))
+	for _, d := range checkIns {
+		done[d] = true
+	}
+
+	stats := Stats{From: from, To: to, Unit: UnitDay}
+	if schedule.Kind == models.ScheduleWeekly {
+		stats.Unit = UnitWeek
+	}
+
+	run := 0
+	for _, p := range periods(schedule, start, today) {
+		switch {
+		case p.count(done) >= p.target:
+			run++
+			stats.LongestStreak = max(stats.LongestStreak, run)
+		case p.contains(today):
+			// Still in progress
+		default:
+			run = 0
+		}
+	}
+	stats.CurrentStreak = run
+
+	rangeFrom, rangeTo := from, to
+	if rangeFrom.Before(start) {
+		rangeFrom = start
+	}
+	if rangeTo.After(today) {
+		rangeTo = today
+	}
+	for _, p := range periods(schedule, rangeFrom, rangeTo) {
+		n := min(p.count(done), p.target)
+		if n < p.target && p.contains(today) {
+			continue
+		}
+		stats.Due += p.target
+		stats.Completed += n
+	}
+	if stats.Due > 0 {
+		stats.CompletionRate = math.Round(float64(stats.Completed)/float64(stats.Due)*1e4) / 1e4
+	}
+	return stats
+}
+
+// periods splits from..to into the periods the schedule makes due. Weeks cut
+// short by the range get a proportional target, rounded up.
+func periods(schedule models.HabitSchedule, from, to models.Date) []period {
+	var ps []period
+	switch schedule.Kind {
+	case models.ScheduleWeekly:
+		for first := from; !first.After(to); {
+			// Weeks end on Sunday
+			last := first.AddDays((7 - int(first.Weekday())) % 7)
+			if last.After(to) {
+				last = to
+			}
+			days := last.DaysSince(first) + 1
+			target := (schedule.TimesPerWeek*days + 6) / 7
+			ps = append(ps, period{first: first, last: last, target: target})
+			first = last.AddDays(1)
+		}
+	case models.ScheduleWeekdays:
+		var due [7]bool
+		for _, name := range schedule.Weekdays {
+			if wd, ok := models.ParseWeekday(name); ok {
+				due[wd] = true
+			}
+		}
+		for d := from; !d.After(to); d = d.AddDays(1) {
+			if due[d.Weekday()] {
+				ps = append(ps, period{first: d, last: d, target: 1})
+			}
+		}
+	default:
+		for d := from; !d.After(to); d = d.AddDays(1) {
+			ps = append(ps, period{first: d, last: d, target: 1})
+		}
+	}
+	return ps
+}
@@ -0,0 +1,116 @@
+package habits
+
+import (
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+)
+
+// march returns a day in March 2025; 3 March is a Monday
+func march(day int) models.Date {
+	return models.NewDate(2025, 3, day)
+}
+
+func days(ds ...int) []models.Date {
+	out := make([]models.Date, len(ds))
+	for i, d := range ds {
+		out[i] = march(d)
+	}
+	return out
+}
+
+func TestCompute(t *testing.T) {
+	daily := models.HabitSchedule{Kind: models.ScheduleDaily}
+	monWedFri := models.HabitSchedule{Kind: models.ScheduleWeekdays, Weekdays: []string{"mon", "wed", "fri"}}
+	thricePerWeek := models.HabitSchedule{Kind: models.ScheduleWeekly, TimesPerWeek: 3}
+
+	tests := []struct {
+		name          string
+		schedule      models.HabitSchedule
+		start         models.Date
+		checkIns      []models.Date
+		from, to      models.Date
+		today         models.Date
+		wantCurrent   int
+		wantLongest   int
+		wantDue       int
+		wantCompleted int
+		wantRate      float64
+	}{
+		{
+			name:     "daily streak broken by a missed day",
+			schedule: daily, start: march(1),
+			checkIns: days(1, 2, 3, 5, 6),
+			from:     march(1), to: march(6), today: march(6),
+			wantCurrent: 2, wantLongest: 3, wantDue: 6, wantCompleted: 5, wantRate: 0.8333,
+		},
+		{
+			name:     "today not yet done keeps the streak",
+			schedule: daily, start: march(1),
+			checkIns: days(4, 5, 6),
+			from:     march(4), to: march(7), today: march(7),
+			wantCurrent: 3, wantLongest: 3, wantDue: 3, wantCompleted: 3, wantRate: 1,
+		},
+		{
+			name:     "yesterday missed ends the streak",
+			schedule: daily, start: march(1),
+			checkIns: days(4, 5),
+			from:     march(1), to: march(7), today: march(7),
+			wantCurrent: 0, wantLongest: 2, wantDue: 6, wantCompleted: 2, wantRate: 0.3333,
+		},
+		{
+			name:     "unscheduled days do not break the streak",
+			schedule: monWedFri, start: march(3),
+			checkIns: days(3, 5, 7, 10),
+			from:     march(3), to: march(11), today: march(11),
+			wantCurrent: 4, wantLongest: 4, wantDue: 4, wantCompleted: 4, wantRate: 1,
+		},
+		{
+			name:     "check-ins on unscheduled days do not count",
+			schedule: monWedFri, start: march(3),
+			checkIns: days(3, 4, 6, 7),
+			from:     march(3), to: march(9), today: march(9),
+			wantCurrent: 1, wantLongest: 1, wantDue: 3, wantCompleted: 2, wantRate: 0.6667,
+		},
+		{
+			name:     "weekly streak counts weeks",
+			schedule: thricePerWeek, start: march(3),
+			checkIns: days(3, 4, 5, 10, 12, 14, 18),
+			from:     march(3), to: march(19), today: march(19),
+			wantCurrent: 2, wantLongest: 2, wantDue: 6, wantCompleted: 6, wantRate: 1,
+		},
+		{
+			name:     "weekly target missed",
+			schedule: thricePerWeek, start: march(3),
+			checkIns: days(3, 4, 10, 11, 12),
+			from:     march(3), to: march(16), today: march(17),
+			wantCurrent: 1, wantLongest: 1, wantDue: 6, wantCompleted: 5, wantRate: 0.8333,
+		},
+		{
+			name:     "partial first week has a proportional target",
+			schedule: thricePerWeek, start: march(7),
+			checkIns: days(7, 9),
+			from:     march(7), to: march(9), today: march(10),
+			wantCurrent: 1, wantLongest: 1, wantDue: 2, wantCompleted: 2, wantRate: 1,
+		},
+		{
+			name:     "range before the start",
+			schedule: daily, start: march(10),
+			checkIns: nil,
+			from:     march(1), to: march(9), today: march(12),
+			wantCurrent: 0, wantLongest: 0, wantDue: 0, wantCompleted: 0, wantRate: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(tt.schedule, tt.start, tt.checkIns, tt.from, tt.to, tt.today)
+			if got.CurrentStreak != tt.wantCurrent || got.LongestStreak != tt.wantLongest {
+				t.Errorf("Expected streaks %d/%d, got %d/%d", tt.wantCurrent, tt.wantLongest, got.CurrentStreak, got.LongestStreak)
+			}
+			if got.Due != tt.wantDue || got.Completed != tt.wantCompleted || got.CompletionRate != tt.wantRate {
+				t.Errorf("Expected %d/%d (%v), got %d/%d (%v)", tt.wantCompleted, tt.wantDue, tt.wantRate, got.Completed, got.Due, got.CompletionRate)
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/audit"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+)
+
+// RegisterRequest is the body of Register
+type RegisterRequest struct {
+	Email    string `json:"email" binding:"required,email,max=254"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+	Name     string `json:"name" binding:"required,max=100" example:"Ada Lovelace"`
+	TimeZone string `json:"timezone,omitempty" binding:"omitempty,timezone" example:"Europe/Moscow" doc:"IANA time zone, default UTC"`
+}
+
+// LoginRequest is the body of Login
+type LoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// RefreshRequest is the body of Refresh and Logout
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// UpdateProfileRequest is the body of UpdateMe. Omitted fields are left
+// unchanged; changing the email or password requires the current password.
+type UpdateProfileRequest struct {
+	Name            *string `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	Email           *string `json:"email,omitempty" binding:"omitempty,email,max=254"`
+	Password        *string `json:"password,omitempty" binding:"omitempty,min=8,max=72"`
+	TimeZone        *string `json:"timezone,omitempty" binding:"omitempty,timezone" example:"Europe/Moscow"`
+	CurrentPassword string  `json:"current_password,omitempty" doc:"required when changing email or password"`
+}
+
+// TokenResponse carries a freshly issued token pair
+type TokenResponse struct {
+	AccessToken  string       `json:"access_token"`
+	RefreshToken string       `json:"refresh_token"`
+	TokenType    string       `json:"token_type" example:"Bearer"`
+	ExpiresIn    int          `json:"expires_in" doc:"access token lifetime in seconds" example:"900"`
+	User         *models.User `json:"user"`
+}
+
+// AccountHandler serves registration, login, token refresh and the profile
+// of the authenticated user
+type AccountHandler struct {
+	users    store.UserStore
+	sessions store.RefreshTokenStore
+	tokens   *auth.TokenService
+	cfg      config.AuthConfig
+	now      func() time.Time
+}
+
+// NewAccountHandler creates an account handler
+func NewAccountHandler(users store.UserStore, sessions store.RefreshTokenStore, tokens *auth.TokenService, cfg config.AuthConfig) *AccountHandler {
+	return &AccountHandler{
+		users:    users,
+		sessions: sessions,
+		tokens:   tokens,
+		cfg:      cfg,
+		now:      time.Now,
+	}
+}
+
+// Register creates an account and signs it in
+func (h *AccountHandler) Register(c *gin.Context) {
+	var req RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+
+	hash, err := auth.HashPassword(req.Password, h.cfg.BcryptCost)
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	user := &models.User{
+		Email:        normalizeEmail(req.Email),
+		Name:         strings.TrimSpace(req.Name),
+		PasswordHash: hash,
+		Roles:        []string{auth.RoleUser},
+		TimeZone:     req.TimeZone,
+	}
+	if err := h.users.CreateUser(c.Request.Context(), user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			apperr.Abort(c, apperr.Wrap(err, apperr.CodeEmailTaken, ""))
+			return
+		}
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+
+	h.respondWithTokens(c, http.StatusCreated, user)
+}
+
+// Login exchanges email and password for a token pair
+func (h *AccountHandler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+
+	user, err := h.users.GetUserByEmail(c.Request.Context(), normalizeEmail(req.Email))
+	hash := ""
+	switch {
+	case err == nil:
+		hash = user.PasswordHash
+	case !errors.Is(err, store.ErrNotFound):
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	if err := auth.CheckLogin(hash, req.Password, h.cfg.BcryptCost); err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			failed := audit.Event{Action: audit.ActionLoginFailed, Details: map[string]any{"email": normalizeEmail(req.Email)}}
+			if user != nil {
+				failed.TargetType, failed.TargetID = audit.TargetUser, strconv.FormatInt(user.ID, 10)
+			}
+			recordAudit(c, failed)
+			apperr.Abort(c, apperr.New(apperr.CodeInvalidCredentials, ""))
+			return
+		}
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	// Only reveal that the account is disabled to someone who knows the password
+	if user.Disabled() {
+		failed := userEvent(audit.ActionLoginFailed, user.ID, nil)
+		failed.Details = map[string]any{"email": user.Email, "reason": "account disabled"}
+		recordAudit(c, failed)
+		apperr.Abort(c, apperr.New(apperr.CodeForbidden, "the account is disabled"))
+		return
+	}
+
+	recordAudit(c, userEvent(audit.ActionLoginSucceeded, user.ID, &user.ID))
+	h.respondWithTokens(c, http.StatusOK, user)
+}
+
+// Refresh rotates a refresh token into a new token pair. Presenting a token
+// that was already rotated revokes every session of its user, since either
+// the client or an attacker holds a stolen copy.
+func (h *AccountHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+	ctx := c.Request.Context()
+	now := h.now()
+
+	token, err := h.sessions.GetRefreshToken(ctx, auth.HashRefreshToken(req.RefreshToken))
+	if errors.Is(err, store.ErrNotFound) {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidToken, "unknown refresh token"))
+		return
+	}
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	if !now.Before(token.ExpiresAt) {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidToken, "refresh token expired"))
+		return
+	}
+
+	rotated := false
+	if token.RevokedAt == nil {
+		if rotated, err = h.sessions.RevokeRefreshToken(ctx, token.ID, now); err != nil {
+			apperr.Abort(c, apperr.Internal(err))
+			return
+		}
+	}
+	if !rotated {
+		if err := h.sessions.RevokeUserRefreshTokens(ctx, token.UserID, now); err != nil {
+			apperr.Abort(c, apperr.Internal(err))
+			return
+		}
+		middleware.LoggerFromContext(ctx).Warn("refresh token reused, sessions revoked", "user_id", token.UserID)
+		recordAudit(c, userEvent(audit.ActionRefreshTokenReuse, token.UserID, nil))
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidToken, "refresh token was already used"))
+		return
+	}
+
+	user, err := h.users.GetUser(ctx, token.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidToken, "account no longer exists"))
+		return
+	}
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	if user.Disabled() {
+		apperr.Abort(c, apperr.New(apperr.CodeInvalidToken, "the account is disabled"))
+		return
+	}
+
+	h.respondWithTokens(c, http.StatusOK, user)
+}
+
+// Logout revokes a refresh token. Unknown tokens are ignored so the call is
+// idempotent. Access tokens stay valid until they expire.
+func (h *AccountHandler) Logout(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+	ctx := c.Request.Context()
+
+	token, err := h.sessions.GetRefreshToken(ctx, auth.HashRefreshToken(req.RefreshToken))
+	switch {
+	case err == nil:
+		if _, err := h.sessions.RevokeRefreshToken(ctx, token.ID, h.now()); err != nil {
+			apperr.Abort(c, apperr.Internal(err))
+			return
+		}
+	case !errors.Is(err, store.ErrNotFound):
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Me returns the authenticated user's profile
+func (h *AccountHandler) Me(c *gin.Context) {
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+	middleware.SetLastModified(c, user.UpdatedAt)
+	c.JSON(http.StatusOK, user)
+}
+
+// UpdateMe changes the authenticated user's profile. A password change
+// revokes every refresh token of the user, the caller's included, so all
+// sessions end once their access tokens expire.
+func (h *AccountHandler) UpdateMe(c *gin.Context) {
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+
+	if req.Email != nil || req.Password != nil {
+		if err := auth.CheckPassword(user.PasswordHash, req.CurrentPassword); err != nil {
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				abortField(c, "current_password", "incorrect", "is incorrect")
+				return
+			}
+			apperr.Abort(c, apperr.Internal(err))
+			return
+		}
+	}
+
+	if req.Name != nil {
+		user.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Email != nil {
+		user.Email = normalizeEmail(*req.Email)
+	}
+	if req.TimeZone != nil {
+		user.TimeZone = *req.TimeZone
+	}
+	if req.Password != nil {
+		hash, err := auth.HashPassword(*req.Password, h.cfg.BcryptCost)
+		if err != nil {
+			apperr.Abort(c, apperr.Internal(err))
+			return
+		}
+		user.PasswordHash = hash
+	}
+
+	ctx := c.Request.Context()
+	if err := h.users.UpdateUser(ctx, user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			apperr.Abort(c, apperr.Wrap(err, apperr.CodeEmailTaken, ""))
+			return
+		}
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	if req.Password != nil {
+		recordAudit(c, userEvent(audit.ActionPasswordChanged, user.ID, nil))
+		if err := h.sessions.RevokeUserRefreshTokens(ctx, user.ID, h.now()); err != nil {
+			apperr.Abort(c, apperr.Internal(err))
+			return
+		}
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteMe deletes the authenticated user's account and sessions
+func (h *AccountHandler) DeleteMe(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, ""))
+		return
+	}
+	if err := h.users.DeleteUser(c.Request.Context(), claims.UserID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperr.Abort(c, apperr.New(apperr.CodeNotFound, "account not found"))
+			return
+		}
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	recordAudit(c, userEvent(audit.ActionAccountDeleted, claims.UserID, nil))
+	c.Status(http.StatusNoContent)
+}
+
+// userEvent returns an audit event targeting the account with the given ID.
+// A nil actor defaults to the authenticated user.
+func userEvent(action string, userID int64, actorID *int64) audit.Event {
+	return audit.Event{Action: action, ActorID: actorID, TargetType: audit.TargetUser, TargetID: strconv.FormatInt(userID, 10)}
+}
+
+// currentUser loads the user named by the access token
+func currentUser(c *gin.Context, users store.UserStore) (*models.User, bool) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, ""))
+		return nil, false
+	}
+	user, err := users.GetUser(c.Request.Context(), claims.UserID)
+	if errors.Is(err, store.ErrNotFound) {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "account not found"))
+		return nil, false
+	}
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return nil, false
+	}
+	return user, true
+}
+
+// respondWithTokens issues an access and refresh token for user
+func (h *AccountHandler) respondWithTokens(c *gin.Context, status int, user *models.User) {
+	access, _, err := h.tokens.Issue(auth.Claims{UserID: user.ID, Email: user.Email, Roles: user.Roles})
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	refresh, hash, err := auth.NewRefreshToken()
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	token := &models.RefreshToken{
+		UserID:    user.ID,
+		TokenHash: hash,
+		ExpiresAt: h.now().Add(h.cfg.RefreshTokenTTL),
+	}
+	if err := h.sessions.CreateRefreshToken(c.Request.Context(), token); err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+
+	c.JSON(status, TokenResponse{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(h.tokens.TTL().Seconds()),
+		User:         user,
+	})
+}
+
+// normalizeEmail makes emails comparable regardless of case and padding
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func accountRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	cfg := config.AuthConfig{
+		JWTSecret:       "test-secret",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+		BcryptCost:      bcrypt.MinCost,
+	}
+	tokens, err := auth.NewTokenService(cfg)
+	if err != nil {
+		t.Fatalf("NewTokenService() failed: %v", err)
+	}
+	s := store.New(dbtest.SQLite(t))
+	h := NewAccountHandler(s, s, tokens, cfg)
+
+	router := gin.New()
+	router.Use(middleware.Errors(true))
+	router.POST("/auth/register", h.Register)
+	router.POST("/auth/login", h.Login)
+	router.POST("/auth/refresh", h.Refresh)
+	router.POST("/auth/logout", h.Logout)
+	me := router.Group("/users/me", middleware.Auth(tokens))
+	me.GET("", h.Me)
+	me.PATCH("", h.UpdateMe)
+	me.DELETE("", h.DeleteMe)
+	return router
+}
+
+// call sends a JSON request and decodes the JSON response into out
+func call(t *testing.T, router *gin.Engine, method, path, token string, body, out any) int {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&payload).Encode(body)
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if out != nil && w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: invalid JSON %q: %v", method, path, w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+func TestAccountFlow(t *testing.T) {
+	router := accountRouter(t)
+
+	var registered TokenResponse
+	code := call(t, router, http.MethodPost, "/auth/register", "",
+		gin.H{"email": "Ada@Example.com", "password": "correct horse", "name": "Ada"}, &registered)
+	if code != http.StatusCreated {
+		t.Fatalf("Expected 201 from register, got %d", code)
+	}
+	if registered.AccessToken == "" || registered.RefreshToken == "" || registered.TokenType != "Bearer" {
+		t.Fatalf("Expected a token pair, got %+v", registered)
+	}
+	if registered.User.Email != "ada@example.com" || !registered.User.HasRole(auth.RoleUser) {
+		t.Errorf("Expected normalised email and user role, got %+v", registered.User)
+	}
+
+	if code := call(t, router, http.MethodPost, "/auth/register", "",
+		gin.H{"email": "ada@example.com", "password": "another pass", "name": "Imposter"}, nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 for a taken email, got %d", code)
+	}
+
+	var problem apperr.Problem
+	if code := call(t, router, http.MethodPost, "/auth/login", "",
+		gin.H{"email": "ada@example.com", "password": "wrong password"}, &problem); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong password, got %d", code)
+	}
+	if code := call(t, router, http.MethodPost, "/auth/login", "",
+		gin.H{"email": "nobody@example.com", "password": "wrong password"}, &problem); code != http.StatusUnauthorized || problem.Code != apperr.CodeInvalidCredentials {
+		t.Errorf("Expected unknown emails to look like wrong passwords, got %d %+v", code, problem)
+	}
+
+	var login TokenResponse
+	if code := call(t, router, http.MethodPost, "/auth/login", "",
+		gin.H{"email": "ADA@example.com", "password": "correct horse"}, &login); code != http.StatusOK {
+		t.Fatalf("Expected 200 from login, got %d", code)
+	}
+
+	var profile struct {
+		ID    int64  `json:"id"`
+		Email string `json:"email"`
+		Name  string `json:"name"`
+	}
+	if code := call(t, router, http.MethodGet, "/users/me", login.AccessToken, nil, &profile); code != http.StatusOK {
+		t.Fatalf("Expected 200 from /users/me, got %d", code)
+	}
+	if profile.ID != registered.User.ID || profile.Name != "Ada" {
+		t.Errorf("Expected own profile, got %+v", profile)
+	}
+
+	var refreshed TokenResponse
+	if code := call(t, router, http.MethodPost, "/auth/refresh", "",
+		gin.H{"refresh_token": login.RefreshToken}, &refreshed); code != http.StatusOK {
+		t.Fatalf("Expected 200 from refresh, got %d", code)
+	}
+	if refreshed.RefreshToken == login.RefreshToken {
+		t.Error("Expected refresh to rotate the refresh token")
+	}
+
+	// Replaying the rotated token revokes every session
+	if code := call(t, router, http.MethodPost, "/auth/refresh", "",
+		gin.H{"refresh_token": login.RefreshToken}, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 when reusing a refresh token, got %d", code)
+	}
+	for _, token := range []string{refreshed.RefreshToken, registered.RefreshToken} {
+		if code := call(t, router, http.MethodPost, "/auth/refresh", "",
+			gin.H{"refresh_token": token}, nil); code != http.StatusUnauthorized {
+			t.Errorf("Expected reuse to revoke all sessions, got %d", code)
+		}
+	}
+
+	var session TokenResponse
+	call(t, router, http.MethodPost, "/auth/login", "", gin.H{"email": "ada@example.com", "password": "correct horse"}, &session)
+	if code := call(t, router, http.MethodPost, "/auth/logout", "", gin.H{"refresh_token": session.RefreshToken}, nil); code != http.StatusNoContent {
+		t.Errorf("Expected 204 from logout, got %d", code)
+	}
+	if code := call(t, router, http.MethodPost, "/auth/logout", "", gin.H{"refresh_token": "unknown"}, nil); code != http.StatusNoContent {
+		t.Errorf("Expected logout to be idempotent, got %d", code)
+	}
+	if code := call(t, router, http.MethodPost, "/auth/refresh", "", gin.H{"refresh_token": session.RefreshToken}, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected logged out token to be rejected, got %d", code)
+	}
+}
+
+func TestUpdateAndDeleteMe(t *testing.T) {
+	router := accountRouter(t)
+	var session TokenResponse
+	call(t, router, http.MethodPost, "/auth/register", "",
+		gin.H{"email": "grace@example.com", "password": "first password", "name": "Grace"}, &session)
+	call(t, router, http.MethodPost, "/auth/register", "",
+		gin.H{"email": "taken@example.com", "password": "first password", "name": "Other"}, nil)
+
+	tests := []struct {
+		name       string
+		body       gin.H
+		wantStatus int
+	}{
+		{"rename", gin.H{"name": "Grace Hopper"}, http.StatusOK},
+		{"time zone", gin.H{"timezone": "America/New_York"}, http.StatusOK},
+		{"unknown time zone", gin.H{"timezone": "Mars/Olympus"}, http.StatusUnprocessableEntity},
+		{"email without current password", gin.H{"email": "g@example.com"}, http.StatusUnprocessableEntity},
+		{"email taken", gin.H{"email": "taken@example.com", "current_password": "first password"}, http.StatusConflict},
+		{"short password", gin.H{"password": "short", "current_password": "first password"}, http.StatusUnprocessableEntity},
+		{"password change", gin.H{"password": "second password", "current_password": "first password"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := call(t, router, http.MethodPatch, "/users/me", session.AccessToken, tt.body, nil); code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d", tt.wantStatus, code)
+			}
+		})
+	}
+
+	if code := call(t, router, http.MethodPost, "/auth/refresh", "", gin.H{"refresh_token": session.RefreshToken}, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected password change to revoke sessions, got %d", code)
+	}
+	if code := call(t, router, http.MethodPost, "/auth/login", "",
+		gin.H{"email": "grace@example.com", "password": "second password"}, nil); code != http.StatusOK {
+		t.Errorf("Expected login with the new password, got %d", code)
+	}
+
+	if code := call(t, router, http.MethodDelete, "/users/me", session.AccessToken, nil, nil); code != http.StatusNoContent {
+		t.Fatalf("Expected 204 from delete, got %d", code)
+	}
+	if code := call(t, router, http.MethodGet, "/users/me", session.AccessToken, nil, nil); code != http.StatusNotFound {
+		t.Errorf("Expected deleted account to be gone, got %d", code)
+	}
+	if code := call(t, router, http.MethodPost, "/auth/login", "",
+		gin.H{"email": "grace@example.com", "password": "second password"}, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected deleted account to be unable to log in, got %d", code)
+	}
+}
+
+func TestDisabledAccount(t *testing.T) {
+	cfg := config.AuthConfig{JWTSecret: "test-secret", AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, BcryptCost: bcrypt.MinCost}
+	tokens, err := auth.NewTokenService(cfg)
+	if err != nil {
+		t.Fatalf("NewTokenService() failed: %v", err)
+	}
+	s := store.New(dbtest.SQLite(t))
+	h := NewAccountHandler(s, s, tokens, cfg)
+	router := gin.New()
+	router.Use(middleware.Errors(true))
+	router.POST("/auth/register", h.Register)
+	router.POST("/auth/login", h.Login)
+	router.POST("/auth/refresh", h.Refresh)
+
+	var session TokenResponse
+	call(t, router, http.MethodPost, "/auth/register", "",
+		gin.H{"email": "mallory@example.com", "password": "first password", "name": "Mallory"}, &session)
+	user, err := s.GetUser(context.Background(), session.User.ID)
+	if err != nil {
+		t.Fatalf("GetUser() failed: %v", err)
+	}
+	now := time.Now()
+	user.DisabledAt = &now
+	if err := s.UpdateUser(context.Background(), user); err != nil {
+		t.Fatalf("UpdateUser() failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		body   gin.H
+		status int
+	}{
+		{"login", "/auth/login", gin.H{"email": "mallory@example.com", "password": "first password"}, http.StatusForbidden},
+		{"wrong password", "/auth/login", gin.H{"email": "mallory@example.com", "password": "wrong password"}, http.StatusUnauthorized},
+		{"refresh", "/auth/refresh", gin.H{"refresh_token": session.RefreshToken}, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := call(t, router, http.MethodPost, tt.path, "", tt.body, nil); code != tt.status {
+				t.Errorf("Expected %d, got %d", tt.status, code)
+			}
+		})
+	}
+}
+
+func TestRegisterValidation(t *testing.T) {
+	router := accountRouter(t)
+
+	var problem apperr.Problem
+	code := call(t, router, http.MethodPost, "/auth/register", "",
+		gin.H{"email": "not-an-email", "password": "short"}, &problem)
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected 422, got %d", code)
+	}
+
+	fields := make(map[string]string)
+	for _, fe := range problem.Errors {
+		fields[fe.Field] = fe.Code
+	}
+	want := map[string]string{"email": "email", "password": "min", "name": "required"}
+	for field, rule := range want {
+		if fields[field] != rule {
+			t.Errorf("Expected %s to fail %q, got %q", field, rule, fields[field])
+		}
+	}
+}
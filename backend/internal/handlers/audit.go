@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/audit"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+)
+
+// Page sizes of the audit log
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 500
+)
+
+// AuditQuery holds the query parameters of ListAuditEvents
+type AuditQuery struct {
+	Action     string     `form:"action" example:"login.failed"`
+	ActorID    *int64     `form:"actor_id"`
+	TargetType string     `form:"target_type" example:"user"`
+	TargetID   string     `form:"target_id"`
+	From       *time.Time `form:"from" doc:"earliest event time, inclusive"`
+	To         *time.Time `form:"to" doc:"latest event time, exclusive"`
+	Before     int64      `form:"before" binding:"omitempty,min=1" doc:"only events with a lower ID; pass next_before to page"`
+	Limit      int        `form:"limit" binding:"omitempty,min=1,max=500" doc:"default 100"`
+}
+
+// AuditEventsResponse is one page of the audit log
+type AuditEventsResponse struct {
+	Events     []audit.Event `json:"events" doc:"newest first"`
+	NextBefore int64         `json:"next_before,omitempty" doc:"before value of the next page; absent on the last page"`
+}
+
+// AuditLog queries and verifies the audit log
+type AuditLog interface {
+	Query(ctx context.Context, f audit.Filter) ([]audit.Event, error)
+	Verify(ctx context.Context) (audit.Verification, error)
+}
+
+// AuditHandler serves the audit log to administrators
+type AuditHandler struct {
+	log AuditLog
+}
+
+// NewAuditHandler creates an audit log handler
+func NewAuditHandler(log AuditLog) *AuditHandler {
+	return &AuditHandler{log: log}
+}
+
+// ListAuditEvents returns a page of events matching the query, newest first
+func (h *AuditHandler) ListAuditEvents(c *gin.Context) {
+	var query AuditQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultAuditLimit
+	}
+	filter := audit.Filter{
+		Action:     query.Action,
+		ActorID:    query.ActorID,
+		TargetType: query.TargetType,
+		TargetID:   query.TargetID,
+		Before:     query.Before,
+		// One extra event tells whether another page follows
+		Limit: limit + 1,
+	}
+	if query.From != nil {
+		filter.From = *query.From
+	}
+	if query.To != nil {
+		filter.To = *query.To
+	}
+
+	events, err := h.log.Query(c.Request.Context(), filter)
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	resp := AuditEventsResponse{Events: events}
+	if len(events) > limit {
+		resp.Events = events[:limit]
+		resp.NextBefore = resp.Events[limit-1].ID
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// VerifyAuditLog checks the hash chain of the whole log
+func (h *AuditHandler) VerifyAuditLog(c *gin.Context) {
+	v, err := h.log.Verify(c.Request.Context())
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// recordAudit records e for the current request. A failure is logged but
+// does not fail the request.
+func recordAudit(c *gin.Context, e audit.Event) {
+	ctx := c.Request.Context()
+	if err := audit.Record(ctx, e); err != nil {
+		middleware.LoggerFromContext(ctx).Error("audit record failed", "action", e.Action, "error", err)
+	}
+}
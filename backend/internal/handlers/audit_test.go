@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/audit"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuditTrail(t *testing.T) {
+	cfg := config.AuthConfig{JWTSecret: "test-secret", AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, BcryptCost: bcrypt.MinCost}
+	tokens, err := auth.NewTokenService(cfg)
+	if err != nil {
+		t.Fatalf("NewTokenService() failed: %v", err)
+	}
+	db := dbtest.SQLite(t)
+	s := store.New(db)
+	accounts := NewAccountHandler(s, s, tokens, cfg)
+	auditLog := NewAuditHandler(audit.NewStore(db))
+
+	router := gin.New()
+	router.Use(middleware.RequestID(), middleware.Errors(true), middleware.Audit(audit.NewStore(db)))
+	router.POST("/auth/register", accounts.Register)
+	router.POST("/auth/login", accounts.Login)
+	protected := router.Group("", middleware.Auth(tokens))
+	protected.PATCH("/users/me", accounts.UpdateMe)
+	admin := protected.Group("/admin", middleware.RequireRole(auth.RoleAdmin))
+	admin.GET("/audit", auditLog.ListAuditEvents)
+	admin.GET("/audit/verify", auditLog.VerifyAuditLog)
+
+	var session TokenResponse
+	call(t, router, http.MethodPost, "/auth/register", "",
+		gin.H{"email": "ada@example.com", "password": "first password", "name": "Ada"}, &session)
+	call(t, router, http.MethodPost, "/auth/login", "", gin.H{"email": "ada@example.com", "password": "wrong password"}, nil)
+	call(t, router, http.MethodPost, "/auth/login", "", gin.H{"email": "nobody@example.com", "password": "wrong password"}, nil)
+	call(t, router, http.MethodPost, "/auth/login", "", gin.H{"email": "ada@example.com", "password": "first password"}, nil)
+	if code := call(t, router, http.MethodPatch, "/users/me", session.AccessToken,
+		gin.H{"password": "second password", "current_password": "first password"}, nil); code != http.StatusOK {
+		t.Fatalf("Expected 200 from password change, got %d", code)
+	}
+
+	if code := call(t, router, http.MethodGet, "/admin/audit", session.AccessToken, nil, nil); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a non-admin, got %d", code)
+	}
+	adminToken, _, _ := tokens.Issue(auth.Claims{UserID: 99, Roles: []string{auth.RoleAdmin}})
+
+	var page AuditEventsResponse
+	if code := call(t, router, http.MethodGet, "/admin/audit", adminToken, nil, &page); code != http.StatusOK {
+		t.Fatalf("Expected 200 from the audit log, got %d", code)
+	}
+	ada := fmt.Sprint(session.User.ID)
+	want := []struct {
+		action   string
+		targetID string
+		actor    bool
+	}{
+		{audit.ActionPasswordChanged, ada, true},
+		{audit.ActionLoginSucceeded, ada, true},
+		{audit.ActionLoginFailed, "", false},
+		{audit.ActionLoginFailed, ada, false},
+	}
+	if len(page.Events) != len(want) {
+		t.Fatalf("Expected %d events, got %+v", len(want), page.Events)
+	}
+	for i, w := range want {
+		e := page.Events[i]
+		if e.Action != w.action || e.TargetID != w.targetID || (e.ActorID != nil) != w.actor {
+			t.Errorf("Event %d: expected %+v, got %+v", i, w, e)
+		}
+		if e.RequestID == "" || e.IP == "" {
+			t.Errorf("Event %d: expected the request origin, got %+v", i, e)
+		}
+	}
+	if page.Events[3].Details["email"] != "ada@example.com" {
+		t.Errorf("Expected the attempted email in the details, got %v", page.Events[3].Details)
+	}
+
+	var first, second AuditEventsResponse
+	call(t, router, http.MethodGet, "/admin/audit?action=login.failed&limit=1", adminToken, nil, &first)
+	if len(first.Events) != 1 || first.NextBefore != first.Events[0].ID {
+		t.Fatalf("Expected a first page of one with a cursor, got %+v", first)
+	}
+	call(t, router, http.MethodGet, fmt.Sprintf("/admin/audit?action=login.failed&limit=1&before=%d", first.NextBefore), adminToken, nil, &second)
+	if len(second.Events) != 1 || second.NextBefore != 0 || second.Events[0].ID >= first.Events[0].ID {
+		t.Errorf("Expected the last page, got %+v", second)
+	}
+	if code := call(t, router, http.MethodGet, "/admin/audit?limit=1000", adminToken, nil, nil); code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 for an oversized page, got %d", code)
+	}
+
+	var v audit.Verification
+	if code := call(t, router, http.MethodGet, "/admin/audit/verify", adminToken, nil, &v); code != http.StatusOK || !v.Valid || v.Checked != 4 {
+		t.Errorf("Expected an intact chain of 4, got %d %+v", code, v)
+	}
+}
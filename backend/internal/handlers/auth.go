@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+)
+
+// SessionResponse describes the caller's access token
+type SessionResponse struct {
+	UserID    int64     `json:"user_id" example:"42"`
+	Email     string    `json:"email" format:"email"`
+	Roles     []string  `json:"roles"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Session describes the verified access token of the current request
+func Session(c *gin.Context) {
+	claims, ok := middleware.GetClaims(c)
+	if !ok {
+		apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, ""))
+		return
+	}
+	c.JSON(http.StatusOK, SessionResponse{
+		UserID:    claims.UserID,
+		Email:     claims.Email,
+		Roles:     claims.Roles,
+		Scopes:    claims.Scopes(),
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+)
+
+// FlagsResponse lists every feature flag with its state for the user
+type FlagsResponse struct {
+	Flags map[string]bool `json:"flags" doc:"flag name to whether it is on for the user"`
+}
+
+// FlagEvaluator evaluates every feature flag for a user
+type FlagEvaluator interface {
+	For(userID int64) map[string]bool
+}
+
+// Flags returns the feature flags as they apply to the current user
+func Flags(flags FlagEvaluator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := middleware.GetClaims(c)
+		if !ok {
+			apperr.Abort(c, apperr.New(apperr.CodeUnauthorized, ""))
+			return
+		}
+		c.JSON(http.StatusOK, FlagsResponse{Flags: flags.For(claims.UserID)})
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/features"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+)
+
+func TestFlags(t *testing.T) {
+	tokens, err := auth.NewTokenService(config.AuthConfig{JWTSecret: "test-secret", AccessTokenTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewTokenService() failed: %v", err)
+	}
+	flags := features.New(config.FeaturesConfig{Flags: map[string]config.FlagConfig{
+		"beta":      {Enabled: true, Users: []int64{1}},
+		"dark_mode": {Enabled: true},
+		"retired":   {Enabled: false},
+	}}, nil, nil)
+	router := gin.New()
+	router.Use(middleware.Errors(true))
+	router.GET("/flags", middleware.Auth(tokens), Flags(flags))
+
+	tests := []struct {
+		name   string
+		userID int64
+		want   map[string]bool
+	}{
+		{"tester", 1, map[string]bool{"beta": true, "dark_mode": true, "retired": false}},
+		{"everyone else", 2, map[string]bool{"beta": false, "dark_mode": true, "retired": false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, _, _ := tokens.Issue(auth.Claims{UserID: tt.userID})
+			var resp FlagsResponse
+			if code := call(t, router, http.MethodGet, "/flags", token, nil, &resp); code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d", code)
+			}
+			if len(resp.Flags) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, resp.Flags)
+			}
+			for name, on := range tt.want {
+				if resp.Flags[name] != on {
+					t.Errorf("Expected %s to be %v, got %v", name, on, resp.Flags[name])
+				}
+			}
+		})
+	}
+
+	if code := call(t, router, http.MethodGet, "/flags", "", nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", code)
+	}
+}
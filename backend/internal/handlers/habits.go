@@ -0,0 +1,414 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/habits"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+)
+
+// Date ranges default to the last defaultStatsDays days and are capped at
+// maxRangeDays
+const (
+	defaultStatsDays = 30
+	maxRangeDays     = 3660
+)
+
+// CreateHabitRequest is the body of CreateHabit
+type CreateHabitRequest struct {
+	Name        string               `json:"name" binding:"required,max=100" example:"Meditate"`
+	Description string               `json:"description,omitempty" binding:"max=1000"`
+	Schedule    models.HabitSchedule `json:"schedule"`
+	StartDate   *models.Date         `json:"start_date,omitempty" format:"date" doc:"defaults to today in the user's time zone"`
+}
+
+// UpdateHabitRequest is the body of UpdateHabit. Omitted fields are left
+// unchanged.
+type UpdateHabitRequest struct {
+	Name        *string               `json:"name,omitempty" binding:"omitempty,min=1,max=100"`
+	Description *string               `json:"description,omitempty" binding:"omitempty,max=1000"`
+	Schedule    *models.HabitSchedule `json:"schedule,omitempty"`
+	StartDate   *models.Date          `json:"start_date,omitempty" format:"date"`
+	Archived    *bool                 `json:"archived,omitempty"`
+}
+
+// CheckInRequest is the body of CheckIn
+type CheckInRequest struct {
+	Date *models.Date `json:"date,omitempty" format:"date" doc:"defaults to today in the user's time zone"`
+	Note string       `json:"note,omitempty" binding:"max=500"`
+}
+
+// ListHabitsQuery holds the query parameters of ListHabits
+type ListHabitsQuery struct {
+	Archived bool `form:"archived" doc:"include archived habits"`
+}
+
+// DateRangeQuery selects the days from..to inclusive, by default the last
+// 30 days up to today in the user's time zone
+type DateRangeQuery struct {
+	From *models.Date `form:"from" format:"date"`
+	To   *models.Date `form:"to" format:"date"`
+}
+
+// HabitStatsResponse is the body returned by HabitStats
+type HabitStatsResponse struct {
+	HabitID int64 `json:"habit_id" example:"7"`
+	habits.Stats
+}
+
+// HabitHandler serves the habits of the authenticated user. Calendar days
+// are interpreted in the user's time zone.
+type HabitHandler struct {
+	habits store.HabitStore
+	users  store.UserStore
+	now    func() time.Time
+}
+
+// NewHabitHandler creates a habit handler
+func NewHabitHandler(habits store.HabitStore, users store.UserStore) *HabitHandler {
+	return &HabitHandler{
+		habits: habits,
+		users:  users,
+		now:    time.Now,
+	}
+}
+
+// ListHabits returns the user's habits
+func (h *HabitHandler) ListHabits(c *gin.Context) {
+	var query ListHabitsQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+	list, err := h.habits.ListHabits(c.Request.Context(), user.ID, query.Archived)
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	if list == nil {
+		list = []*models.Habit{}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// CreateHabit adds a habit
+func (h *HabitHandler) CreateHabit(c *gin.Context) {
+	var req CreateHabitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+
+	habit := &models.Habit{
+		UserID:      user.ID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Schedule:    normalizeSchedule(req.Schedule),
+		StartDate:   h.today(user),
+	}
+	if req.StartDate != nil {
+		habit.StartDate = *req.StartDate
+	}
+	if err := h.habits.CreateHabit(c.Request.Context(), habit); err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusCreated, habit)
+}
+
+// GetHabit returns one habit
+func (h *HabitHandler) GetHabit(c *gin.Context) {
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+	habit, ok := h.habit(c, user)
+	if !ok {
+		return
+	}
+	middleware.SetLastModified(c, habit.UpdatedAt)
+	c.JSON(http.StatusOK, habit)
+}
+
+// UpdateHabit changes a habit
+func (h *HabitHandler) UpdateHabit(c *gin.Context) {
+	var req UpdateHabitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+	habit, ok := h.habit(c, user)
+	if !ok {
+		return
+	}
+
+	if req.Name != nil {
+		habit.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		habit.Description = *req.Description
+	}
+	if req.Schedule != nil {
+		habit.Schedule = normalizeSchedule(*req.Schedule)
+	}
+	if req.StartDate != nil {
+		habit.StartDate = *req.StartDate
+	}
+	if req.Archived != nil {
+		habit.Archived = *req.Archived
+	}
+	if err := h.habits.UpdateHabit(c.Request.Context(), habit); err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, habit)
+}
+
+// DeleteHabit removes a habit and its check-ins
+func (h *HabitHandler) DeleteHabit(c *gin.Context) {
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	if err := h.habits.DeleteHabit(c.Request.Context(), user.ID, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperr.Abort(c, apperr.New(apperr.CodeNotFound, "habit not found"))
+			return
+		}
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// CheckIn records that a habit was done, today unless a date is given.
+// Days in the future or before the habit started are rejected.
+func (h *HabitHandler) CheckIn(c *gin.Context) {
+	var req CheckInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+	habit, ok := h.habit(c, user)
+	if !ok {
+		return
+	}
+
+	today := h.today(user)
+	date := today
+	if req.Date != nil {
+		date = *req.Date
+	}
+	switch {
+	case date.After(today):
+		abortField(c, "date", "future", "cannot be in the future")
+		return
+	case date.Before(habit.StartDate):
+		abortField(c, "date", "before_start", "cannot be before the habit's start date "+habit.StartDate.String())
+		return
+	}
+
+	checkIn := &models.CheckIn{HabitID: habit.ID, Date: date, Note: req.Note}
+	if err := h.habits.CreateCheckIn(c.Request.Context(), checkIn); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			apperr.Abort(c, apperr.New(apperr.CodeConflict, "already checked in on "+date.String()))
+			return
+		}
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusCreated, checkIn)
+}
+
+// ListCheckIns returns a habit's check-ins in a date range
+func (h *HabitHandler) ListCheckIns(c *gin.Context) {
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+	from, to, ok := h.dateRange(c, user)
+	if !ok {
+		return
+	}
+	habit, ok := h.habit(c, user)
+	if !ok {
+		return
+	}
+	checkIns, err := h.habits.ListCheckIns(c.Request.Context(), habit.ID, from, to)
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	if checkIns == nil {
+		checkIns = []*models.CheckIn{}
+	}
+	c.JSON(http.StatusOK, checkIns)
+}
+
+// DeleteCheckIn removes the check-in of a day
+func (h *HabitHandler) DeleteCheckIn(c *gin.Context) {
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+	date, err := models.ParseDate(c.Param("date"))
+	if err != nil {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "check-in not found"))
+		return
+	}
+	habit, ok := h.habit(c, user)
+	if !ok {
+		return
+	}
+	if err := h.habits.DeleteCheckIn(c.Request.Context(), habit.ID, date); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			apperr.Abort(c, apperr.New(apperr.CodeNotFound, "check-in not found"))
+			return
+		}
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// HabitStats returns the current and longest streak of a habit and its
+// completion rate over a date range
+func (h *HabitHandler) HabitStats(c *gin.Context) {
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+	from, to, ok := h.dateRange(c, user)
+	if !ok {
+		return
+	}
+	habit, ok := h.habit(c, user)
+	if !ok {
+		return
+	}
+
+	// Streaks need the whole history, not just the requested range
+	today := h.today(user)
+	checkIns, err := h.habits.ListCheckIns(c.Request.Context(), habit.ID, habit.StartDate, today)
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	days := make([]models.Date, len(checkIns))
+	for i, ci := range checkIns {
+		days[i] = ci.Date
+	}
+
+	c.JSON(http.StatusOK, HabitStatsResponse{
+		HabitID: habit.ID,
+		Stats:   habits.Compute(habit.Schedule, habit.StartDate, days, from, to, today),
+	})
+}
+
+// habit loads the user's habit named by the id path parameter
+func (h *HabitHandler) habit(c *gin.Context, user *models.User) (*models.Habit, bool) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return nil, false
+	}
+	habit, err := h.habits.GetHabit(c.Request.Context(), user.ID, id)
+	if errors.Is(err, store.ErrNotFound) {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "habit not found"))
+		return nil, false
+	}
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return nil, false
+	}
+	return habit, true
+}
+
+// today returns the current calendar day in the user's time zone
+func (h *HabitHandler) today(user *models.User) models.Date {
+	return models.DateOf(h.now().In(user.Location()))
+}
+
+// dateRange binds DateRangeQuery, applying the defaults
+func (h *HabitHandler) dateRange(c *gin.Context, user *models.User) (models.Date, models.Date, bool) {
+	var query DateRangeQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return models.Date{}, models.Date{}, false
+	}
+	to := h.today(user)
+	if query.To != nil {
+		to = *query.To
+	}
+	from := to.AddDays(1 - defaultStatsDays)
+	if query.From != nil {
+		from = *query.From
+	}
+	switch {
+	case from.After(to):
+		abortField(c, "from", "range", "must not be after to")
+		return models.Date{}, models.Date{}, false
+	case to.DaysSince(from) >= maxRangeDays:
+		abortField(c, "from", "range", "range must not exceed "+strconv.Itoa(maxRangeDays)+" days")
+		return models.Date{}, models.Date{}, false
+	}
+	return from, to, true
+}
+
+// normalizeSchedule drops the settings that do not apply to the schedule kind
+func normalizeSchedule(s models.HabitSchedule) models.HabitSchedule {
+	switch s.Kind {
+	case models.ScheduleWeekly:
+		s.Weekdays = nil
+	case models.ScheduleWeekdays:
+		s.TimesPerWeek = 0
+	default:
+		s.TimesPerWeek, s.Weekdays = 0, nil
+	}
+	return s
+}
+
+// pathID parses a numeric path parameter; malformed IDs cannot name a
+// resource, so they are reported as not found
+func pathID(c *gin.Context, name string) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param(name), 10, 64)
+	if err != nil || id < 1 {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, ""))
+		return 0, false
+	}
+	return id, true
+}
+
+// abortField rejects the request with a single field validation error
+func abortField(c *gin.Context, field, code, message string) {
+	e := apperr.New(apperr.CodeValidationFailed, "")
+	e.Fields = []apperr.FieldError{{Field: field, Code: code, Message: message}}
+	apperr.Abort(c, e)
+}
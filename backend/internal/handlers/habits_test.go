@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+)
+
+// habitRouter serves the habit routes for a user in Moscow (UTC+3) at
+// 22:30 UTC on Monday 10 March 2025, already Tuesday 11 March locally. It
+// returns the user's access token and another user's.
+func habitRouter(t *testing.T) (*gin.Engine, string, string) {
+	t.Helper()
+	tokens, err := auth.NewTokenService(config.AuthConfig{JWTSecret: "test-secret", AccessTokenTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewTokenService() failed: %v", err)
+	}
+	s := store.New(dbtest.SQLite(t))
+	h := NewHabitHandler(s, s)
+	h.now = func() time.Time { return time.Date(2025, 3, 10, 22, 30, 0, 0, time.UTC) }
+
+	router := gin.New()
+	router.Use(middleware.Errors(true))
+	g := router.Group("/habits", middleware.Auth(tokens))
+	g.GET("", h.ListHabits)
+	g.POST("", h.CreateHabit)
+	g.GET("/:id", h.GetHabit)
+	g.PATCH("/:id", h.UpdateHabit)
+	g.DELETE("/:id", h.DeleteHabit)
+	g.GET("/:id/stats", h.HabitStats)
+	g.GET("/:id/checkins", h.ListCheckIns)
+	g.POST("/:id/checkins", h.CheckIn)
+	g.DELETE("/:id/checkins/:date", h.DeleteCheckIn)
+	return router, testUser(t, s, tokens, "ada@example.com", "Europe/Moscow"), testUser(t, s, tokens, "bob@example.com", "")
+}
+
+// testUser creates a user in the given time zone and returns an access token
+func testUser(t *testing.T, s store.Store, tokens *auth.TokenService, email, zone string) string {
+	t.Helper()
+	u := &models.User{Email: email, Name: "Test", PasswordHash: "hash", TimeZone: zone}
+	if err := s.CreateUser(context.Background(), u); err != nil {
+		t.Fatalf("CreateUser() failed: %v", err)
+	}
+	token, _, err := tokens.Issue(auth.Claims{UserID: u.ID, Email: u.Email})
+	if err != nil {
+		t.Fatalf("Issue() failed: %v", err)
+	}
+	return token
+}
+
+func TestHabitFlow(t *testing.T) {
+	router, token, other := habitRouter(t)
+
+	var habit models.Habit
+	code := call(t, router, http.MethodPost, "/habits", token, gin.H{
+		"name":       "Stretch",
+		"schedule":   gin.H{"kind": "weekdays", "weekdays": []string{"mon", "tue", "thu"}, "times_per_week": 3},
+		"start_date": "2025-03-03",
+	}, &habit)
+	if code != http.StatusCreated {
+		t.Fatalf("Expected 201 from create, got %d", code)
+	}
+	if habit.Schedule.TimesPerWeek != 0 || len(habit.Schedule.Weekdays) != 3 {
+		t.Errorf("Expected settings of other schedule kinds to be dropped, got %+v", habit.Schedule)
+	}
+	path := "/habits/" + strconv.FormatInt(habit.ID, 10)
+
+	if code := call(t, router, http.MethodGet, path, other, nil, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another user's habit, got %d", code)
+	}
+
+	// Tuesday 4 March is missed; today is Tuesday 11 March in the user's
+	// time zone and still open
+	for _, date := range []string{"2025-03-03", "2025-03-06", "2025-03-10"} {
+		if code := call(t, router, http.MethodPost, path+"/checkins", token, gin.H{"date": date}, nil); code != http.StatusCreated {
+			t.Fatalf("Expected 201 checking in on %s, got %d", date, code)
+		}
+	}
+	var stats HabitStatsResponse
+	call(t, router, http.MethodGet, path+"/stats?from=2025-03-03", token, nil, &stats)
+	if stats.CurrentStreak != 2 || stats.LongestStreak != 2 || stats.Due != 4 || stats.Completed != 3 {
+		t.Errorf("Expected streaks 2/2 and 3 of 4 done, got %+v", stats)
+	}
+
+	var checkIn models.CheckIn
+	if code := call(t, router, http.MethodPost, path+"/checkins", token, gin.H{"note": "late"}, &checkIn); code != http.StatusCreated {
+		t.Fatalf("Expected 201 checking in today, got %d", code)
+	}
+	if checkIn.Date != models.NewDate(2025, 3, 11) {
+		t.Errorf("Expected check-in on the user's local day 2025-03-11, got %s", checkIn.Date)
+	}
+	if code := call(t, router, http.MethodPost, path+"/checkins", token, gin.H{}, nil); code != http.StatusConflict {
+		t.Errorf("Expected 409 for a second check-in today, got %d", code)
+	}
+	call(t, router, http.MethodGet, path+"/stats?from=2025-03-03&to=2025-03-11", token, nil, &stats)
+	if stats.CurrentStreak != 3 || stats.CompletionRate != 0.8 {
+		t.Errorf("Expected streak 3 and rate 0.8 after checking in today, got %+v", stats)
+	}
+
+	var checkIns []models.CheckIn
+	call(t, router, http.MethodGet, path+"/checkins?from=2025-03-06&to=2025-03-10", token, nil, &checkIns)
+	if len(checkIns) != 2 {
+		t.Errorf("Expected 2 check-ins in range, got %d", len(checkIns))
+	}
+
+	if code := call(t, router, http.MethodDelete, path+"/checkins/2025-03-11", token, nil, nil); code != http.StatusNoContent {
+		t.Errorf("Expected 204 removing a check-in, got %d", code)
+	}
+
+	var archived models.Habit
+	call(t, router, http.MethodPatch, path, token, gin.H{"archived": true}, &archived)
+	if !archived.Archived || archived.Name != "Stretch" {
+		t.Errorf("Expected archived habit with unchanged name, got %+v", archived)
+	}
+	var list []models.Habit
+	call(t, router, http.MethodGet, "/habits", token, nil, &list)
+	if len(list) != 0 {
+		t.Errorf("Expected archived habits to be hidden, got %d", len(list))
+	}
+	call(t, router, http.MethodGet, "/habits?archived=true", token, nil, &list)
+	if len(list) != 1 {
+		t.Errorf("Expected archived habit when requested, got %d", len(list))
+	}
+
+	if code := call(t, router, http.MethodDelete, path, token, nil, nil); code != http.StatusNoContent {
+		t.Errorf("Expected 204 from delete, got %d", code)
+	}
+	if code := call(t, router, http.MethodGet, path, token, nil, nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 after delete, got %d", code)
+	}
+}
+
+func TestHabitValidation(t *testing.T) {
+	router, token, _ := habitRouter(t)
+
+	var habit models.Habit
+	call(t, router, http.MethodPost, "/habits", token, gin.H{"name": "Read", "schedule": gin.H{"kind": "daily"}}, &habit)
+	if habit.StartDate != models.NewDate(2025, 3, 11) {
+		t.Errorf("Expected start date to default to the user's today, got %s", habit.StartDate)
+	}
+	path := "/habits/" + strconv.FormatInt(habit.ID, 10)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       any
+		wantStatus int
+		wantField  string
+	}{
+		{
+			name:   "weekly without times per week",
+			method: http.MethodPost, path: "/habits",
+			body:       gin.H{"name": "Gym", "schedule": gin.H{"kind": "weekly"}},
+			wantStatus: http.StatusUnprocessableEntity, wantField: "schedule.times_per_week",
+		},
+		{
+			name:   "unknown weekday",
+			method: http.MethodPost, path: "/habits",
+			body:       gin.H{"name": "Gym", "schedule": gin.H{"kind": "weekdays", "weekdays": []string{"monday"}}},
+			wantStatus: http.StatusUnprocessableEntity, wantField: "schedule.weekdays[0]",
+		},
+		{
+			name:   "unknown schedule kind",
+			method: http.MethodPost, path: "/habits",
+			body:       gin.H{"name": "Gym", "schedule": gin.H{"kind": "hourly"}},
+			wantStatus: http.StatusUnprocessableEntity, wantField: "schedule.kind",
+		},
+		{
+			name:   "check-in in the future",
+			method: http.MethodPost, path: path + "/checkins",
+			body:       gin.H{"date": "2025-03-12"},
+			wantStatus: http.StatusUnprocessableEntity, wantField: "date",
+		},
+		{
+			name:   "check-in before the start",
+			method: http.MethodPost, path: path + "/checkins",
+			body:       gin.H{"date": "2025-03-10"},
+			wantStatus: http.StatusUnprocessableEntity, wantField: "date",
+		},
+		{
+			name:   "inverted range",
+			method: http.MethodGet, path: path + "/stats?from=2025-03-10&to=2025-03-01",
+			wantStatus: http.StatusUnprocessableEntity, wantField: "from",
+		},
+		{
+			name:   "malformed date",
+			method: http.MethodGet, path: path + "/stats?from=March",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "malformed id",
+			method: http.MethodGet, path: "/habits/abc",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problem apperr.Problem
+			code := call(t, router, tt.method, tt.path, token, tt.body, &problem)
+			if code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d: %+v", tt.wantStatus, code, problem)
+			}
+			if tt.wantField != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != tt.wantField) {
+				t.Errorf("Expected an error on %s, got %+v", tt.wantField, problem.Errors)
+			}
+		})
+	}
+}
@@ -6,18 +6,12 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// HealthCheck returns server health status
-func HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":  "healthy",
-		"service": "sum25-go-flutter-course-backend",
-		"version": "1.0.0",
-	})
+// PingResponse is the body returned by Ping
+type PingResponse struct {
+	Message string `json:"message" example:"pong"`
 }
 
 // Ping returns a simple pong response
 func Ping(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "pong",
-	})
+	c.JSON(http.StatusOK, PingResponse{Message: "pong"})
 }
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/health"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/version"
+)
+
+// ServiceName identifies this backend in health responses
+const ServiceName = "sum25-go-flutter-course-backend"
+
+// LivenessResponse is the body returned by Liveness
+type LivenessResponse struct {
+	Status  string `json:"status" example:"alive"`
+	Service string `json:"service" example:"sum25-go-flutter-course-backend"`
+	Version string `json:"version" doc:"build version from ldflags or module info" example:"v1.2.0"`
+	Commit  string `json:"commit,omitempty" doc:"VCS revision the binary was built from"`
+}
+
+// ReadinessResponse is the body returned by Readiness
+type ReadinessResponse struct {
+	Status  string                   `json:"status" enum:"ready,not_ready"`
+	Service string                   `json:"service" example:"sum25-go-flutter-course-backend"`
+	Version string                   `json:"version" example:"v1.2.0"`
+	Checks  map[string]health.Result `json:"checks" doc:"result of each dependency check by name"`
+}
+
+// Liveness reports that the process is up and serving requests
+func Liveness(c *gin.Context) {
+	info := version.Get()
+	c.JSON(http.StatusOK, LivenessResponse{
+		Status:  "alive",
+		Service: ServiceName,
+		Version: info.Version,
+		Commit:  info.Commit,
+	})
+}
+
+// Readiness runs the registered dependency checks and answers 503 with
+// per-check detail when any of them fails
+func Readiness(registry *health.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := registry.Check(c.Request.Context())
+
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, ReadinessResponse{
+			Status:  report.Status,
+			Service: ServiceName,
+			Version: version.Get().Version,
+			Checks:  report.Checks,
+		})
+	}
+}
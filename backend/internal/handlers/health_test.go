@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/health"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func TestReadiness(t *testing.T) {
+	registry := health.NewRegistry(time.Second)
+	router := gin.New()
+	router.GET("/health/live", Liveness)
+	router.GET("/health/ready", Readiness(registry))
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected 200 with no failing checks, got %d", w.Code)
+	}
+
+	registry.Register("database", health.CheckerFunc(func(ctx context.Context) error {
+		return errors.New("connection refused")
+	}))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 with a failing check, got %d", w.Code)
+	}
+
+	var body struct {
+		Status string                   `json:"status"`
+		Checks map[string]health.Result `json:"checks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid JSON body: %v", err)
+	}
+	if body.Status != health.StatusNotReady || body.Checks["database"].Error != "connection refused" {
+		t.Errorf("Unexpected body: %+v", body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Liveness should not depend on checks, got %d", w.Code)
+	}
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/series"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+)
+
+// Limits of measurement ingestion and series queries
+const (
+	maxClockSkew   = 5 * time.Minute
+	maxSeriesSteps = 1000
+)
+
+// MeasurementInput is one reading in an ingestion batch
+type MeasurementInput struct {
+	ClientID   string    `json:"client_id" binding:"required,max=100" doc:"client-generated ID; resending it is a no-op" example:"3f1c9a52-watch-1741600800"`
+	Type       string    `json:"type" binding:"required,oneof=water sleep heart_rate steps mood"`
+	Value      *float64  `json:"value" binding:"required" example:"250"`
+	Unit       string    `json:"unit,omitempty" doc:"water: ml, l, fl_oz; sleep: min, h, s; heart_rate: bpm; steps: count; mood: score (1-10). Defaults to the first" example:"ml"`
+	RecordedAt time.Time `json:"recorded_at" binding:"required"`
+}
+
+// IngestMeasurementsRequest is the body of IngestMeasurements
+type IngestMeasurementsRequest struct {
+	Measurements []MeasurementInput `json:"measurements" binding:"required,min=1,max=1000,dive"`
+}
+
+// IngestMeasurementsResponse reports what a batch changed
+type IngestMeasurementsResponse struct {
+	Accepted   int `json:"accepted" doc:"measurements stored"`
+	Duplicates int `json:"duplicates" doc:"measurements skipped because their client_id was already sent"`
+}
+
+// SeriesQuery holds the query parameters of Series
+type SeriesQuery struct {
+	Bucket string     `form:"bucket" binding:"omitempty,oneof=hour day week month" doc:"default day"`
+	From   *time.Time `form:"from" doc:"start of the range, rounded down to a bucket; defaults to 24 hours, 30 days, 12 weeks or 12 months before to"`
+	To     *time.Time `form:"to" doc:"end of the range, exclusive, at 15 minute precision; defaults to now"`
+}
+
+// SeriesResponse is an aggregated series of one metric
+type SeriesResponse struct {
+	Type     string         `json:"type" example:"water"`
+	Unit     string         `json:"unit" example:"ml"`
+	Bucket   string         `json:"bucket" example:"day"`
+	TimeZone string         `json:"timezone" example:"Europe/Moscow"`
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Points   []series.Point `json:"points" doc:"buckets with at least one measurement, oldest first"`
+}
+
+// MeasurementHandler ingests wellness measurements and serves aggregated
+// series in the user's time zone
+type MeasurementHandler struct {
+	measurements store.MeasurementStore
+	users        store.UserStore
+	now          func() time.Time
+}
+
+// NewMeasurementHandler creates a measurement handler
+func NewMeasurementHandler(measurements store.MeasurementStore, users store.UserStore) *MeasurementHandler {
+	return &MeasurementHandler{
+		measurements: measurements,
+		users:        users,
+		now:          time.Now,
+	}
+}
+
+// IngestMeasurements stores a batch of measurements. Values are converted to
+// each metric's canonical unit. The batch is rejected as a whole if any
+// measurement is invalid.
+func (h *MeasurementHandler) IngestMeasurements(c *gin.Context) {
+	var req IngestMeasurementsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+
+	latest := h.now().Add(maxClockSkew)
+	batch := make([]*models.Measurement, 0, len(req.Measurements))
+	var fields []apperr.FieldError
+	for i, in := range req.Measurements {
+		field := fmt.Sprintf("measurements[%d]", i)
+		value, err := models.NormalizeMeasurement(in.Type, *in.Value, in.Unit)
+		if err != nil {
+			fields = append(fields, apperr.FieldError{Field: field + ".value", Code: "invalid", Message: err.Error()})
+			continue
+		}
+		if in.RecordedAt.After(latest) {
+			fields = append(fields, apperr.FieldError{Field: field + ".recorded_at", Code: "future", Message: "cannot be in the future"})
+			continue
+		}
+		batch = append(batch, &models.Measurement{
+			ClientID:   in.ClientID,
+			Metric:     in.Type,
+			Value:      value,
+			RecordedAt: in.RecordedAt,
+		})
+	}
+	if len(fields) > 0 {
+		e := apperr.New(apperr.CodeValidationFailed, "")
+		e.Fields = fields
+		apperr.Abort(c, e)
+		return
+	}
+
+	accepted, err := h.measurements.AddMeasurements(c.Request.Context(), user.ID, batch)
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, IngestMeasurementsResponse{
+		Accepted:   accepted,
+		Duplicates: len(batch) - accepted,
+	})
+}
+
+// Series returns a metric aggregated into buckets of the user's time zone
+func (h *MeasurementHandler) Series(c *gin.Context) {
+	metric := c.Param("type")
+	unit, ok := models.MetricUnit(metric)
+	if !ok {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, fmt.Sprintf("unknown metric %q", metric)))
+		return
+	}
+	var query SeriesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		apperr.Abort(c, apperr.Binding(err))
+		return
+	}
+	user, ok := currentUser(c, h.users)
+	if !ok {
+		return
+	}
+	loc := user.Location()
+
+	bucket := query.Bucket
+	if bucket == "" {
+		bucket = series.Day
+	}
+	to := h.now()
+	if query.To != nil {
+		to = *query.To
+	}
+	to = to.In(loc)
+	from := defaultSeriesStart(to, bucket)
+	if query.From != nil {
+		from = *query.From
+	}
+	from = series.Floor(from, bucket, loc)
+
+	if !from.Before(to) {
+		abortField(c, "from", "range", "must be before to")
+		return
+	}
+	steps := 0
+	for t := from; t.Before(to); t = series.Next(t, bucket) {
+		if steps++; steps > maxSeriesSteps {
+			abortField(c, "from", "range", fmt.Sprintf("range must not exceed %d buckets", maxSeriesSteps))
+			return
+		}
+	}
+
+	rollups, err := h.measurements.ListRollups(c.Request.Context(), user.ID, metric, from, to)
+	if err != nil {
+		apperr.Abort(c, apperr.Internal(err))
+		return
+	}
+	c.JSON(http.StatusOK, SeriesResponse{
+		Type:     metric,
+		Unit:     unit,
+		Bucket:   bucket,
+		TimeZone: loc.String(),
+		From:     from,
+		To:       to,
+		Points:   series.Aggregate(rollups, bucket, loc),
+	})
+}
+
+// defaultSeriesStart returns the default start of a series ending at to
+func defaultSeriesStart(to time.Time, bucket string) time.Time {
+	switch bucket {
+	case series.Hour:
+		return to.Add(-24 * time.Hour)
+	case series.Week:
+		return to.AddDate(0, 0, -7*12)
+	case series.Month:
+		return to.AddDate(-1, 0, 0)
+	default:
+		return to.AddDate(0, 0, -30)
+	}
+}
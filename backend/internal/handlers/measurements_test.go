@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/middleware"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/store"
+)
+
+// measurementRouter serves the measurement routes at 12:00 UTC on
+// 12 March 2025 and returns the token of a user in Asia/Kolkata (UTC+5:30)
+func measurementRouter(t *testing.T) (*gin.Engine, string) {
+	t.Helper()
+	tokens, err := auth.NewTokenService(config.AuthConfig{JWTSecret: "test-secret", AccessTokenTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewTokenService() failed: %v", err)
+	}
+	s := store.New(dbtest.SQLite(t))
+	h := NewMeasurementHandler(s, s)
+	h.now = func() time.Time { return time.Date(2025, 3, 12, 12, 0, 0, 0, time.UTC) }
+
+	router := gin.New()
+	router.Use(middleware.Errors(true))
+	g := router.Group("/measurements", middleware.Auth(tokens))
+	g.POST("", h.IngestMeasurements)
+	g.GET("/:type/series", h.Series)
+	return router, testUser(t, s, tokens, "ada@example.com", "Asia/Kolkata")
+}
+
+func TestMeasurementIngestion(t *testing.T) {
+	router, token := measurementRouter(t)
+
+	batch := gin.H{"measurements": []gin.H{
+		// 10 March 23:00 in Kolkata
+		{"client_id": "w1", "type": "water", "value": 0.5, "unit": "l", "recorded_at": "2025-03-10T17:30:00Z"},
+		// 11 March 00:15 in Kolkata
+		{"client_id": "w2", "type": "water", "value": 250, "recorded_at": "2025-03-10T18:45:00Z"},
+		{"client_id": "w3", "type": "water", "value": 300, "unit": "ml", "recorded_at": "2025-03-11T10:00:00+05:30"},
+		{"client_id": "m1", "type": "mood", "value": 7, "recorded_at": "2025-03-11T08:00:00Z"},
+	}}
+	var resp IngestMeasurementsResponse
+	if code := call(t, router, http.MethodPost, "/measurements", token, batch, &resp); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if resp.Accepted != 4 || resp.Duplicates != 0 {
+		t.Errorf("Expected 4 accepted, got %+v", resp)
+	}
+
+	// A retried upload is deduplicated by client_id
+	call(t, router, http.MethodPost, "/measurements", token, batch, &resp)
+	if resp.Accepted != 0 || resp.Duplicates != 4 {
+		t.Errorf("Expected 4 duplicates on retry, got %+v", resp)
+	}
+
+	var daily SeriesResponse
+	code := call(t, router, http.MethodGet, "/measurements/water/series?bucket=day&from=2025-03-10T00:00:00Z", token, nil, &daily)
+	if code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if daily.Unit != "ml" || daily.TimeZone != "Asia/Kolkata" || len(daily.Points) != 2 {
+		t.Fatalf("Expected 2 daily points in ml, got %+v", daily)
+	}
+	first, second := daily.Points[0], daily.Points[1]
+	if first.Start.Day() != 10 || first.Sum != 500 || first.Count != 1 {
+		t.Errorf("Expected 500 ml on 10 March, got %+v", first)
+	}
+	if second.Start.Day() != 11 || second.Sum != 550 || second.Avg != 275 || second.Min != 250 || second.Max != 300 {
+		t.Errorf("Expected 550 ml over 2 readings on 11 March, got %+v", second)
+	}
+
+	var hourly SeriesResponse
+	call(t, router, http.MethodGet, "/measurements/water/series?bucket=hour&from=2025-03-10T17:00:00Z&to=2025-03-10T20:00:00Z", token, nil, &hourly)
+	if len(hourly.Points) != 2 {
+		t.Errorf("Expected 2 hourly points, got %+v", hourly.Points)
+	}
+}
+
+func TestMeasurementValidation(t *testing.T) {
+	router, token := measurementRouter(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       any
+		wantStatus int
+		wantField  string
+	}{
+		{
+			name:   "empty batch",
+			method: http.MethodPost, path: "/measurements",
+			body:       gin.H{"measurements": []gin.H{}},
+			wantStatus: http.StatusUnprocessableEntity, wantField: "measurements",
+		},
+		{
+			name:   "unit of another metric",
+			method: http.MethodPost, path: "/measurements",
+			body: gin.H{"measurements": []gin.H{
+				{"client_id": "a", "type": "steps", "value": 100, "recorded_at": "2025-03-11T08:00:00Z"},
+				{"client_id": "b", "type": "sleep", "value": 8, "unit": "ml", "recorded_at": "2025-03-11T08:00:00Z"},
+			}},
+			wantStatus: http.StatusUnprocessableEntity, wantField: "measurements[1].value",
+		},
+		{
+			name:   "implausible value",
+			method: http.MethodPost, path: "/measurements",
+			body:       gin.H{"measurements": []gin.H{{"client_id": "a", "type": "mood", "value": 11, "recorded_at": "2025-03-11T08:00:00Z"}}},
+			wantStatus: http.StatusUnprocessableEntity, wantField: "measurements[0].value",
+		},
+		{
+			name:   "missing value",
+			method: http.MethodPost, path: "/measurements",
+			body:       gin.H{"measurements": []gin.H{{"client_id": "a", "type": "steps", "recorded_at": "2025-03-11T08:00:00Z"}}},
+			wantStatus: http.StatusUnprocessableEntity, wantField: "measurements[0].value",
+		},
+		{
+			name:   "recorded in the future",
+			method: http.MethodPost, path: "/measurements",
+			body:       gin.H{"measurements": []gin.H{{"client_id": "a", "type": "steps", "value": 10, "recorded_at": "2025-03-13T08:00:00Z"}}},
+			wantStatus: http.StatusUnprocessableEntity, wantField: "measurements[0].recorded_at",
+		},
+		{
+			name:   "unknown metric",
+			method: http.MethodGet, path: "/measurements/weight/series",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:   "too many buckets",
+			method: http.MethodGet, path: "/measurements/steps/series?bucket=hour&from=2024-01-01T00:00:00Z",
+			wantStatus: http.StatusUnprocessableEntity, wantField: "from",
+		},
+		{
+			name:   "unknown bucket",
+			method: http.MethodGet, path: "/measurements/steps/series?bucket=year",
+			wantStatus: http.StatusUnprocessableEntity, wantField: "bucket",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var problem apperr.Problem
+			code := call(t, router, tt.method, tt.path, token, tt.body, &problem)
+			if code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d: %+v", tt.wantStatus, code, problem)
+			}
+			if tt.wantField != "" && (len(problem.Errors) != 1 || problem.Errors[0].Field != tt.wantField) {
+				t.Errorf("Expected an error on %s, got %+v", tt.wantField, problem.Errors)
+			}
+		})
+	}
+}
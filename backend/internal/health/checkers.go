@@ -0,0 +1,74 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// DatabaseChecker pings the database
+func DatabaseChecker(db *sql.DB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	})
+}
+
+// VersionSource reports the applied schema version and pending migrations.
+// It is called on every probe, so it must only read the database.
+type VersionSource interface {
+	Version(ctx context.Context) (int64, int, error)
+}
+
+// MigrationChecker fails while migrations are pending
+func MigrationChecker(src VersionSource) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		version, pending, err := src.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if pending > 0 {
+			return fmt.Errorf("schema at version %d with %d pending migration(s)", version, pending)
+		}
+		return nil
+	})
+}
+
+// DiskSpaceChecker fails when the filesystem holding path has less than
+// minFreeBytes available
+func DiskSpaceChecker(path string, minFreeBytes uint64) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		free, err := freeDiskSpace(path)
+		if err != nil {
+			return err
+		}
+		if free < minFreeBytes {
+			return fmt.Errorf("%d MB free on %s, need %d MB", free>>20, path, minFreeBytes>>20)
+		}
+		return nil
+	})
+}
+
+// GRPCChecker queries the standard gRPC health service at addr. An empty
+// service name checks the server as a whole.
+func GRPCChecker(addr, service string) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("service status %s", resp.GetStatus())
+		}
+		return nil
+	})
+}
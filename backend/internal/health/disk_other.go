@@ -0,0 +1,10 @@
+//go:build !unix
+
+package health
+
+import "errors"
+
+// freeDiskSpace is not implemented on this platform
+func freeDiskSpace(path string) (uint64, error) {
+	return 0, errors.New("disk space check is not supported on this platform")
+}
@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Check statuses
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Report statuses
+const (
+	StatusReady    = "ready"
+	StatusNotReady = "not_ready"
+)
+
+// Checker reports whether a dependency is usable
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts an ordinary function to the Checker interface
+type CheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result is the outcome of a single check
+type Result struct {
+	Status     string  `json:"status" enum:"up,down"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+// Report aggregates the results of every registered check
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// Ready reports whether every check passed
+func (r Report) Ready() bool {
+	return r.Status == StatusReady
+}
+
+type check struct {
+	name    string
+	checker Checker
+	timeout time.Duration
+}
+
+// Registry holds named dependency checks
+type Registry struct {
+	mu             sync.RWMutex
+	checks         []check
+	defaultTimeout time.Duration
+}
+
+// NewRegistry creates a registry whose checks time out after defaultTimeout
+func NewRegistry(defaultTimeout time.Duration) *Registry {
+	return &Registry{defaultTimeout: defaultTimeout}
+}
+
+// Register adds a check using the registry's default timeout
+func (r *Registry) Register(name string, checker Checker) {
+	r.RegisterWithTimeout(name, checker, r.defaultTimeout)
+}
+
+// RegisterWithTimeout adds a check with its own timeout, replacing any check
+// with the same name
+func (r *Registry) RegisterWithTimeout(name string, checker Checker, timeout time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := check{name: name, checker: checker, timeout: timeout}
+	for i := range r.checks {
+		if r.checks[i].name == name {
+			r.checks[i] = c
+			return
+		}
+	}
+	r.checks = append(r.checks, c)
+	sort.Slice(r.checks, func(i, j int) bool { return r.checks[i].name < r.checks[j].name })
+}
+
+// Check runs every registered check concurrently, each bounded by its timeout
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusReady, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusNotReady
+		}
+	}
+	return report
+}
+
+// run executes one check, converting timeouts and panics into failures
+func run(ctx context.Context, c check) (result Result) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result = Result{Status: StatusUp, DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status, result.Error = StatusDown, err.Error()
+	}
+	return result
+}
@@ -0,0 +1,84 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeVersions struct {
+	version int64
+	pending int
+}
+
+func (f fakeVersions) Version(ctx context.Context) (int64, int, error) {
+	return f.version, f.pending, nil
+}
+
+func TestRegistryCheck(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register("ok", CheckerFunc(func(ctx context.Context) error { return nil }))
+	registry.Register("broken", CheckerFunc(func(ctx context.Context) error { return errors.New("boom") }))
+	registry.RegisterWithTimeout("slow", CheckerFunc(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}), 20*time.Millisecond)
+	registry.Register("panics", CheckerFunc(func(ctx context.Context) error { panic("oops") }))
+
+	start := time.Now()
+	report := registry.Check(context.Background())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Checks should run concurrently with timeouts, took %v", elapsed)
+	}
+
+	if report.Ready() {
+		t.Error("Expected report to be not ready")
+	}
+	expected := map[string]string{
+		"ok":     StatusUp,
+		"broken": StatusDown,
+		"slow":   StatusDown,
+		"panics": StatusDown,
+	}
+	for name, status := range expected {
+		if got := report.Checks[name].Status; got != status {
+			t.Errorf("Expected %s to be %s, got %s", name, status, got)
+		}
+	}
+	if report.Checks["broken"].Error != "boom" {
+		t.Errorf("Expected error detail 'boom', got %q", report.Checks["broken"].Error)
+	}
+}
+
+func TestRegistryReady(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	if report := registry.Check(context.Background()); !report.Ready() {
+		t.Error("Empty registry should be ready")
+	}
+
+	registry.Register("db", CheckerFunc(func(ctx context.Context) error { return errors.New("down") }))
+	registry.Register("db", CheckerFunc(func(ctx context.Context) error { return nil }))
+	report := registry.Check(context.Background())
+	if !report.Ready() || len(report.Checks) != 1 {
+		t.Errorf("Expected re-registered check to replace the old one, got %+v", report)
+	}
+}
+
+func TestMigrationChecker(t *testing.T) {
+	if err := MigrationChecker(fakeVersions{version: 3}).Check(context.Background()); err != nil {
+		t.Errorf("Expected up-to-date schema to pass, got %v", err)
+	}
+	if err := MigrationChecker(fakeVersions{version: 2, pending: 1}).Check(context.Background()); err == nil {
+		t.Error("Expected pending migrations to fail the check")
+	}
+}
+
+func TestDiskSpaceChecker(t *testing.T) {
+	if err := DiskSpaceChecker(t.TempDir(), 1).Check(context.Background()); err != nil {
+		t.Errorf("Expected disk check to pass, got %v", err)
+	}
+	if err := DiskSpaceChecker(t.TempDir(), 1<<62).Check(context.Background()); err == nil {
+		t.Error("Expected disk check with huge threshold to fail")
+	}
+}
@@ -0,0 +1,66 @@
+// Package idempotency stores the responses of requests sent with an
+// Idempotency-Key so retries can be answered without running them again.
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Response is a stored HTTP response
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record is the state of a key. Response is nil while the request that
+// claimed the key is still running.
+type Record struct {
+	Fingerprint string
+	Response    *Response
+	ExpiresAt   time.Time
+}
+
+// Store keeps records by key. Implementations must be safe for concurrent
+// use; a shared store lets several replicas honour the same key.
+type Store interface {
+	// Lock claims key for a request with the given fingerprint until
+	// lockTimeout passes. If key holds an unexpired record, Lock returns it
+	// and claims nothing; otherwise it returns nil and a token identifying
+	// the claim.
+	Lock(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (*Record, string, error)
+	// Save stores the response of the request holding key for ttl. It does
+	// nothing unless key is still claimed with token.
+	Save(ctx context.Context, key, token string, resp *Response, ttl time.Duration) error
+	// Release drops an unfinished claim so the request can be retried. It
+	// does nothing unless key is still claimed with token.
+	Release(ctx context.Context, key, token string) error
+}
+
+// newToken returns a random claim token. A claim that outlived its lock
+// timeout may be taken over by a retry; the token keeps the first request
+// from saving or releasing the retry's claim.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("idempotency: claim token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Fingerprint identifies a request by its method, URI and body
+func Fingerprint(method, uri string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write([]byte(uri))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,138 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+)
+
+func TestFingerprint(t *testing.T) {
+	base := Fingerprint(http.MethodPost, "/orders", []byte(`{"a":1}`))
+	if base != Fingerprint(http.MethodPost, "/orders", []byte(`{"a":1}`)) {
+		t.Error("Expected equal requests to share a fingerprint")
+	}
+	others := []string{
+		Fingerprint(http.MethodPatch, "/orders", []byte(`{"a":1}`)),
+		Fingerprint(http.MethodPost, "/orders?x=1", []byte(`{"a":1}`)),
+		Fingerprint(http.MethodPost, "/orders", []byte(`{"a":2}`)),
+		// The separator keeps URI and body apart
+		Fingerprint(http.MethodPost, "/orders{", []byte(`"a":1}`)),
+	}
+	for i, other := range others {
+		if other == base {
+			t.Errorf("Variant %d: expected a different fingerprint", i)
+		}
+	}
+}
+
+// testStore exercises a store whose clock is controlled by now
+func testStore(t *testing.T, s Store, now *time.Time) {
+	ctx := context.Background()
+
+	r, token, err := s.Lock(ctx, "k", "fp1", time.Minute)
+	if err != nil || r != nil || token == "" {
+		t.Fatalf("Expected a free key to be claimed, got %+v %q %v", r, token, err)
+	}
+	r, other, err := s.Lock(ctx, "k", "fp1", time.Minute)
+	if err != nil || r == nil || r.Response != nil || r.Fingerprint != "fp1" || other != "" {
+		t.Fatalf("Expected an in-flight record, got %+v %q %v", r, other, err)
+	}
+
+	resp := &Response{
+		Status: http.StatusCreated,
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   []byte(`{"id":1}`),
+	}
+	if err := s.Save(ctx, "k", token, resp, time.Hour); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	r, _, err = s.Lock(ctx, "k", "fp2", time.Minute)
+	if err != nil || r == nil || r.Response == nil {
+		t.Fatalf("Expected the stored record, got %+v %v", r, err)
+	}
+	if r.Fingerprint != "fp1" || r.Response.Status != http.StatusCreated ||
+		string(r.Response.Body) != `{"id":1}` || r.Response.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected the saved response, got %+v %+v", r, r.Response)
+	}
+
+	// Release leaves finished records alone
+	if err := s.Release(ctx, "k", token); err != nil {
+		t.Fatalf("Release() failed: %v", err)
+	}
+	if r, _, _ := s.Lock(ctx, "k", "fp1", time.Minute); r == nil || r.Response == nil {
+		t.Error("Expected release to keep the saved response")
+	}
+
+	_, token, _ = s.Lock(ctx, "released", "fp1", time.Minute)
+	if err := s.Release(ctx, "released", token); err != nil {
+		t.Fatalf("Release() failed: %v", err)
+	}
+	if r, _, _ := s.Lock(ctx, "released", "fp2", time.Minute); r != nil {
+		t.Errorf("Expected a released key to be claimable, got %+v", r)
+	}
+
+	// A request that outlived its lock cannot touch the claim of the retry
+	// that took the key over
+	_, slow, _ := s.Lock(ctx, "slow", "fp1", time.Minute)
+	*now = now.Add(2 * time.Minute)
+	_, retry, _ := s.Lock(ctx, "slow", "fp2", time.Minute)
+	if err := s.Save(ctx, "slow", slow, resp, time.Hour); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if err := s.Release(ctx, "slow", slow); err != nil {
+		t.Fatalf("Release() failed: %v", err)
+	}
+	if r, _, _ := s.Lock(ctx, "slow", "fp2", time.Minute); r == nil || r.Response != nil || r.Fingerprint != "fp2" {
+		t.Errorf("Expected the retry's claim to stand, got %+v", r)
+	}
+	if err := s.Release(ctx, "slow", retry); err != nil {
+		t.Fatalf("Release() failed: %v", err)
+	}
+	if r, _, _ := s.Lock(ctx, "slow", "fp2", time.Minute); r != nil {
+		t.Errorf("Expected the retry to release its claim, got %+v", r)
+	}
+
+	*now = now.Add(2 * time.Hour)
+	if r, _, err := s.Lock(ctx, "k", "fp2", time.Minute); err != nil || r != nil {
+		t.Errorf("Expected an expired key to be claimable, got %+v %v", r, err)
+	}
+	if r, _, _ := s.Lock(ctx, "k", "fp1", time.Minute); r == nil || r.Fingerprint != "fp2" {
+		t.Errorf("Expected the new claim, got %+v", r)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	testStore(t, s, &now)
+
+	now = now.Add(time.Hour)
+	s.Lock(context.Background(), "other", "fp", time.Minute)
+	if n := s.Len(); n != 1 {
+		t.Errorf("Expected expired records to be swept, got %d", n)
+	}
+}
+
+func TestSQLStore(t *testing.T) {
+	for name, open := range map[string]func(testing.TB) *SQLStore{
+		"sqlite":   func(t testing.TB) *SQLStore { return NewSQLStore(dbtest.SQLite(t)) },
+		"postgres": func(t testing.TB) *SQLStore { return NewSQLStore(dbtest.Postgres(t)) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+			s := open(t)
+			s.db.ExecContext(context.Background(), "DELETE FROM idempotency_keys")
+			s.now = func() time.Time { return now }
+			testStore(t, s, &now)
+
+			n, err := s.DeleteExpired(context.Background(), now.Add(2*time.Minute))
+			if err != nil || n != 3 {
+				t.Errorf("Expected 3 expired records deleted, got %d %v", n, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired records are dropped from memory
+const sweepInterval = time.Minute
+
+// MemoryStore keeps records in process memory, so keys are only honoured by
+// the replica that saw them first. Expired records are evicted during
+// periodic sweeps.
+type MemoryStore struct {
+	mu        sync.Mutex
+	records   map[string]*memoryRecord
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// memoryRecord is a record with the token of its claim
+type memoryRecord struct {
+	Record
+	token string
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		records: make(map[string]*memoryRecord),
+		now:     time.Now,
+	}
+}
+
+// Lock claims key unless it holds an unexpired record
+func (s *MemoryStore) Lock(_ context.Context, key, fingerprint string, lockTimeout time.Duration) (*Record, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	if r, ok := s.records[key]; ok && now.Before(r.ExpiresAt) {
+		copied := r.Record
+		return &copied, "", nil
+	}
+	s.records[key] = &memoryRecord{Record: Record{Fingerprint: fingerprint, ExpiresAt: now.Add(lockTimeout)}, token: token}
+	return nil, token, nil
+}
+
+// Save stores the response for key if the key is still claimed with token
+func (s *MemoryStore) Save(_ context.Context, key, token string, resp *Response, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok && r.Response == nil && r.token == token {
+		r.Response = resp
+		r.ExpiresAt = s.now().Add(ttl)
+	}
+	return nil
+}
+
+// Release drops the claim on key if it is still held with token
+func (s *MemoryStore) Release(_ context.Context, key, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r, ok := s.records[key]; ok && r.Response == nil && r.token == token {
+		delete(s.records, key)
+	}
+	return nil
+}
+
+// Len returns the number of records held
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.records)
+}
+
+// sweep drops expired records
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, r := range s.records {
+		if !now.Before(r.ExpiresAt) {
+			delete(s.records, key)
+		}
+	}
+	s.lastSweep = now
+}
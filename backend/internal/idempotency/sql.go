@@ -0,0 +1,133 @@
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// SQLStore keeps records in the idempotency_keys table, shared by every
+// replica. Expired rows are reclaimed by Lock and deleted by DeleteExpired.
+type SQLStore struct {
+	db  *database.DB
+	now func() time.Time
+}
+
+// NewSQLStore creates a store on a migrated database
+func NewSQLStore(db *database.DB) *SQLStore {
+	return &SQLStore{db: db, now: time.Now}
+}
+
+// Lock claims key unless it holds an unexpired record. Claiming is a single
+// upsert, so concurrent requests with the same key cannot both win.
+func (s *SQLStore) Lock(ctx context.Context, key, fingerprint string, lockTimeout time.Duration) (*Record, string, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, "", err
+	}
+	query := s.db.Rebind(`INSERT INTO idempotency_keys (idempotency_key, fingerprint, claim, status, headers, body, expires_at, created_at)
+		VALUES (?, ?, ?, 0, '', NULL, ?, ?)
+		ON CONFLICT (idempotency_key) DO UPDATE SET
+			fingerprint = excluded.fingerprint, claim = excluded.claim, status = 0, headers = '', body = NULL,
+			expires_at = excluded.expires_at, created_at = excluded.created_at
+		WHERE idempotency_keys.expires_at <= excluded.created_at`)
+
+	// A record expiring between the upsert and the select is claimed on the
+	// second attempt
+	for attempt := 0; attempt < 2; attempt++ {
+		now := s.now().UTC()
+		res, err := s.db.ExecContext(ctx, query, key, fingerprint, token, now.Add(lockTimeout), now)
+		if err != nil {
+			return nil, "", fmt.Errorf("idempotency: lock: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, "", fmt.Errorf("idempotency: lock: %w", err)
+		}
+		if n == 1 {
+			return nil, token, nil
+		}
+
+		r, err := s.get(ctx, key, now)
+		if err != nil {
+			return nil, "", err
+		}
+		if r != nil {
+			return r, "", nil
+		}
+	}
+	return nil, "", fmt.Errorf("idempotency: lock: key %q changed concurrently", key)
+}
+
+// get returns the unexpired record of key, or nil
+func (s *SQLStore) get(ctx context.Context, key string, now time.Time) (*Record, error) {
+	var r Record
+	var status int
+	var headers string
+	var body []byte
+	err := s.db.QueryRowContext(ctx, s.db.Rebind(`SELECT fingerprint, status, headers, body, expires_at
+		FROM idempotency_keys WHERE idempotency_key = ? AND expires_at > ?`), key, now).
+		Scan(&r.Fingerprint, &status, &headers, &body, &r.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("idempotency: get: %w", err)
+	}
+	r.ExpiresAt = r.ExpiresAt.UTC()
+	if status != 0 {
+		r.Response = &Response{Status: status, Body: body}
+		if err := json.Unmarshal([]byte(headers), &r.Response.Header); err != nil {
+			return nil, fmt.Errorf("idempotency: decode headers of %q: %w", key, err)
+		}
+	}
+	return &r, nil
+}
+
+// Save stores the response for key if the key is still claimed with token
+func (s *SQLStore) Save(ctx context.Context, key, token string, resp *Response, ttl time.Duration) error {
+	header := resp.Header
+	if header == nil {
+		header = http.Header{}
+	}
+	headers, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("idempotency: encode headers: %w", err)
+	}
+	body := resp.Body
+	if body == nil {
+		body = []byte{}
+	}
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE idempotency_keys
+		SET status = ?, headers = ?, body = ?, expires_at = ?
+		WHERE idempotency_key = ? AND status = 0 AND claim = ?`),
+		resp.Status, string(headers), body, s.now().UTC().Add(ttl), key, token); err != nil {
+		return fmt.Errorf("idempotency: save: %w", err)
+	}
+	return nil
+}
+
+// Release drops the claim on key if it is still held with token
+func (s *SQLStore) Release(ctx context.Context, key, token string) error {
+	query := s.db.Rebind("DELETE FROM idempotency_keys WHERE idempotency_key = ? AND status = 0 AND claim = ?")
+	if _, err := s.db.ExecContext(ctx, query, key, token); err != nil {
+		return fmt.Errorf("idempotency: release: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired deletes records that expired before the given time and
+// returns how many were removed
+func (s *SQLStore) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.db.Rebind("DELETE FROM idempotency_keys WHERE expires_at <= ?"), before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("idempotency: delete expired: %w", err)
+	}
+	return res.RowsAffected()
+}
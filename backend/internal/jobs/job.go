@@ -0,0 +1,133 @@
+// Package jobs runs deferred and recurring work stored in the jobs table.
+// Jobs are leased before they run, so each run happens on one replica at a
+// time; a run whose lease expires (e.g. the process died) is retried until
+// its attempts run out, which makes execution at-least-once. Handlers must
+// therefore be idempotent.
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Status of a job
+type Status string
+
+// Job statuses
+const (
+	StatusScheduled Status = "scheduled"
+	StatusRunning   Status = "running"
+	StatusDone      Status = "done"
+	StatusFailed    Status = "failed"
+)
+
+// Job is a stored unit of work
+type Job struct {
+	ID          int64
+	Kind        string
+	Key         string
+	Payload     json.RawMessage
+	Schedule    string
+	TimeZone    string
+	Status      Status
+	RunAt       time.Time
+	Attempts    int
+	MaxAttempts int
+	LastError   string
+}
+
+// Recurring reports whether the job runs on a schedule
+func (j *Job) Recurring() bool {
+	return j.Schedule != ""
+}
+
+// Decode unmarshals the payload into v
+func (j *Job) Decode(v any) error {
+	return json.Unmarshal(j.Payload, v)
+}
+
+// Spec describes a job to enqueue
+type Spec struct {
+	// Kind selects the handler
+	Kind string
+	// Key makes the job unique: enqueueing an existing key replaces it
+	Key string
+	// Payload is marshalled to JSON and handed to the handler
+	Payload any
+	// RunAt is when a one-off job runs, or the first run of a recurring one;
+	// zero means now, or the next scheduled time
+	RunAt time.Time
+	// Schedule is a cron expression ("0 9 * * 1") or descriptor
+	// ("@every 2h", "@daily"); empty for one-off jobs
+	Schedule string
+	// TimeZone is the IANA zone the schedule is evaluated in; default UTC
+	TimeZone string
+	// MaxAttempts bounds retries of one run; zero uses the scheduler default
+	MaxAttempts int
+}
+
+// cronParser accepts standard five-field expressions and descriptors
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// nextRun returns the first time after now that schedule fires in zone
+func nextRun(schedule, zone string, now time.Time) (time.Time, error) {
+	loc, err := loadLocation(zone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	sched, err := cronParser.Parse(schedule)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("jobs: invalid schedule %q: %w", schedule, err)
+	}
+	next := sched.Next(now.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("jobs: schedule %q never fires", schedule)
+	}
+	return next.UTC(), nil
+}
+
+// loadLocation resolves an IANA zone name, defaulting to UTC
+func loadLocation(zone string) (*time.Location, error) {
+	if zone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: unknown time zone %q", zone)
+	}
+	return loc, nil
+}
+
+// backoff returns the delay before retry number attempt (1-based):
+// base, 2×base, 4×base, … capped at one hour
+func backoff(base time.Duration, attempt int) time.Duration {
+	const maxBackoff = time.Hour
+	d := base
+	for i := 1; i < attempt && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, maxBackoff)
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the run is not retried
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+// isPermanent reports whether err was wrapped with Permanent
+func isPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
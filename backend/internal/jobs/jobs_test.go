@@ -0,0 +1,425 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/clock"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+)
+
+var start = time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+
+func testConfig() config.JobsConfig {
+	return config.Default().Jobs
+}
+
+func newScheduler(db *database.DB, clk clock.Clock) *Scheduler {
+	return New(db, testConfig(), slog.New(slog.NewTextHandler(io.Discard, nil)), clk)
+}
+
+func mustGet(t *testing.T, s *Scheduler, key string) *Job {
+	t.Helper()
+	j, err := s.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get(%q) failed: %v", key, err)
+	}
+	return j
+}
+
+func mustRunDue(t *testing.T, s *Scheduler, want int) {
+	t.Helper()
+	n, err := s.RunDue(context.Background())
+	if err != nil {
+		t.Fatalf("RunDue() failed: %v", err)
+	}
+	if n != want {
+		t.Errorf("Expected %d jobs to run, got %d", want, n)
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		zone     string
+		now      time.Time
+		want     time.Time
+		wantErr  bool
+	}{
+		{
+			name:     "daily in UTC",
+			schedule: "0 9 * * *",
+			now:      start,
+			want:     time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "daily in time zone",
+			schedule: "0 9 * * *",
+			zone:     "Europe/Moscow",
+			now:      start,
+			want:     time.Date(2025, 3, 11, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "across DST change",
+			schedule: "0 9 * * *",
+			zone:     "America/New_York",
+			now:      time.Date(2025, 3, 8, 15, 0, 0, 0, time.UTC),
+			want:     time.Date(2025, 3, 9, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "descriptor",
+			schedule: "@every 90m",
+			now:      start,
+			want:     start.Add(90 * time.Minute),
+		},
+		{name: "invalid expression", schedule: "every day", now: start, wantErr: true},
+		{name: "unknown zone", schedule: "@daily", zone: "Mars/Olympus", now: start, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextRun(tt.schedule, tt.zone, tt.now)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("nextRun() failed: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := backoff(10*time.Second, tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d): expected %v, got %v", tt.attempt, tt.want, got)
+		}
+	}
+}
+
+func TestOneOffJob(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(start)
+	s := newScheduler(dbtest.SQLite(t), clk)
+
+	var got struct{ UserID int64 }
+	s.Handle("welcome", func(ctx context.Context, j *Job) error {
+		return j.Decode(&got)
+	})
+	if err := s.Enqueue(ctx, Spec{Kind: "welcome", Key: "welcome:7", Payload: map[string]int64{"UserID": 7}, RunAt: start.Add(time.Minute)}); err != nil {
+		t.Fatalf("Enqueue() failed: %v", err)
+	}
+
+	mustRunDue(t, s, 0)
+	clk.Advance(time.Minute)
+	mustRunDue(t, s, 1)
+
+	if got.UserID != 7 {
+		t.Errorf("Expected payload user 7, got %d", got.UserID)
+	}
+	if j := mustGet(t, s, "welcome:7"); j.Status != StatusDone || j.Attempts != 1 {
+		t.Errorf("Expected done after 1 attempt, got %s after %d", j.Status, j.Attempts)
+	}
+	clk.Advance(time.Hour)
+	mustRunDue(t, s, 0)
+}
+
+func TestRecurringJob(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(start)
+	s := newScheduler(dbtest.SQLite(t), clk)
+
+	var runs atomic.Int32
+	s.Handle("digest", func(ctx context.Context, j *Job) error {
+		runs.Add(1)
+		return nil
+	})
+	if err := s.Enqueue(ctx, Spec{Kind: "digest", Key: "digest", Schedule: "0 9 * * *", TimeZone: "Europe/Moscow"}); err != nil {
+		t.Fatalf("Enqueue() failed: %v", err)
+	}
+	first := time.Date(2025, 3, 11, 6, 0, 0, 0, time.UTC)
+	if j := mustGet(t, s, "digest"); !j.RunAt.Equal(first) {
+		t.Fatalf("Expected first run at %v, got %v", first, j.RunAt)
+	}
+
+	clk.Set(first)
+	mustRunDue(t, s, 1)
+	j := mustGet(t, s, "digest")
+	if j.Status != StatusScheduled || !j.RunAt.Equal(first.Add(24*time.Hour)) || j.Attempts != 0 {
+		t.Errorf("Expected rescheduled for next day, got %s at %v (attempts %d)", j.Status, j.RunAt, j.Attempts)
+	}
+
+	// Re-enqueueing the same key replaces the schedule instead of duplicating
+	if err := s.Enqueue(ctx, Spec{Kind: "digest", Key: "digest", Schedule: "@hourly"}); err != nil {
+		t.Fatalf("Enqueue() failed: %v", err)
+	}
+	clk.Advance(time.Hour)
+	mustRunDue(t, s, 1)
+	if runs.Load() != 2 {
+		t.Errorf("Expected 2 runs, got %d", runs.Load())
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(start)
+	s := newScheduler(dbtest.SQLite(t), clk)
+
+	s.Handle("flaky", func(ctx context.Context, j *Job) error {
+		return errors.New("upstream unavailable")
+	})
+	if err := s.Enqueue(ctx, Spec{Kind: "flaky", Key: "flaky", MaxAttempts: 3}); err != nil {
+		t.Fatalf("Enqueue() failed: %v", err)
+	}
+
+	mustRunDue(t, s, 1)
+	j := mustGet(t, s, "flaky")
+	if j.Status != StatusScheduled || !j.RunAt.Equal(start.Add(10*time.Second)) || j.LastError != "upstream unavailable" {
+		t.Fatalf("Expected retry in 10s, got %s at %v (%q)", j.Status, j.RunAt, j.LastError)
+	}
+
+	clk.Advance(10 * time.Second)
+	mustRunDue(t, s, 1)
+	if j := mustGet(t, s, "flaky"); !j.RunAt.Equal(clk.Now().Add(20 * time.Second)) {
+		t.Errorf("Expected second retry in 20s, got %v", j.RunAt)
+	}
+
+	clk.Advance(20 * time.Second)
+	mustRunDue(t, s, 1)
+	if j := mustGet(t, s, "flaky"); j.Status != StatusFailed || j.Attempts != 3 {
+		t.Errorf("Expected failed after 3 attempts, got %s after %d", j.Status, j.Attempts)
+	}
+}
+
+func TestPermanentError(t *testing.T) {
+	ctx := context.Background()
+	s := newScheduler(dbtest.SQLite(t), clock.NewFake(start))
+
+	s.Handle("broken", func(ctx context.Context, j *Job) error {
+		panic("bad payload")
+	})
+	s.Handle("invalid", func(ctx context.Context, j *Job) error {
+		return Permanent(errors.New("user deleted"))
+	})
+	for _, kind := range []string{"broken", "invalid"} {
+		if err := s.Enqueue(ctx, Spec{Kind: kind, Key: kind}); err != nil {
+			t.Fatalf("Enqueue() failed: %v", err)
+		}
+	}
+	mustRunDue(t, s, 2)
+
+	if j := mustGet(t, s, "broken"); j.Status != StatusScheduled || j.LastError != "jobs: handler panicked: bad payload" {
+		t.Errorf("Expected panic to be retried, got %s (%q)", j.Status, j.LastError)
+	}
+	if j := mustGet(t, s, "invalid"); j.Status != StatusFailed {
+		t.Errorf("Expected permanent error to fail the job, got %s", j.Status)
+	}
+}
+
+func TestLeasing(t *testing.T) {
+	ctx := context.Background()
+	db := dbtest.SQLite(t)
+	clk := clock.NewFake(start)
+	a, b := newScheduler(db, clk), newScheduler(db, clk)
+
+	// a's run outlives its lease, as if the process had hung or died
+	release := make(chan struct{})
+	var runs atomic.Int32
+	a.Handle("sync", func(ctx context.Context, j *Job) error {
+		runs.Add(1)
+		<-release
+		return nil
+	})
+	b.Handle("sync", func(ctx context.Context, j *Job) error {
+		runs.Add(1)
+		return nil
+	})
+	if err := a.Enqueue(ctx, Spec{Kind: "sync", Key: "sync"}); err != nil {
+		t.Fatalf("Enqueue() failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := a.RunDue(ctx)
+		done <- err
+	}()
+	for runs.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	mustRunDue(t, b, 0)
+
+	clk.Advance(testConfig().LeaseDuration + time.Second)
+	mustRunDue(t, b, 1)
+	if j := mustGet(t, b, "sync"); j.Status != StatusDone || j.Attempts != 2 {
+		t.Errorf("Expected done after 2 attempts, got %s after %d", j.Status, j.Attempts)
+	}
+
+	// a finishes late; its outcome must not overwrite b's
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("RunDue() failed: %v", err)
+	}
+	if j := mustGet(t, b, "sync"); j.Status != StatusDone || j.Attempts != 2 {
+		t.Errorf("Expected b's outcome to stand, got %s after %d", j.Status, j.Attempts)
+	}
+}
+
+func TestLeaseReturnsOnlyNewClaims(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(start)
+	s := newScheduler(dbtest.SQLite(t), clk)
+	if err := s.Enqueue(ctx, Spec{Kind: "sync", Key: "sync"}); err != nil {
+		t.Fatalf("Enqueue() failed: %v", err)
+	}
+
+	until := start.Add(testConfig().LeaseDuration)
+	leased, err := s.store.lease(ctx, s.owner, start, until, 10)
+	if err != nil || len(leased) != 1 {
+		t.Fatalf("Expected 1 leased job, got %d (%v)", len(leased), err)
+	}
+	// The release failed, so the job is still leased to this owner; the next
+	// poll must not run it again while the lease lasts
+	clk.Advance(time.Minute)
+	mustRunDue(t, s, 0)
+
+	clk.Set(until.Add(time.Second))
+	mustRunDue(t, s, 1)
+}
+
+func TestExhaustedLeaseFails(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(start)
+	s := newScheduler(dbtest.SQLite(t), clk)
+	maxAttempts := testConfig().MaxAttempts
+	for _, spec := range []Spec{{Kind: "crash", Key: "once"}, {Kind: "crash", Key: "hourly", Schedule: "@hourly", RunAt: start}} {
+		if err := s.Enqueue(ctx, spec); err != nil {
+			t.Fatalf("Enqueue() failed: %v", err)
+		}
+	}
+
+	// Every attempt dies with the process, so no outcome is recorded
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		leased, err := s.store.lease(ctx, "crashed", clk.Now(), clk.Now().Add(testConfig().LeaseDuration), 10)
+		if err != nil || len(leased) != 2 {
+			t.Fatalf("Attempt %d: expected 2 leased jobs, got %d (%v)", attempt, len(leased), err)
+		}
+		clk.Advance(testConfig().LeaseDuration + time.Second)
+	}
+
+	mustRunDue(t, s, 0)
+	if j := mustGet(t, s, "once"); j.Status != StatusFailed || j.Attempts != maxAttempts || j.LastError != errLeaseExpired.Error() {
+		t.Errorf("Expected failed after %d attempts, got %s after %d (%q)", maxAttempts, j.Status, j.Attempts, j.LastError)
+	}
+	if j := mustGet(t, s, "hourly"); j.Status != StatusScheduled || j.Attempts != 0 || !j.RunAt.After(clk.Now()) {
+		t.Errorf("Expected the next occurrence to be scheduled, got %s at %v after %d", j.Status, j.RunAt, j.Attempts)
+	}
+}
+
+func TestEnqueueOnce(t *testing.T) {
+	ctx := context.Background()
+	clk := clock.NewFake(start)
+	s := newScheduler(dbtest.SQLite(t), clk)
+	s.Handle("purge", func(ctx context.Context, j *Job) error { return errors.New("database down") })
+
+	spec := Spec{Kind: "purge", Key: "purge", Schedule: "@hourly"}
+	if err := s.EnqueueOnce(ctx, spec); err != nil {
+		t.Fatalf("EnqueueOnce() failed: %v", err)
+	}
+	clk.Advance(time.Hour)
+	mustRunDue(t, s, 1)
+	before := mustGet(t, s, "purge")
+
+	// A restart keeps the pending retry instead of resetting it
+	clk.Advance(time.Second)
+	if err := s.EnqueueOnce(ctx, spec); err != nil {
+		t.Fatalf("EnqueueOnce() failed: %v", err)
+	}
+	if j := mustGet(t, s, "purge"); !j.RunAt.Equal(before.RunAt) || j.Attempts != 1 {
+		t.Errorf("Expected run at %v after 1 attempt, got %v after %d", before.RunAt, j.RunAt, j.Attempts)
+	}
+
+	// A changed schedule replaces the job
+	spec.Schedule = "0 3 * * *"
+	if err := s.EnqueueOnce(ctx, spec); err != nil {
+		t.Fatalf("EnqueueOnce() failed: %v", err)
+	}
+	if j := mustGet(t, s, "purge"); j.Schedule != "0 3 * * *" || j.Attempts != 0 {
+		t.Errorf("Expected the new schedule with no attempts, got %q after %d", j.Schedule, j.Attempts)
+	}
+
+	if err := s.EnqueueOnce(ctx, Spec{Kind: "purge"}); err == nil {
+		t.Error("Expected error for a job without a key, got nil")
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	ctx := context.Background()
+	s := newScheduler(dbtest.SQLite(t), nil)
+	s.cfg.PollInterval = 10 * time.Millisecond
+
+	ran := make(chan struct{}, 1)
+	s.Handle("ping", func(ctx context.Context, j *Job) error {
+		ran <- struct{}{}
+		return nil
+	})
+	if err := s.Enqueue(ctx, Spec{Kind: "ping"}); err != nil {
+		t.Fatalf("Enqueue() failed: %v", err)
+	}
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected job to run after Start")
+	}
+
+	stopCtx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	if err := s.Stop(stopCtx); err != nil {
+		t.Errorf("Stop() failed: %v", err)
+	}
+}
+
+func TestEnqueueValidation(t *testing.T) {
+	s := newScheduler(dbtest.SQLite(t), clock.NewFake(start))
+	tests := []struct {
+		name string
+		spec Spec
+	}{
+		{name: "missing kind", spec: Spec{}},
+		{name: "bad schedule", spec: Spec{Kind: "x", Schedule: "61 * * * *"}},
+		{name: "bad zone", spec: Spec{Kind: "x", TimeZone: "Nowhere"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Enqueue(context.Background(), tt.spec); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/clock"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/lifecycle"
+)
+
+// errLeaseExpired is recorded for jobs whose last attempt never finished
+var errLeaseExpired = errors.New("jobs: lease expired before the last attempt finished")
+
+// Handler runs one attempt of a job. Returning an error schedules a retry
+// unless the error is wrapped with Permanent.
+type Handler func(ctx context.Context, job *Job) error
+
+// Scheduler polls the jobs table and runs due jobs with registered handlers
+type Scheduler struct {
+	store    store
+	cfg      config.JobsConfig
+	logger   *slog.Logger
+	clock    clock.Clock
+	owner    string
+	handlers map[string]Handler
+
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	stopRuns context.CancelFunc
+	loop     sync.WaitGroup
+}
+
+// New creates a scheduler. A nil clk uses the system clock.
+func New(db *database.DB, cfg config.JobsConfig, logger *slog.Logger, clk clock.Clock) *Scheduler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if clk == nil {
+		clk = clock.Real{}
+	}
+	return &Scheduler{
+		store:    store{db: db},
+		cfg:      cfg,
+		logger:   logger,
+		clock:    clk,
+		owner:    newOwnerID(),
+		handlers: make(map[string]Handler),
+	}
+}
+
+// newOwnerID identifies this process in leases
+func newOwnerID() string {
+	host, _ := os.Hostname()
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(b))
+}
+
+// Handle registers the handler for a job kind; it must be called before Start
+func (s *Scheduler) Handle(kind string, h Handler) {
+	s.handlers[kind] = h
+}
+
+// Enqueue stores a job. A job with the same Key is replaced unless it is
+// running, in which case the call is a no-op.
+func (s *Scheduler) Enqueue(ctx context.Context, spec Spec) error {
+	return s.enqueue(ctx, spec, false)
+}
+
+// EnqueueOnce stores a job unless one with the same Key exists, for jobs
+// declared at startup. An existing job keeps its next run and attempts; it
+// is only replaced when its schedule or time zone changed.
+func (s *Scheduler) EnqueueOnce(ctx context.Context, spec Spec) error {
+	if spec.Key == "" {
+		return errors.New("jobs: EnqueueOnce needs a key")
+	}
+	return s.enqueue(ctx, spec, true)
+}
+
+func (s *Scheduler) enqueue(ctx context.Context, spec Spec, keepRuns bool) error {
+	if spec.Kind == "" {
+		return errors.New("jobs: kind is required")
+	}
+	now := s.clock.Now()
+
+	j := &Job{
+		Kind:        spec.Kind,
+		Key:         spec.Key,
+		Schedule:    spec.Schedule,
+		TimeZone:    spec.TimeZone,
+		RunAt:       spec.RunAt,
+		MaxAttempts: spec.MaxAttempts,
+	}
+	if j.TimeZone == "" {
+		j.TimeZone = "UTC"
+	}
+	if j.MaxAttempts <= 0 {
+		j.MaxAttempts = s.cfg.MaxAttempts
+	}
+	if _, err := loadLocation(j.TimeZone); err != nil {
+		return err
+	}
+	if j.Recurring() {
+		next, err := nextRun(j.Schedule, j.TimeZone, now)
+		if err != nil {
+			return err
+		}
+		if j.RunAt.IsZero() {
+			j.RunAt = next
+		}
+	} else if j.RunAt.IsZero() {
+		j.RunAt = now
+	}
+
+	j.Payload = json.RawMessage("{}")
+	if spec.Payload != nil {
+		payload, err := json.Marshal(spec.Payload)
+		if err != nil {
+			return fmt.Errorf("jobs: encode payload: %w", err)
+		}
+		j.Payload = payload
+	}
+	return s.store.insert(ctx, j, now, keepRuns)
+}
+
+// Cancel deletes the job with the given key
+func (s *Scheduler) Cancel(ctx context.Context, key string) error {
+	return s.store.cancel(ctx, key)
+}
+
+// Get returns the job with the given key
+func (s *Scheduler) Get(ctx context.Context, key string) (*Job, error) {
+	return s.store.getByKey(ctx, key)
+}
+
+// RunDue leases the jobs that are due and runs them concurrently, returning
+// once they finish. It returns the number of jobs run. Jobs whose last
+// attempt never finished are settled as failed first instead of being run.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	now := s.clock.Now()
+	if err := s.failExhausted(ctx, now); err != nil {
+		return 0, err
+	}
+	leased, err := s.store.lease(ctx, s.owner, now, now.Add(s.cfg.LeaseDuration), s.cfg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, j := range leased {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.run(ctx, j)
+		}()
+	}
+	wg.Wait()
+	return len(leased), nil
+}
+
+// failExhausted settles the jobs whose lease expired on their last attempt
+// as if that attempt had failed: one-off jobs fail and recurring jobs move on
+// to their next occurrence. Otherwise a job that crashes the process would
+// be retried forever.
+func (s *Scheduler) failExhausted(ctx context.Context, now time.Time) error {
+	exhausted, err := s.store.leaseExhausted(ctx, s.owner, now, now.Add(s.cfg.LeaseDuration), s.cfg.BatchSize)
+	if err != nil {
+		return err
+	}
+	for _, j := range exhausted {
+		s.settle(j, errLeaseExpired, now)
+		s.logger.Warn("job failed", "job_id", j.ID, "kind", j.Kind, "attempt", j.Attempts,
+			"error", errLeaseExpired, "status", j.Status, "next_run", j.RunAt)
+		if _, err := s.store.release(ctx, j, s.owner, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run executes one leased job and records the outcome
+func (s *Scheduler) run(ctx context.Context, j *Job) {
+	logger := s.logger.With("job_id", j.ID, "kind", j.Kind, "attempt", j.Attempts)
+
+	runCtx, cancel := context.WithTimeout(ctx, s.cfg.LeaseDuration)
+	err := s.invoke(runCtx, j)
+	cancel()
+
+	now := s.clock.Now()
+	s.settle(j, err, now)
+	if err != nil {
+		logger.Warn("job failed", "error", err, "status", j.Status, "next_run", j.RunAt)
+	} else {
+		logger.Debug("job done", "next_run", j.RunAt)
+	}
+
+	// Record the outcome even if ctx was cancelled during shutdown
+	ok, err := s.store.release(context.WithoutCancel(ctx), j, s.owner, now)
+	if err != nil {
+		logger.Error("recording job outcome failed", "error", err)
+	} else if !ok {
+		logger.Warn("job lease expired before it finished")
+	}
+}
+
+// invoke calls the handler, converting panics to errors
+func (s *Scheduler) invoke(ctx context.Context, j *Job) (err error) {
+	h, ok := s.handlers[j.Kind]
+	if !ok {
+		return fmt.Errorf("jobs: no handler for kind %q", j.Kind)
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("jobs: handler panicked: %v", r)
+		}
+	}()
+	return h(ctx, j)
+}
+
+// settle updates j after a run: success completes a one-off job, failures
+// back off until attempts are exhausted, and recurring jobs then move on to
+// their next occurrence
+func (s *Scheduler) settle(j *Job, err error, now time.Time) {
+	if err == nil {
+		j.LastError = ""
+	} else {
+		j.LastError = err.Error()
+		if j.Attempts < j.MaxAttempts && !isPermanent(err) {
+			j.Status = StatusScheduled
+			j.RunAt = now.Add(backoff(s.cfg.RetryBackoff, j.Attempts))
+			return
+		}
+	}
+
+	if !j.Recurring() {
+		j.Status = StatusDone
+		if err != nil {
+			j.Status = StatusFailed
+		}
+		return
+	}
+	next, nextErr := nextRun(j.Schedule, j.TimeZone, now)
+	if nextErr != nil {
+		j.Status, j.LastError = StatusFailed, nextErr.Error()
+		return
+	}
+	j.Status, j.RunAt, j.Attempts = StatusScheduled, next, 0
+}
+
+// Start polls for due jobs every PollInterval until Stop
+func (s *Scheduler) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return errors.New("jobs: scheduler already started")
+	}
+
+	// Polling stops first on shutdown; runs in progress get until the
+	// shutdown deadline before their context is cancelled
+	runCtx, stopRuns := context.WithCancel(context.WithoutCancel(ctx))
+	pollCtx, cancel := context.WithCancel(runCtx)
+	s.cancel, s.stopRuns = cancel, stopRuns
+
+	s.loop.Add(1)
+	go func() {
+		defer s.loop.Done()
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			s.poll(pollCtx, runCtx)
+			select {
+			case <-pollCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// poll runs one batch unless polling has stopped
+func (s *Scheduler) poll(pollCtx, runCtx context.Context) {
+	if pollCtx.Err() != nil {
+		return
+	}
+	if _, err := s.RunDue(runCtx); err != nil {
+		s.logger.Error("polling jobs failed", "error", err)
+	}
+}
+
+// Stop stops polling and waits for running jobs until ctx expires, then
+// cancels them. Cancelled jobs are retried later.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel, stopRuns := s.cancel, s.stopRuns
+	s.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.loop.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		stopRuns()
+		return nil
+	case <-ctx.Done():
+		stopRuns()
+		<-done
+		return fmt.Errorf("jobs: stop: %w", ctx.Err())
+	}
+}
+
+// Component wraps the scheduler for the lifecycle manager
+func (s *Scheduler) Component(name string, dependsOn ...string) lifecycle.Component {
+	return lifecycle.Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start:     s.Start,
+		Stop:      s.Stop,
+	}
+}
@@ -0,0 +1,174 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+const jobColumns = "id, kind, unique_key, payload, schedule, timezone, status, run_at, attempts, max_attempts, last_error"
+
+// store holds the jobs table queries
+type store struct {
+	db *database.DB
+}
+
+// insert adds a job, or replaces the definition of the job with the same key
+// unless that job is currently leased. With keepRuns set an existing job is
+// only replaced when its schedule or time zone changed, so its next run and
+// attempts are kept.
+func (s store) insert(ctx context.Context, j *Job, now time.Time, keepRuns bool) error {
+	var key sql.NullString
+	if j.Key != "" {
+		key = sql.NullString{String: j.Key, Valid: true}
+	}
+	replace := "jobs.locked_until IS NULL OR jobs.locked_until < excluded.updated_at"
+	if keepRuns {
+		replace = "(" + replace + ") AND (jobs.schedule <> excluded.schedule OR jobs.timezone <> excluded.timezone)"
+	}
+	query := s.db.Rebind(`INSERT INTO jobs (kind, unique_key, payload, schedule, timezone, status, run_at, max_attempts, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (unique_key) DO UPDATE SET
+			kind = excluded.kind, payload = excluded.payload, schedule = excluded.schedule,
+			timezone = excluded.timezone, status = excluded.status, run_at = excluded.run_at,
+			max_attempts = excluded.max_attempts, attempts = 0, last_error = NULL, updated_at = excluded.updated_at
+		WHERE ` + replace)
+	_, err := s.db.ExecContext(ctx, query,
+		j.Kind, key, string(j.Payload), j.Schedule, j.TimeZone, StatusScheduled, j.RunAt.UTC(), j.MaxAttempts, now.UTC(), now.UTC())
+	if err != nil {
+		return fmt.Errorf("jobs: insert: %w", err)
+	}
+	return nil
+}
+
+// get returns the job with the given ID
+func (s store) get(ctx context.Context, id int64) (*Job, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind("SELECT "+jobColumns+" FROM jobs WHERE id = ?"), id)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: get: %w", err)
+	}
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("jobs: job %d not found", id)
+	}
+	return jobs[0], nil
+}
+
+// getByKey returns the job with the given unique key
+func (s store) getByKey(ctx context.Context, key string) (*Job, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind("SELECT "+jobColumns+" FROM jobs WHERE unique_key = ?"), key)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: get: %w", err)
+	}
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("jobs: job %q not found", key)
+	}
+	return jobs[0], nil
+}
+
+// lease claims up to limit due jobs for owner until the given time, counting
+// an attempt, and returns only the jobs claimed by this call. Jobs still
+// marked running whose lease expired are claimed again while they have
+// attempts left.
+func (s store) lease(ctx context.Context, owner string, now, until time.Time, limit int) ([]*Job, error) {
+	return s.claim(ctx, false, owner, now, until, limit)
+}
+
+// leaseExhausted claims up to limit jobs still marked running whose lease
+// expired on their last attempt, so they can be settled without running
+// again. Their handler never returned, e.g. because it crashed the process.
+func (s store) leaseExhausted(ctx context.Context, owner string, now, until time.Time, limit int) ([]*Job, error) {
+	return s.claim(ctx, true, owner, now, until, limit)
+}
+
+// claim is lease, or leaseExhausted when exhausted is set
+func (s store) claim(ctx context.Context, exhausted bool, owner string, now, until time.Time, limit int) ([]*Job, error) {
+	attempts, count := "attempts < max_attempts", "attempts = attempts + 1,"
+	if exhausted {
+		attempts, count = "attempts >= max_attempts", ""
+	}
+	due := `SELECT id FROM jobs
+		WHERE status IN ('scheduled', 'running') AND run_at <= ? AND (locked_until IS NULL OR locked_until < ?)
+			AND ` + attempts + `
+		ORDER BY run_at LIMIT ?`
+	if s.db.Dialect == database.Postgres {
+		// Replicas skip rows another replica is claiming instead of waiting
+		due += " FOR UPDATE SKIP LOCKED"
+	}
+	query := s.db.Rebind(`UPDATE jobs SET status = ?, locked_by = ?, locked_until = ?, ` + count + ` updated_at = ?
+		WHERE id IN (` + due + `) RETURNING ` + jobColumns)
+	rows, err := s.db.QueryContext(ctx, query,
+		StatusRunning, owner, until.UTC(), now.UTC(), now.UTC(), now.UTC(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("jobs: lease: %w", err)
+	}
+	jobs, err := scanJobs(rows)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].RunAt.Before(jobs[k].RunAt) })
+	return jobs, nil
+}
+
+// release records the outcome of a run if owner still holds the lease. It
+// reports false when the lease was lost to another replica.
+func (s store) release(ctx context.Context, j *Job, owner string, now time.Time) (bool, error) {
+	var lastError sql.NullString
+	if j.LastError != "" {
+		lastError = sql.NullString{String: j.LastError, Valid: true}
+	}
+	res, err := s.db.ExecContext(ctx, s.db.Rebind(`UPDATE jobs
+		SET status = ?, run_at = ?, attempts = ?, last_error = ?, locked_by = NULL, locked_until = NULL, updated_at = ?
+		WHERE id = ? AND locked_by = ?`),
+		j.Status, j.RunAt.UTC(), j.Attempts, lastError, now.UTC(), j.ID, owner)
+	if err != nil {
+		return false, fmt.Errorf("jobs: release: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("jobs: release: %w", err)
+	}
+	return n == 1, nil
+}
+
+// cancel deletes the job with the given key
+func (s store) cancel(ctx context.Context, key string) error {
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind("DELETE FROM jobs WHERE unique_key = ?"), key); err != nil {
+		return fmt.Errorf("jobs: cancel: %w", err)
+	}
+	return nil
+}
+
+// scanJobs reads rows selected with jobColumns and closes them
+func scanJobs(rows *sql.Rows) ([]*Job, error) {
+	defer rows.Close()
+	var jobs []*Job
+	for rows.Next() {
+		var j Job
+		var key, lastError sql.NullString
+		var payload string
+		if err := rows.Scan(&j.ID, &j.Kind, &key, &payload, &j.Schedule, &j.TimeZone, &j.Status,
+			&j.RunAt, &j.Attempts, &j.MaxAttempts, &lastError); err != nil {
+			return nil, fmt.Errorf("jobs: scan: %w", err)
+		}
+		j.Key, j.LastError = key.String, lastError.String
+		j.Payload = []byte(payload)
+		j.RunAt = j.RunAt.UTC()
+		jobs = append(jobs, &j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("jobs: scan: %w", err)
+	}
+	return jobs, nil
+}
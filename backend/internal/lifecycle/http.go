@@ -0,0 +1,33 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// HTTPServer wraps srv as a component. Start binds the listener before
+// returning, so port conflicts fail startup; Stop shuts the server down
+// gracefully. If serving fails later, m is told to shut down.
+func (m *Manager) HTTPServer(name string, srv *http.Server, dependsOn ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: dependsOn,
+		Start: func(ctx context.Context) error {
+			ln, err := (&net.ListenConfig{}).Listen(ctx, "tcp", srv.Addr)
+			if err != nil {
+				return err
+			}
+			m.logger.Info("listening", "component", name, "addr", ln.Addr().String())
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					m.Fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		Stop: srv.Shutdown,
+	}
+}
@@ -0,0 +1,276 @@
+// Package lifecycle starts application components in dependency order and
+// stops them in reverse order on shutdown.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Predefined errors
+var (
+	ErrNotReady     = errors.New("lifecycle: not started")
+	ErrShuttingDown = errors.New("lifecycle: shutting down")
+)
+
+// Component is a part of the application with a lifecycle. Start must return
+// once the component is running, leaving long-running work in goroutines;
+// Stop must release its resources before ctx expires. Either hook may be nil.
+type Component struct {
+	Name      string
+	DependsOn []string
+	Start     func(ctx context.Context) error
+	Stop      func(ctx context.Context) error
+}
+
+// state of the manager
+type state int
+
+const (
+	stateIdle state = iota
+	stateRunning
+	stateDraining
+	stateStopped
+)
+
+// Manager owns the registered components. It implements health.Checker:
+// it reports ready only between a successful Start and the beginning of Stop.
+type Manager struct {
+	mu         sync.Mutex
+	components []Component
+	started    []Component
+	state      state
+	drainDelay time.Duration
+	logger     *slog.Logger
+	failures   chan error
+	sleep      func(ctx context.Context, d time.Duration)
+}
+
+// New creates a manager. On Stop, readiness fails drainDelay before the first
+// component stops so load balancers stop routing traffic here.
+func New(logger *slog.Logger, drainDelay time.Duration) *Manager {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Manager{
+		drainDelay: drainDelay,
+		logger:     logger,
+		failures:   make(chan error, 1),
+		sleep:      sleepContext,
+	}
+}
+
+// Register adds a component; it must be called before Start
+func (m *Manager) Register(c Component) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.components = append(m.components, c)
+}
+
+// Start starts every component after the components it depends on. If one
+// fails, those already started are stopped again and the error returned.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	if m.state != stateIdle {
+		m.mu.Unlock()
+		return errors.New("lifecycle: already started")
+	}
+	order, err := sortComponents(m.components)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range order {
+		begin := time.Now()
+		if c.Start != nil {
+			if err := c.Start(ctx); err != nil {
+				err = fmt.Errorf("lifecycle: start %s: %w", c.Name, err)
+				m.logger.Error("component failed to start", "component", c.Name, "error", err)
+				if stopErr := m.stopStarted(context.WithoutCancel(ctx)); stopErr != nil {
+					err = errors.Join(err, stopErr)
+				}
+				return err
+			}
+		}
+		m.mu.Lock()
+		m.started = append(m.started, c)
+		m.mu.Unlock()
+		m.logger.Info("component started", "component", c.Name, "duration_ms", msSince(begin))
+	}
+
+	m.mu.Lock()
+	m.state = stateRunning
+	m.mu.Unlock()
+	return nil
+}
+
+// Stop marks the application not ready, waits for the drain delay and stops
+// the started components in reverse order. Every component is asked to stop
+// even after ctx expires; the errors are joined.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	if m.state == stateDraining || m.state == stateStopped {
+		m.mu.Unlock()
+		return nil
+	}
+	m.state = stateDraining
+	m.mu.Unlock()
+
+	if m.drainDelay > 0 {
+		m.logger.Info("draining before shutdown", "delay", m.drainDelay.String())
+		m.sleep(ctx, m.drainDelay)
+	}
+	return m.stopStarted(ctx)
+}
+
+// stopStarted stops started components in reverse start order
+func (m *Manager) stopStarted(ctx context.Context) error {
+	m.mu.Lock()
+	started := m.started
+	m.started = nil
+	m.state = stateDraining
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(started) - 1; i >= 0; i-- {
+		c := started[i]
+		if c.Stop == nil {
+			continue
+		}
+		begin := time.Now()
+		if err := c.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("lifecycle: stop %s: %w", c.Name, err))
+			m.logger.Error("component failed to stop", "component", c.Name, "error", err)
+			continue
+		}
+		m.logger.Info("component stopped", "component", c.Name, "duration_ms", msSince(begin))
+	}
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, fmt.Errorf("lifecycle: shutdown deadline: %w", err))
+	}
+
+	m.mu.Lock()
+	m.state = stateStopped
+	m.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// Fail reports that a running component broke, e.g. a server stopped
+// accepting connections. Run then shuts the application down.
+func (m *Manager) Fail(err error) {
+	select {
+	case m.failures <- err:
+	default:
+	}
+}
+
+// Run starts the components, waits until ctx is cancelled or a component
+// fails, then stops everything within shutdownTimeout
+func (m *Manager) Run(ctx context.Context, shutdownTimeout time.Duration) error {
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+
+	var cause error
+	select {
+	case <-ctx.Done():
+		m.logger.Info("shutdown requested")
+	case cause = <-m.failures:
+		m.logger.Error("component failed, shutting down", "error", cause)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	return errors.Join(cause, m.Stop(stopCtx))
+}
+
+// Check implements health.Checker
+func (m *Manager) Check(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	switch m.state {
+	case stateRunning:
+		return nil
+	case stateIdle:
+		return ErrNotReady
+	default:
+		return ErrShuttingDown
+	}
+}
+
+// sortComponents orders components so dependencies come first, keeping
+// registration order otherwise
+func sortComponents(components []Component) ([]Component, error) {
+	index := make(map[string]int, len(components))
+	for i, c := range components {
+		if c.Name == "" {
+			return nil, fmt.Errorf("lifecycle: component %d has no name", i)
+		}
+		if _, dup := index[c.Name]; dup {
+			return nil, fmt.Errorf("lifecycle: duplicate component %q", c.Name)
+		}
+		index[c.Name] = i
+	}
+	for _, c := range components {
+		for _, dep := range c.DependsOn {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("lifecycle: %s depends on unknown component %q", c.Name, dep)
+			}
+		}
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	marks := make([]int, len(components))
+	order := make([]Component, 0, len(components))
+	var path []string
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch marks[i] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("lifecycle: dependency cycle: %s -> %s", strings.Join(path, " -> "), components[i].Name)
+		}
+		marks[i] = visiting
+		path = append(path, components[i].Name)
+		for _, dep := range components[i].DependsOn {
+			if err := visit(index[dep]); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[i] = done
+		order = append(order, components[i])
+		return nil
+	}
+	for i := range components {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// sleepContext waits for d or until ctx is done
+func sleepContext(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+func msSince(t time.Time) float64 {
+	return float64(time.Since(t).Microseconds()) / 1000
+}
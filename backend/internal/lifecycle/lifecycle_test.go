@@ -0,0 +1,202 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder logs hook calls in order
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) add(call string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, call)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return strings.Join(r.calls, " ")
+}
+
+func (r *recorder) component(name string, deps ...string) Component {
+	return Component{
+		Name:      name,
+		DependsOn: deps,
+		Start:     func(context.Context) error { r.add("start:" + name); return nil },
+		Stop:      func(context.Context) error { r.add("stop:" + name); return nil },
+	}
+}
+
+func testManager() *Manager {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), 0)
+}
+
+func TestStartStopOrder(t *testing.T) {
+	rec := &recorder{}
+	m := testManager()
+	m.Register(rec.component("http", "database", "cache"))
+	m.Register(rec.component("database"))
+	m.Register(rec.component("cache", "database"))
+	m.Register(rec.component("metrics"))
+
+	if err := m.Check(context.Background()); !errors.Is(err, ErrNotReady) {
+		t.Errorf("Expected not ready before start, got %v", err)
+	}
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if err := m.Check(context.Background()); err != nil {
+		t.Errorf("Expected ready after start, got %v", err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop() failed: %v", err)
+	}
+	if err := m.Check(context.Background()); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("Expected not ready after stop, got %v", err)
+	}
+
+	want := "start:database start:cache start:http start:metrics stop:metrics stop:http stop:cache stop:database"
+	if got := rec.String(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestStartFailureStopsStarted(t *testing.T) {
+	rec := &recorder{}
+	m := testManager()
+	m.Register(rec.component("database"))
+	m.Register(Component{
+		Name:      "http",
+		DependsOn: []string{"database"},
+		Start:     func(context.Context) error { return errors.New("address in use") },
+	})
+	m.Register(rec.component("worker", "http"))
+
+	err := m.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "start http: address in use") {
+		t.Fatalf("Expected start error, got %v", err)
+	}
+	if got := rec.String(); got != "start:database stop:database" {
+		t.Errorf("Expected started components to be stopped, got %q", got)
+	}
+}
+
+func TestInvalidGraphs(t *testing.T) {
+	tests := []struct {
+		name       string
+		components []Component
+		wantErr    string
+	}{
+		{"unknown dependency", []Component{{Name: "a", DependsOn: []string{"b"}}}, "unknown component"},
+		{"cycle", []Component{{Name: "a", DependsOn: []string{"b"}}, {Name: "b", DependsOn: []string{"a"}}}, "cycle: a -> b -> a"},
+		{"duplicate", []Component{{Name: "a"}, {Name: "a"}}, "duplicate"},
+		{"unnamed", []Component{{}}, "no name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := testManager()
+			for _, c := range tt.components {
+				m.Register(c)
+			}
+			if err := m.Start(context.Background()); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestStopDrainsFirst(t *testing.T) {
+	m := testManager()
+	m.drainDelay = time.Minute
+	var readyDuringDrain error
+	m.sleep = func(ctx context.Context, d time.Duration) {
+		readyDuringDrain = m.Check(ctx)
+	}
+	stopped := false
+	m.Register(Component{Name: "http", Stop: func(context.Context) error {
+		stopped = true
+		return nil
+	}})
+
+	m.Start(context.Background())
+	m.Stop(context.Background())
+	if !errors.Is(readyDuringDrain, ErrShuttingDown) {
+		t.Errorf("Expected readiness to fail while draining, got %v", readyDuringDrain)
+	}
+	if !stopped {
+		t.Error("Expected component to stop after draining")
+	}
+}
+
+func TestStopDeadline(t *testing.T) {
+	m := testManager()
+	dbStopped := false
+	m.Register(Component{Name: "database", Stop: func(context.Context) error {
+		dbStopped = true
+		return nil
+	}})
+	m.Register(Component{Name: "slow", Stop: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	m.Start(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := m.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline error, got %v", err)
+	}
+	if !dbStopped {
+		t.Error("Expected remaining components to be stopped after the deadline")
+	}
+}
+
+func TestRunStopsOnFailure(t *testing.T) {
+	rec := &recorder{}
+	m := testManager()
+	m.Register(rec.component("database"))
+	m.Register(Component{Name: "server", DependsOn: []string{"database"}, Start: func(context.Context) error {
+		go m.Fail(errors.New("listener closed"))
+		return nil
+	}})
+
+	err := m.Run(context.Background(), time.Second)
+	if err == nil || !strings.Contains(err.Error(), "listener closed") {
+		t.Errorf("Expected the failure to be returned, got %v", err)
+	}
+	if got := rec.String(); got != "start:database stop:database" {
+		t.Errorf("Expected shutdown after failure, got %q", got)
+	}
+}
+
+func TestHTTPServer(t *testing.T) {
+	m := testManager()
+	srv := &http.Server{Addr: "127.0.0.1:0", Handler: http.NotFoundHandler()}
+	m.Register(m.HTTPServer("http", srv))
+	if err := m.Start(context.Background()); err != nil {
+		t.Fatalf("Start() failed: %v", err)
+	}
+	if err := m.Stop(context.Background()); err != nil {
+		t.Errorf("Stop() failed: %v", err)
+	}
+
+	taken := &http.Server{Addr: "127.0.0.1:-1"}
+	m = testManager()
+	m.Register(m.HTTPServer("http", taken))
+	if err := m.Start(context.Background()); err == nil {
+		t.Error("Expected an invalid address to fail startup")
+	}
+}
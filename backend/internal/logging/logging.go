@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+)
+
+// New builds a structured logger writing to w with the configured level and format
+func New(cfg config.LoggingConfig, w io.Writer) (*slog.Logger, error) {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch cfg.Format {
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case "text", "":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("logging: unknown format %q", cfg.Format)
+	}
+}
+
+// ParseLevel converts debug/info/warn/error into a slog.Level
+func ParseLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.ToUpper(s))); err != nil {
+		return 0, fmt.Errorf("logging: unknown level %q", s)
+	}
+	return level, nil
+}
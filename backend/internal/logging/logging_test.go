@@ -0,0 +1,38 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(config.LoggingConfig{Level: "warn", Format: "json"}, &buf)
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	logger.Info("dropped")
+	logger.Warn("kept", slog.String("key", "value"))
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected a single JSON record, got %q", buf.String())
+	}
+	if record["msg"] != "kept" || record["key"] != "value" {
+		t.Errorf("Unexpected record: %v", record)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	if _, err := New(config.LoggingConfig{Level: "verbose", Format: "json"}, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for unknown level, got none")
+	}
+	if _, err := New(config.LoggingConfig{Level: "info", Format: "xml"}, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for unknown format, got none")
+	}
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace prefixes every metric exported by the backend
+const Namespace = "backend"
+
+// Metrics owns the Prometheus registry and the HTTP server instruments
+type Metrics struct {
+	registry *prometheus.Registry
+
+	Requests        *prometheus.CounterVec
+	RequestDuration *prometheus.HistogramVec
+	ResponseSize    *prometheus.HistogramVec
+	InFlight        *prometheus.GaugeVec
+}
+
+// New creates a registry with Go runtime, process and HTTP metrics
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "HTTP requests processed, by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		RequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency, by method and route template.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route"}),
+		ResponseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "response_size_bytes",
+			Help:      "HTTP response body size, by method and route template.",
+			Buckets:   prometheus.ExponentialBuckets(64, 4, 8),
+		}, []string{"method", "route"}),
+		InFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: Namespace,
+			Subsystem: "http",
+			Name:      "requests_in_flight",
+			Help:      "HTTP requests currently being served, by method and route template.",
+		}, []string{"method", "route"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.Requests,
+		m.RequestDuration,
+		m.ResponseSize,
+		m.InFlight,
+	)
+	return m
+}
+
+// Registerer lets other packages add their own collectors
+func (m *Metrics) Registerer() prometheus.Registerer {
+	return m.registry
+}
+
+// Handler serves the registry in the Prometheus text exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/audit"
+)
+
+// Audit lets handlers record audit events to r through audit.Record with the
+// request context, which carries the client IP, user agent and request ID
+func Audit(r audit.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := audit.WithRecorder(c.Request.Context(), r)
+		ctx = audit.WithSource(ctx, audit.Source{
+			IP:        c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			RequestID: GetRequestID(c),
+		})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
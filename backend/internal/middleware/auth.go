@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+)
+
+// claimsKey stores the verified *auth.Claims in the Gin context
+const claimsKey = "auth_claims"
+
+// authRealm is advertised in WWW-Authenticate challenges
+const authRealm = "api"
+
+// Auth requires a valid bearer token and stores its claims in the Gin and
+// request contexts. Failures answer 401 with a WWW-Authenticate challenge.
+func Auth(tokens *auth.TokenService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := bearerToken(c)
+		if !ok {
+			unauthorized(c, "", "missing bearer token")
+			return
+		}
+
+		claims, err := tokens.Parse(token)
+		if err != nil {
+			description := "invalid token"
+			if errors.Is(err, auth.ErrTokenExpired) {
+				description = "token expired"
+			}
+			unauthorized(c, "invalid_token", description)
+			return
+		}
+
+		setClaims(c, claims)
+		c.Next()
+	}
+}
+
+// RequireRole allows the request when the authenticated user has any of roles
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			unauthorized(c, "", "authentication required")
+			return
+		}
+		for _, role := range roles {
+			if claims.HasRole(role) {
+				c.Next()
+				return
+			}
+		}
+		forbidden(c, "", fmt.Sprintf("requires role: %s", strings.Join(roles, " or ")))
+	}
+}
+
+// RequireScope allows the request when the token grants every scope listed
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := GetClaims(c)
+		if !ok {
+			unauthorized(c, "", "authentication required")
+			return
+		}
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				forbidden(c, strings.Join(scopes, " "), fmt.Sprintf("requires scope: %s", strings.Join(scopes, " ")))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// GetClaims returns the claims stored by Auth
+func GetClaims(c *gin.Context) (*auth.Claims, bool) {
+	value, ok := c.Get(claimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := value.(*auth.Claims)
+	return claims, ok
+}
+
+// setClaims exposes claims to handlers, the request logger and context-aware code
+func setClaims(c *gin.Context, claims *auth.Claims) {
+	c.Set(claimsKey, claims)
+	c.Set(userIDKey, claims.UserID)
+	c.Request = c.Request.WithContext(auth.WithClaims(c.Request.Context(), claims))
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// unauthorized aborts with 401 and an RFC 6750 challenge
+func unauthorized(c *gin.Context, code, description string) {
+	challenge := fmt.Sprintf(`Bearer realm=%q`, authRealm)
+	if code != "" {
+		challenge += fmt.Sprintf(`, error=%q, error_description=%q`, code, description)
+	}
+	c.Header("WWW-Authenticate", challenge)
+	errCode := apperr.CodeUnauthorized
+	if code == "invalid_token" {
+		errCode = apperr.CodeInvalidToken
+	}
+	apperr.Abort(c, apperr.New(errCode, description))
+}
+
+// forbidden aborts with 403; scope is advertised when a scope was missing
+func forbidden(c *gin.Context, scope, description string) {
+	challenge := fmt.Sprintf(`Bearer realm=%q, error="insufficient_scope"`, authRealm)
+	if scope != "" {
+		challenge += fmt.Sprintf(`, scope=%q`, scope)
+	}
+	c.Header("WWW-Authenticate", challenge)
+	apperr.Abort(c, apperr.New(apperr.CodeForbidden, description))
+}
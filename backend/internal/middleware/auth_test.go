@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+)
+
+func authRouter(t *testing.T) (*gin.Engine, *auth.TokenService) {
+	t.Helper()
+	tokens, err := auth.NewTokenService(config.AuthConfig{JWTSecret: "secret", AccessTokenTTL: time.Minute})
+	if err != nil {
+		t.Fatalf("NewTokenService() failed: %v", err)
+	}
+
+	router := gin.New()
+	api := router.Group("/api", Auth(tokens))
+	api.GET("/me", func(c *gin.Context) {
+		claims, _ := auth.ClaimsFromContext(c.Request.Context())
+		c.JSON(http.StatusOK, gin.H{"user_id": claims.UserID})
+	})
+	api.GET("/admin", RequireRole(auth.RoleAdmin), func(c *gin.Context) { c.Status(http.StatusOK) })
+	api.GET("/habits", RequireScope("habits:read"), func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router, tokens
+}
+
+func TestAuth(t *testing.T) {
+	router, tokens := authRouter(t)
+	userToken, _, _ := tokens.Issue(auth.Claims{UserID: 1, Roles: []string{auth.RoleUser}})
+	adminToken, _, _ := tokens.Issue(auth.Claims{UserID: 2, Roles: []string{auth.RoleAdmin}, Scope: "habits:read"})
+
+	tests := []struct {
+		name         string
+		path         string
+		header       string
+		status       int
+		authenticate string
+	}{
+		{name: "missing token", path: "/api/me", status: http.StatusUnauthorized, authenticate: `Bearer realm="api"`},
+		{name: "wrong scheme", path: "/api/me", header: "Basic abc", status: http.StatusUnauthorized, authenticate: `Bearer realm="api"`},
+		{name: "invalid token", path: "/api/me", header: "Bearer nope", status: http.StatusUnauthorized, authenticate: `error="invalid_token"`},
+		{name: "valid token", path: "/api/me", header: "Bearer " + userToken, status: http.StatusOK},
+		{name: "missing role", path: "/api/admin", header: "Bearer " + userToken, status: http.StatusForbidden, authenticate: `error="insufficient_scope"`},
+		{name: "has role", path: "/api/admin", header: "Bearer " + adminToken, status: http.StatusOK},
+		{name: "missing scope", path: "/api/habits", header: "Bearer " + userToken, status: http.StatusForbidden, authenticate: `scope="habits:read"`},
+		{name: "has scope", path: "/api/habits", header: "bearer " + adminToken, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d (%s)", tt.status, w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("WWW-Authenticate"); !strings.Contains(got, tt.authenticate) || (tt.authenticate == "") != (got == "") {
+				t.Errorf("Expected WWW-Authenticate containing %q, got %q", tt.authenticate, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// Content codings offered by Compress, in order of preference
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// brotliLevel trades ratio for speed on dynamic responses
+const brotliLevel = 4
+
+var (
+	gzipWriters   = sync.Pool{New: func() any { return gzip.NewWriter(io.Discard) }}
+	brotliWriters = sync.Pool{New: func() any { return brotli.NewWriterLevel(io.Discard, brotliLevel) }}
+)
+
+// Compress encodes responses with brotli or gzip, as negotiated through
+// Accept-Encoding, once the body reaches minSize bytes. Only textual content
+// types are compressed and responses a handler already encoded are left
+// alone. Strong ETags of compressed responses get a "-br" or "-gzip" suffix
+// so they differ from the identity representation; the suffix is stripped
+// from If-None-Match before inner handlers see it.
+func Compress(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		suffix := "-" + encoding
+		inm := c.GetHeader("If-None-Match")
+		if inm != "" {
+			c.Request.Header.Set("If-None-Match", stripETagSuffix(inm, suffix))
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, encoding: encoding, suffix: suffix, minSize: minSize, ifNoneMatch: inm}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks the preferred supported coding with the highest
+// quality in an Accept-Encoding header, or "" for identity
+func negotiateEncoding(header string) string {
+	quality := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if name == "*" {
+			wildcard = q
+		} else if name != "" {
+			quality[name] = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{encodingBrotli, encodingGzip} {
+		q, ok := quality[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// stripETagSuffix removes suffix from the strong tags of an If-None-Match list
+func stripETagSuffix(list, suffix string) string {
+	tags := strings.Split(list, ",")
+	for i, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if !strings.HasPrefix(tag, "W/") {
+			tag = strings.Replace(tag, suffix+`"`, `"`, 1)
+		}
+		tags[i] = tag
+	}
+	return strings.Join(tags, ", ")
+}
+
+// compressible reports whether a content type benefits from compression
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// compressWriter buffers the start of a body until minSize bytes decide
+// whether it is worth encoding
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding    string
+	suffix      string
+	minSize     int
+	ifNoneMatch string
+	buf         bytes.Buffer
+	decided     bool
+	encoder     io.WriteCloser
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(false)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf.Write(b)
+		if w.buf.Len() < w.minSize {
+			return len(b), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(w.buf.Len() > 0)
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide sets the response encoding and writes out the buffered body.
+// Compression applies only when wanted and the response allows it.
+func (w *compressWriter) decide(wanted bool) error {
+	w.decided = true
+	h := w.Header()
+	status := w.Status()
+	if wanted && h.Get("Content-Encoding") == "" && compressible(h.Get("Content-Type")) &&
+		status != http.StatusNoContent && status != http.StatusNotModified && status >= http.StatusOK {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		w.tagETag()
+		w.encoder = w.newEncoder()
+	} else if status == http.StatusNotModified && w.ifNoneMatch != "" {
+		// The client holds the encoded representation it asked about
+		if etag := h.Get("ETag"); etag != "" && etagListContains(w.ifNoneMatch, w.encodedETag(etag)) {
+			w.tagETag()
+		}
+	}
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// tagETag marks a strong ETag as belonging to the encoded representation
+func (w *compressWriter) tagETag() {
+	h := w.Header()
+	if etag := h.Get("ETag"); etag != "" {
+		h.Set("ETag", w.encodedETag(etag))
+	}
+}
+
+// encodedETag returns the tag of the encoded representation of etag
+func (w *compressWriter) encodedETag(etag string) string {
+	if strings.HasPrefix(etag, "W/") || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + w.suffix + `"`
+}
+
+func (w *compressWriter) newEncoder() io.WriteCloser {
+	if w.encoding == encodingBrotli {
+		bw := brotliWriters.Get().(*brotli.Writer)
+		bw.Reset(w.ResponseWriter)
+		return bw
+	}
+	gw := gzipWriters.Get().(*gzip.Writer)
+	gw.Reset(w.ResponseWriter)
+	return gw
+}
+
+// close finishes the body, writing out a buffer that stayed below minSize
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.encoder == nil {
+		return
+	}
+	w.encoder.Close()
+	switch e := w.encoder.(type) {
+	case *brotli.Writer:
+		e.Reset(io.Discard)
+		brotliWriters.Put(e)
+	case *gzip.Writer:
+		e.Reset(io.Discard)
+		gzipWriters.Put(e)
+	}
+}
+
+// etagListContains reports whether an If-None-Match list holds etag exactly
+func etagListContains(list, etag string) bool {
+	for _, tag := range strings.Split(list, ",") {
+		if strings.TrimSpace(tag) == etag {
+			return true
+		}
+	}
+	return false
+}
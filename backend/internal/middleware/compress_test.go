@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0.1, gzip;q=0.5", "gzip"},
+		{"GZIP", "gzip"},
+		{"gzip;q=bad", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q): expected %q, got %q", tt.header, tt.want, got)
+		}
+	}
+}
+
+func compressRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(Compress(100), Conditional())
+	router.GET("/large", func(c *gin.Context) {
+		c.String(http.StatusOK, strings.Repeat("compress me ", 50))
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.String(http.StatusOK, "tiny")
+	})
+	router.GET("/binary", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", make([]byte, 500))
+	})
+	router.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "text/plain", make([]byte, 500))
+	})
+	return router
+}
+
+func TestCompress(t *testing.T) {
+	router := compressRouter()
+	large := strings.Repeat("compress me ", 50)
+
+	tests := []struct {
+		name         string
+		path         string
+		accept       string
+		wantEncoding string
+	}{
+		{"brotli", "/large", "gzip, br", "br"},
+		{"gzip", "/large", "gzip", "gzip"},
+		{"identity", "/large", "", ""},
+		{"below threshold", "/small", "br", ""},
+		{"binary", "/binary", "br", ""},
+		{"already encoded", "/encoded", "br", "gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", tt.accept)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected 200, got %d", w.Code)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Expected encoding %q, got %q", tt.wantEncoding, got)
+			}
+			if vary := w.Header().Values("Vary"); len(vary) == 0 || vary[0] != "Accept-Encoding" {
+				t.Errorf("Expected Vary: Accept-Encoding, got %v", vary)
+			}
+			if tt.path != "/large" {
+				return
+			}
+
+			var body io.Reader = w.Body
+			switch tt.wantEncoding {
+			case "br":
+				body = brotli.NewReader(w.Body)
+			case "gzip":
+				gr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("Invalid gzip body: %v", err)
+				}
+				body = gr
+			}
+			decoded, err := io.ReadAll(body)
+			if err != nil || string(decoded) != large {
+				t.Errorf("Expected the original body back, got %d bytes, %v", len(decoded), err)
+			}
+		})
+	}
+}
+
+func TestCompressETags(t *testing.T) {
+	router := compressRouter()
+	request := func(accept, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/large", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	identity := request("", "").Header().Get("ETag")
+	encoded := request("br", "").Header().Get("ETag")
+	if encoded != strings.TrimSuffix(identity, `"`)+`-br"` {
+		t.Fatalf("Expected the brotli tag to extend %s, got %s", identity, encoded)
+	}
+
+	w := request("br", encoded)
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != encoded || w.Body.Len() > 0 {
+		t.Errorf("Expected 304 for the encoded tag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+	if w := request("br", identity); w.Code != http.StatusNotModified || w.Header().Get("ETag") != identity {
+		t.Errorf("Expected 304 for the identity tag, got %d %q", w.Code, w.Header().Get("ETag"))
+	}
+	if w := request("", encoded); w.Code != http.StatusOK {
+		t.Errorf("Expected the brotli tag not to match an identity response, got %d", w.Code)
+	}
+}
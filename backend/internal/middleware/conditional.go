@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Conditional answers conditional GET requests. Successful responses get a
+// strong ETag derived from the body unless the handler set one, and are
+// replaced by 304 Not Modified when If-None-Match matches it or, without
+// If-None-Match, when If-Modified-Since is not older than a Last-Modified
+// header set by the handler. The body is buffered to compute the tag, so
+// handlers that flush are passed through untouched.
+func Conditional() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		w := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		// Restored before Recovery answers a panic, discarding the buffer
+		defer func() { c.Writer = w.ResponseWriter }()
+		c.Next()
+
+		if w.passthrough {
+			return
+		}
+		if w.status != http.StatusOK {
+			w.flush()
+			return
+		}
+
+		h := w.Header()
+		if h.Get("ETag") == "" {
+			h.Set("ETag", strongETag(w.body.Bytes()))
+		}
+		if h.Get("Cache-Control") == "" {
+			// Responses are per user; clients may keep them but must revalidate
+			h.Set("Cache-Control", "private, no-cache")
+		}
+		if notModified(c.Request, h) {
+			for _, name := range []string{"Content-Type", "Content-Length"} {
+				h.Del(name)
+			}
+			w.ResponseWriter.WriteHeader(http.StatusNotModified)
+			w.ResponseWriter.WriteHeaderNow()
+			return
+		}
+		w.flush()
+	}
+}
+
+// strongETag returns a quoted tag identifying body
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified evaluates the request preconditions against the response
+// headers as RFC 9110 section 13.2.2 orders them for GET
+func notModified(r *http.Request, h http.Header) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, h.Get("ETag"))
+	}
+	ims, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	lastModified, err := http.ParseTime(h.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(ims)
+}
+
+// etagMatches reports whether the If-None-Match list matches etag, using the
+// weak comparison that If-None-Match calls for
+func etagMatches(list, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLastModified sets the Last-Modified header at the second precision HTTP
+// dates have, so that Conditional can answer If-Modified-Since
+func SetLastModified(c *gin.Context, t time.Time) {
+	if !t.IsZero() {
+		c.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+	}
+}
+
+// bufferedWriter holds back the status and body of a response until the
+// handler chain returns. Flushing switches it to pass through.
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status      int
+	written     bool
+	body        bytes.Buffer
+	passthrough bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+		return
+	}
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *bufferedWriter) Status() int {
+	if w.passthrough {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	if w.passthrough {
+		return w.ResponseWriter.Written()
+	}
+	return w.written
+}
+
+func (w *bufferedWriter) Flush() {
+	if !w.passthrough {
+		w.flush()
+		w.passthrough = true
+	}
+	w.ResponseWriter.Flush()
+}
+
+// flush writes the held back response
+func (w *bufferedWriter) flush() {
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+		w.body.Reset()
+	} else if w.written {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
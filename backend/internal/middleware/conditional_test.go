@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+)
+
+func conditionalRouter() *gin.Engine {
+	router := gin.New()
+	router.Use(Conditional(), Errors(true))
+	router.GET("/items", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"items": []int{1, 2, 3}})
+	})
+	router.GET("/tagged", func(c *gin.Context) {
+		c.Header("ETag", `"v7"`)
+		SetLastModified(c, time.Date(2025, 7, 1, 12, 0, 0, 500, time.UTC))
+		c.String(http.StatusOK, "tagged")
+	})
+	router.GET("/missing", func(c *gin.Context) {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, ""))
+	})
+	router.POST("/items", func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+	return router
+}
+
+func TestConditional(t *testing.T) {
+	router := conditionalRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" || etag[0] != '"' {
+		t.Fatalf("Expected 200 with a strong ETag, got %d %q", w.Code, etag)
+	}
+	if w.Body.String() != `{"items":[1,2,3]}` {
+		t.Errorf("Expected the body to pass through, got %q", w.Body.String())
+	}
+	if cc := w.Header().Get("Cache-Control"); cc != "private, no-cache" {
+		t.Errorf("Expected revalidation to be required, got %q", cc)
+	}
+
+	lastModified := "Tue, 01 Jul 2025 12:00:00 GMT"
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		header     map[string]string
+		wantStatus int
+		wantETag   string
+	}{
+		{"matching tag", http.MethodGet, "/items", map[string]string{"If-None-Match": etag}, http.StatusNotModified, etag},
+		{"tag in list", http.MethodGet, "/items", map[string]string{"If-None-Match": `"old", ` + etag}, http.StatusNotModified, etag},
+		{"weak match", http.MethodGet, "/items", map[string]string{"If-None-Match": "W/" + etag}, http.StatusNotModified, etag},
+		{"any tag", http.MethodGet, "/items", map[string]string{"If-None-Match": "*"}, http.StatusNotModified, etag},
+		{"stale tag", http.MethodGet, "/items", map[string]string{"If-None-Match": `"old"`}, http.StatusOK, etag},
+		{"handler tag", http.MethodGet, "/tagged", map[string]string{"If-None-Match": `"v7"`}, http.StatusNotModified, `"v7"`},
+		{"not modified since", http.MethodGet, "/tagged", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified, `"v7"`},
+		{"modified since", http.MethodGet, "/tagged", map[string]string{"If-Modified-Since": "Tue, 01 Jul 2025 11:59:59 GMT"}, http.StatusOK, `"v7"`},
+		{"tag wins over date", http.MethodGet, "/tagged", map[string]string{"If-None-Match": `"v6"`, "If-Modified-Since": lastModified}, http.StatusOK, `"v7"`},
+		{"no date without last modified", http.MethodGet, "/items", map[string]string{"If-Modified-Since": lastModified}, http.StatusOK, etag},
+		{"errors untouched", http.MethodGet, "/missing", map[string]string{"If-None-Match": "*"}, http.StatusNotFound, ""},
+		{"writes untouched", http.MethodPost, "/items", map[string]string{"If-None-Match": "*"}, http.StatusCreated, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected %d, got %d", tt.wantStatus, w.Code)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("Expected ETag %q, got %q", tt.wantETag, got)
+			}
+			if tt.wantStatus == http.StatusNotModified && (w.Body.Len() > 0 || w.Header().Get("Content-Type") != "") {
+				t.Errorf("Expected an empty 304, got %q %v", w.Body.String(), w.Header())
+			}
+		})
+	}
+}
+
+func TestConditionalPanicReachesRecovery(t *testing.T) {
+	router := gin.New()
+	router.Use(gin.Recovery(), Conditional())
+	router.GET("/panic", func(c *gin.Context) {
+		c.String(http.StatusOK, "partial")
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected 500 after a panic, got %d", w.Code)
+	}
+}
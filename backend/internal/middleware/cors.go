@@ -1,22 +1,181 @@
 package middleware
 
 import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
 )
 
-// CORS middleware to handle Cross-Origin Resource Sharing
-func CORS() gin.HandlerFunc {
-	return gin.HandlerFunc(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+// corsPolicy is a config.CORSPolicy compiled for request matching
+type corsPolicy struct {
+	pathPrefix     string
+	anyOrigin      bool
+	origins        map[string]bool
+	wildcards      []wildcardOrigin
+	credentials    bool
+	allowedMethods string
+	allowedHeaders string
+	exposedHeaders string
+	maxAge         string
+}
+
+// wildcardOrigin matches https://*.example.com style origins
+type wildcardOrigin struct {
+	scheme string
+	suffix string
+	port   string
+}
+
+// CORS middleware to handle Cross-Origin Resource Sharing.
+// The matched origin is echoed back with Vary: Origin so credentialed requests
+// work; route overrides are chosen by the longest matching path prefix.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	policies := make([]corsPolicy, 0, len(cfg.Routes)+1)
+	for _, route := range cfg.Routes {
+		policies = append(policies, compileCORSPolicy(route.Path, route.Merge(cfg.CORSPolicy)))
+	}
+	sort.SliceStable(policies, func(i, j int) bool {
+		return len(policies[i].pathPrefix) > len(policies[j].pathPrefix)
+	})
+	policies = append(policies, compileCORSPolicy("/", cfg.CORSPolicy))
+
+	return func(c *gin.Context) {
+		policy := policies[len(policies)-1]
+		for _, p := range policies {
+			if hasPathPrefix(c.Request.URL.Path, p.pathPrefix) {
+				policy = p
+				break
+			}
+		}
+
+		// Responses without an Origin differ too, so caches must not hand
+		// them to cross-origin callers
+		h := c.Writer.Header()
+		if !policy.anyOrigin || policy.credentials {
+			h.Add("Vary", "Origin")
+		}
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		if !policy.allows(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
 			return
 		}
 
-		c.Next()
-	})
+		if policy.anyOrigin && !policy.credentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if policy.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if policy.exposedHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", policy.exposedHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		h.Set("Access-Control-Allow-Methods", policy.allowedMethods)
+		if policy.allowedHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", policy.allowedHeaders)
+		} else if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+			h.Set("Access-Control-Allow-Headers", requested)
+		}
+		if policy.maxAge != "" {
+			h.Set("Access-Control-Max-Age", policy.maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// compileCORSPolicy prepares a policy for fast origin matching
+func compileCORSPolicy(pathPrefix string, p config.CORSPolicy) corsPolicy {
+	compiled := corsPolicy{
+		pathPrefix:     pathPrefix,
+		origins:        make(map[string]bool),
+		credentials:    p.Credentials(),
+		allowedMethods: strings.Join(p.AllowedMethods, ", "),
+		allowedHeaders: strings.Join(p.AllowedHeaders, ", "),
+		exposedHeaders: strings.Join(p.ExposedHeaders, ", "),
+	}
+	if p.MaxAge > 0 {
+		compiled.maxAge = strconv.Itoa(int(p.MaxAge.Seconds()))
+	}
+
+	for _, origin := range p.Origins {
+		origin = strings.TrimSuffix(strings.ToLower(origin), "/")
+		if origin == "*" {
+			compiled.anyOrigin = true
+			continue
+		}
+		u, err := url.Parse(origin)
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(u.Hostname(), "*.") {
+			compiled.wildcards = append(compiled.wildcards, wildcardOrigin{
+				scheme: u.Scheme,
+				suffix: strings.TrimPrefix(u.Hostname(), "*"),
+				port:   u.Port(),
+			})
+			continue
+		}
+		compiled.origins[origin] = true
+	}
+	return compiled
+}
+
+// allows reports whether origin matches the policy
+func (p corsPolicy) allows(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	if len(p.wildcards) == 0 {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	for _, w := range p.wildcards {
+		if u.Scheme == w.scheme && u.Port() == w.port &&
+			strings.HasSuffix(host, w.suffix) && len(host) > len(w.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPathPrefix reports whether path is prefix or lies below it
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
 }
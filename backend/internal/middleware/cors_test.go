@@ -0,0 +1,195 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func corsRouter(cfg config.CORSConfig) *gin.Engine {
+	router := gin.New()
+	router.Use(CORS(cfg))
+	router.GET("/api/v1/items", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/api/v1/public/feed", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func testCORSConfig() config.CORSConfig {
+	yes, no := true, false
+	return config.CORSConfig{
+		CORSPolicy: config.CORSPolicy{
+			Origins:          []string{"http://localhost:3000", "https://*.github.io"},
+			AllowedMethods:   []string{"GET", "POST"},
+			AllowedHeaders:   []string{"Authorization", "Content-Type"},
+			ExposedHeaders:   []string{"X-Request-ID"},
+			AllowCredentials: &yes,
+			MaxAge:           10 * time.Minute,
+		},
+		Routes: []config.CORSRoute{
+			{
+				Path: "/api/v1/public",
+				CORSPolicy: config.CORSPolicy{
+					Origins:          []string{"*"},
+					AllowCredentials: &no,
+				},
+			},
+		},
+	}
+}
+
+func TestCORS(t *testing.T) {
+	router := corsRouter(testCORSConfig())
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		origin      string
+		preflight   bool
+		status      int
+		allowOrigin string
+		credentials string
+	}{
+		{
+			name:        "exact origin",
+			method:      http.MethodGet,
+			path:        "/api/v1/items",
+			origin:      "http://localhost:3000",
+			status:      http.StatusOK,
+			allowOrigin: "http://localhost:3000",
+			credentials: "true",
+		},
+		{
+			name:        "wildcard subdomain",
+			method:      http.MethodGet,
+			path:        "/api/v1/items",
+			origin:      "https://team7.github.io",
+			status:      http.StatusOK,
+			allowOrigin: "https://team7.github.io",
+			credentials: "true",
+		},
+		{
+			name:   "wildcard does not match apex or other scheme",
+			method: http.MethodGet,
+			path:   "/api/v1/items",
+			origin: "http://team7.github.io",
+			status: http.StatusOK,
+		},
+		{
+			name:   "unknown origin",
+			method: http.MethodGet,
+			path:   "/api/v1/items",
+			origin: "https://evil.example.com",
+			status: http.StatusOK,
+		},
+		{
+			name:        "preflight allowed",
+			method:      http.MethodOptions,
+			path:        "/api/v1/items",
+			origin:      "http://localhost:3000",
+			preflight:   true,
+			status:      http.StatusNoContent,
+			allowOrigin: "http://localhost:3000",
+			credentials: "true",
+		},
+		{
+			name:      "preflight rejected",
+			method:    http.MethodOptions,
+			path:      "/api/v1/items",
+			origin:    "https://evil.example.com",
+			preflight: true,
+			status:    http.StatusForbidden,
+		},
+		{
+			name:        "route override allows any origin without credentials",
+			method:      http.MethodGet,
+			path:        "/api/v1/public/feed",
+			origin:      "https://evil.example.com",
+			status:      http.StatusOK,
+			allowOrigin: "*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("Origin", tt.origin)
+			if tt.preflight {
+				req.Header.Set("Access-Control-Request-Method", "POST")
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Expected Allow-Origin %q, got %q", tt.allowOrigin, got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+				t.Errorf("Expected Allow-Credentials %q, got %q", tt.credentials, got)
+			}
+		})
+	}
+}
+
+func TestCORSHeaders(t *testing.T) {
+	router := corsRouter(testCORSConfig())
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/items", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Expected Max-Age 600, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+		t.Errorf("Expected Allow-Methods 'GET, POST', got %q", got)
+	}
+	if got := w.Header().Values("Vary"); len(got) == 0 || got[0] != "Origin" {
+		t.Errorf("Expected Vary: Origin, got %v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/items", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+		t.Errorf("Expected Expose-Headers 'X-Request-ID', got %q", got)
+	}
+}
+
+func TestCORSWithoutOrigin(t *testing.T) {
+	router := corsRouter(testCORSConfig())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/items", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no CORS headers for same-origin request, got %q", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Expected Vary: Origin so caches keep it apart from cross-origin responses, got %q", got)
+	}
+
+	// Responses for any origin without credentials do not depend on it
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/public/feed", nil))
+	if got := w.Header().Get("Vary"); got != "" {
+		t.Errorf("Expected no Vary for a public route, got %q", got)
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+)
+
+// Errors renders the last error attached with c.Error() as an RFC 7807
+// problem, unless the handler already wrote a response. Binding errors become
+// 400/422 problems; other uncatalogued errors are 500s whose text is only
+// shown when expose is set, i.e. outside production. Bind with
+// c.ShouldBind*, since c.Bind* writes a bare 400 before this runs.
+func Errors(expose bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		last := c.Errors.Last()
+		err := last.Err
+		if _, ok := apperr.As(err); !ok && last.IsType(gin.ErrorTypeBind) {
+			err = apperr.Binding(err)
+		}
+
+		problem := apperr.NewProblem(err, expose)
+		problem.Instance = c.Request.URL.Path
+		problem.RequestID = GetRequestID(c)
+		c.Header("Content-Type", apperr.ContentType)
+		c.JSON(problem.Status, problem)
+	}
+}
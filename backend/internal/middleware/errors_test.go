@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+)
+
+func TestErrors(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID(), Errors(false))
+	router.GET("/missing", func(c *gin.Context) {
+		apperr.Abort(c, apperr.New(apperr.CodeNotFound, "habit not found"))
+	})
+	router.GET("/boom", func(c *gin.Context) {
+		c.Error(errors.New("pq: relation does not exist"))
+	})
+	router.POST("/bind", func(c *gin.Context) {
+		var body struct {
+			Email string `json:"email" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.Error(err).SetType(gin.ErrorTypeBind)
+		}
+	})
+	router.GET("/written", func(c *gin.Context) {
+		c.Error(errors.New("logged only"))
+		c.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		method     string
+		path       string
+		wantStatus int
+		wantCode   apperr.Code
+		wantDetail string
+	}{
+		{http.MethodGet, "/missing", http.StatusNotFound, apperr.CodeNotFound, "habit not found"},
+		{http.MethodGet, "/boom", http.StatusInternalServerError, apperr.CodeInternal, "internal server error"},
+		{http.MethodPost, "/bind", http.StatusUnprocessableEntity, apperr.CodeValidationFailed, "the request failed validation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(RequestIDHeader, "req-123")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d", tt.wantStatus, w.Code)
+			}
+			if ct := w.Header().Get("Content-Type"); ct != apperr.ContentType {
+				t.Errorf("Expected %s, got %q", apperr.ContentType, ct)
+			}
+			var p apperr.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+				t.Fatalf("Invalid problem body %q: %v", w.Body.String(), err)
+			}
+			if p.Code != tt.wantCode || p.Detail != tt.wantDetail || p.Status != tt.wantStatus {
+				t.Errorf("Unexpected problem: %+v", p)
+			}
+			if p.RequestID != "req-123" || p.Instance != tt.path {
+				t.Errorf("Expected request ID and instance, got %q %q", p.RequestID, p.Instance)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/written", nil))
+	if w.Body.String() != "ok" {
+		t.Errorf("Expected written responses to be left alone, got %q", w.Body.String())
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+)
+
+// FlagChecker reports whether a feature flag is on for a user
+type FlagChecker interface {
+	Enabled(name string, userID int64) bool
+}
+
+// RequireFlag lets the request through only when the flag is on for the
+// authenticated user, or for anyone on routes without Auth. Otherwise it
+// answers 404 so an unreleased feature looks like a route that does not exist.
+func RequireFlag(flags FlagChecker, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var userID int64
+		if claims, ok := GetClaims(c); ok {
+			userID = claims.UserID
+		}
+		if !flags.Enabled(name, userID) {
+			apperr.Abort(c, apperr.New(apperr.CodeNotFound, ""))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/features"
+)
+
+func TestRequireFlag(t *testing.T) {
+	router, tokens := authRouter(t)
+	flags := features.New(config.FeaturesConfig{Flags: map[string]config.FlagConfig{
+		"beta":   {Enabled: true, Users: []int64{1}},
+		"launch": {Enabled: true},
+	}}, nil, nil)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/api/beta", Auth(tokens), RequireFlag(flags, "beta"), ok)
+	router.GET("/public/launch", RequireFlag(flags, "launch"), ok)
+	router.GET("/public/beta", RequireFlag(flags, "beta"), ok)
+	tester, _, _ := tokens.Issue(auth.Claims{UserID: 1})
+	other, _, _ := tokens.Issue(auth.Claims{UserID: 2})
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		{"listed user", "/api/beta", tester, http.StatusOK},
+		{"unlisted user", "/api/beta", other, http.StatusNotFound},
+		{"anonymous with boolean flag", "/public/launch", "", http.StatusOK},
+		{"anonymous with allow list", "/public/beta", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, w.Code)
+			}
+		})
+	}
+}
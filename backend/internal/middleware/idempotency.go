@@ -0,0 +1,198 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/idempotency"
+)
+
+// Idempotency headers
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+)
+
+// Limits of idempotent requests
+const (
+	maxIdempotencyKeyLength   = 255
+	maxIdempotentResponseSize = 1 << 20
+)
+
+// Idempotency makes POST and PATCH requests carrying an Idempotency-Key safe
+// to retry. The first response for a key is stored for cfg.TTL and replayed,
+// marked Idempotent-Replayed: true, to retries with the same payload; reusing
+// a key for a different request is rejected with 422, and a retry arriving
+// while the first request runs gets 409. Keys are scoped to the user, so this
+// must run after Auth; anonymous requests pass through. Server errors and
+// errors left for Errors to render are not stored, so the request may be
+// retried with the same key. Store errors let the request through.
+func Idempotency(store idempotency.Store, cfg config.IdempotencyConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(IdempotencyKeyHeader)
+		claims, ok := GetClaims(c)
+		if header == "" || !ok || (c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPatch) {
+			c.Next()
+			return
+		}
+		if len(header) > maxIdempotencyKeyLength {
+			apperr.Abort(c, apperr.Newf(apperr.CodeInvalidRequest, "%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			apperr.Abort(c, apperr.Wrap(err, apperr.CodeInvalidRequest, "the request body could not be read"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		logger := LoggerFromContext(ctx)
+		key := fmt.Sprintf("user:%d:%s", claims.UserID, header)
+		fingerprint := idempotency.Fingerprint(c.Request.Method, c.Request.URL.RequestURI(), body)
+
+		record, token, err := store.Lock(ctx, key, fingerprint, cfg.LockTimeout)
+		if err != nil {
+			logger.Error("idempotency store failed", "error", err)
+			c.Next()
+			return
+		}
+		if record != nil {
+			switch {
+			case record.Fingerprint != fingerprint:
+				apperr.Abort(c, apperr.New(apperr.CodeIdempotencyKeyReused, ""))
+			case record.Response == nil:
+				c.Header("Retry-After", "1")
+				apperr.Abort(c, apperr.New(apperr.CodeIdempotencyKeyInUse, ""))
+			default:
+				replay(c, record.Response)
+			}
+			return
+		}
+
+		// The outcome is recorded even if the client has gone, so the key is
+		// neither left locked nor run again by a retry
+		storeCtx := context.WithoutCancel(ctx)
+		w := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		saved := false
+		defer func() {
+			// Also runs when the handler panics
+			if !saved {
+				if err := store.Release(storeCtx, key, token); err != nil {
+					logger.Error("idempotency store failed", "error", err)
+				}
+			}
+		}()
+
+		c.Next()
+
+		if (len(c.Errors) > 0 && !w.Written()) || w.Status() >= http.StatusInternalServerError || w.overflow {
+			return
+		}
+		resp := &idempotency.Response{
+			Status: w.Status(),
+			Header: replayableHeader(w.Header()),
+			Body:   w.body.Bytes(),
+		}
+		if err := store.Save(storeCtx, key, token, resp, cfg.TTL); err != nil {
+			logger.Error("idempotency store failed", "error", err)
+			return
+		}
+		saved = true
+	}
+}
+
+// replay writes a stored response and stops the chain
+func replay(c *gin.Context, resp *idempotency.Response) {
+	h := c.Writer.Header()
+	for name, values := range resp.Header {
+		if name == "Vary" {
+			// Keep what middleware such as Compress set for this request
+			for _, value := range values {
+				h.Add(name, value)
+			}
+			continue
+		}
+		h[name] = values
+	}
+	h.Set(IdempotentReplayedHeader, "true")
+	c.Status(resp.Status)
+	c.Writer.Write(resp.Body)
+	c.Abort()
+}
+
+// replayableHeader copies the headers worth replaying, leaving out those
+// describing the original request or set again on every response. The body
+// is recorded before Compress encodes it, so the headers Compress adds are
+// left out too and a replay is encoded for the retry as it negotiates.
+func replayableHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		switch {
+		case name == "X-Request-Id", name == "Retry-After", name == "Date", name == "Content-Length",
+			name == "Content-Encoding", name == "Vary",
+			strings.HasPrefix(name, "X-Ratelimit-"), strings.HasPrefix(name, "Access-Control-"):
+			continue
+		}
+		out[name] = append([]string(nil), values...)
+	}
+	if vary := withoutAcceptEncoding(h.Values("Vary")); len(vary) > 0 {
+		out["Vary"] = vary
+	}
+	if encoding := h.Get("Content-Encoding"); encoding != "" && out.Get("ETag") != "" {
+		out.Set("ETag", stripETagSuffix(out.Get("ETag"), "-"+encoding))
+	}
+	return out
+}
+
+// withoutAcceptEncoding returns the Vary values other than Accept-Encoding
+func withoutAcceptEncoding(values []string) []string {
+	var out []string
+	for _, value := range values {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" && !strings.EqualFold(field, "Accept-Encoding") {
+				out = append(out, field)
+			}
+		}
+	}
+	return out
+}
+
+// recordingWriter keeps a copy of the response body for storage. Bodies
+// larger than maxIdempotentResponseSize are passed through but not kept.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.record(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.record([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *recordingWriter) record(b []byte) {
+	if w.overflow {
+		return
+	}
+	if w.body.Len()+len(b) > maxIdempotentResponseSize {
+		w.overflow = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(b)
+}
@@ -0,0 +1,266 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/idempotency"
+)
+
+func idempotencyRouter(t *testing.T, store idempotency.Store) (*gin.Engine, *auth.TokenService, *atomic.Int64) {
+	t.Helper()
+	router, tokens := authRouter(t)
+	router.Use(Errors(true))
+	cfg := config.IdempotencyConfig{Enabled: true, TTL: time.Hour, LockTimeout: time.Minute}
+	var runs atomic.Int64
+	orders := router.Group("/orders", Auth(tokens), Idempotency(store, cfg))
+	orders.POST("", func(c *gin.Context) {
+		n := runs.Add(1)
+		c.Header("X-Order", "created")
+		c.JSON(http.StatusCreated, gin.H{"run": n})
+	})
+	orders.POST("/invalid", func(c *gin.Context) {
+		runs.Add(1)
+		apperr.Abort(c, apperr.New(apperr.CodeValidationFailed, ""))
+	})
+	orders.POST("/broken", func(c *gin.Context) {
+		runs.Add(1)
+		c.JSON(http.StatusInternalServerError, gin.H{})
+	})
+	return router, tokens, &runs
+}
+
+func TestIdempotency(t *testing.T) {
+	router, tokens, runs := idempotencyRouter(t, idempotency.NewMemoryStore())
+	token1, _, _ := tokens.Issue(auth.Claims{UserID: 1})
+	token2, _, _ := tokens.Issue(auth.Claims{UserID: 2})
+
+	request := func(token, path, key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := request(token1, "/orders", "k1", `{"item":1}`)
+	if first.Code != http.StatusCreated || first.Header().Get(IdempotentReplayedHeader) != "" {
+		t.Fatalf("Expected a fresh 201, got %d %v", first.Code, first.Header())
+	}
+
+	replayed := request(token1, "/orders", "k1", `{"item":1}`)
+	if replayed.Code != http.StatusCreated || replayed.Body.String() != first.Body.String() {
+		t.Errorf("Expected the stored response, got %d %s", replayed.Code, replayed.Body.String())
+	}
+	if replayed.Header().Get(IdempotentReplayedHeader) != "true" || replayed.Header().Get("X-Order") != "created" {
+		t.Errorf("Expected replay headers, got %v", replayed.Header())
+	}
+	if n := runs.Load(); n != 1 {
+		t.Errorf("Expected the handler to run once, got %d", n)
+	}
+
+	tests := []struct {
+		name       string
+		token      string
+		path       string
+		key        string
+		body       string
+		wantStatus int
+		wantRuns   int64
+	}{
+		{"different payload", token1, "/orders", "k1", `{"item":2}`, http.StatusUnprocessableEntity, 1},
+		{"different path", token1, "/orders/invalid", "k1", `{"item":1}`, http.StatusUnprocessableEntity, 1},
+		{"other user", token2, "/orders", "k1", `{"item":1}`, http.StatusCreated, 2},
+		{"no key", token1, "/orders", "", `{"item":1}`, http.StatusCreated, 3},
+		{"key too long", token1, "/orders", strings.Repeat("k", 256), `{}`, http.StatusBadRequest, 3},
+		{"error not stored", token1, "/orders/invalid", "k2", `{}`, http.StatusUnprocessableEntity, 4},
+		{"error retried", token1, "/orders/invalid", "k2", `{}`, http.StatusUnprocessableEntity, 5},
+		{"server error not stored", token1, "/orders/broken", "k3", `{}`, http.StatusInternalServerError, 6},
+		{"server error retried", token1, "/orders/broken", "k3", `{}`, http.StatusInternalServerError, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(tt.token, tt.path, tt.key, tt.body)
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected %d, got %d", tt.wantStatus, w.Code)
+			}
+			if n := runs.Load(); n != tt.wantRuns {
+				t.Errorf("Expected %d handler runs, got %d", tt.wantRuns, n)
+			}
+		})
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	store := idempotency.NewMemoryStore()
+	router, tokens, _ := idempotencyRouter(t, store)
+	token, _, _ := tokens.Issue(auth.Claims{UserID: 1})
+
+	body := `{"item":1}`
+	fingerprint := idempotency.Fingerprint(http.MethodPost, "/orders", []byte(body))
+	if _, _, err := store.Lock(context.Background(), "user:1:busy", fingerprint, time.Minute); err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(IdempotencyKeyHeader, "busy")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict || w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 409 with Retry-After while the first request runs, got %d %v", w.Code, w.Header())
+	}
+}
+
+type failingIdempotencyStore struct{}
+
+func (failingIdempotencyStore) Lock(context.Context, string, string, time.Duration) (*idempotency.Record, string, error) {
+	return nil, "", errors.New("store unavailable")
+}
+
+func (failingIdempotencyStore) Save(context.Context, string, string, *idempotency.Response, time.Duration) error {
+	return errors.New("store unavailable")
+}
+
+func (failingIdempotencyStore) Release(context.Context, string, string) error {
+	return errors.New("store unavailable")
+}
+
+func TestIdempotencyFailsOpen(t *testing.T) {
+	router, tokens, runs := idempotencyRouter(t, failingIdempotencyStore{})
+	token, _, _ := tokens.Issue(auth.Claims{UserID: 1})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Errorf("Expected store errors to let requests through, got %d", w.Code)
+		}
+	}
+	if n := runs.Load(); n != 2 {
+		t.Errorf("Expected both requests to run, got %d", n)
+	}
+}
+
+// cancelCheckingStore fails Save and Release called with a cancelled context
+type cancelCheckingStore struct {
+	*idempotency.MemoryStore
+}
+
+func (s cancelCheckingStore) Save(ctx context.Context, key, token string, resp *idempotency.Response, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.Save(ctx, key, token, resp, ttl)
+}
+
+func (s cancelCheckingStore) Release(ctx context.Context, key, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.MemoryStore.Release(ctx, key, token)
+}
+
+func TestIdempotencyClientGone(t *testing.T) {
+	store := cancelCheckingStore{idempotency.NewMemoryStore()}
+	router, tokens, runs := idempotencyRouter(t, store)
+	token, _, _ := tokens.Issue(auth.Claims{UserID: 1})
+
+	// The client disconnects while the handler runs; the response is still
+	// stored for its retry
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`)).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(IdempotencyKeyHeader, "gone")
+	cancel()
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(IdempotencyKeyHeader, "gone")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Header().Get(IdempotentReplayedHeader) != "true" || runs.Load() != 1 {
+		t.Errorf("Expected the retry to be replayed, got %d after %d runs", w.Code, runs.Load())
+	}
+}
+
+func TestIdempotencyWithCompress(t *testing.T) {
+	_, tokens := authRouter(t)
+	router := gin.New()
+	router.Use(Compress(0))
+	cfg := config.IdempotencyConfig{Enabled: true, TTL: time.Hour, LockTimeout: time.Minute}
+	router.POST("/orders", Auth(tokens), Idempotency(idempotency.NewMemoryStore(), cfg), func(c *gin.Context) {
+		c.Header("ETag", `"v1"`)
+		c.JSON(http.StatusCreated, gin.H{"item": 1})
+	})
+	token, _, _ := tokens.Issue(auth.Claims{UserID: 1})
+
+	request := func(acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set(IdempotencyKeyHeader, "k1")
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantEncoding   string
+		wantETag       string
+	}{
+		{name: "first request", acceptEncoding: "gzip", wantEncoding: "gzip", wantETag: `"v1-gzip"`},
+		{name: "replay without compression", wantETag: `"v1"`},
+		{name: "replay compressed again", acceptEncoding: "gzip", wantEncoding: "gzip", wantETag: `"v1-gzip"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(tt.acceptEncoding)
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("Expected encoding %q, got %q", tt.wantEncoding, got)
+			}
+			if got := w.Header().Get("ETag"); got != tt.wantETag {
+				t.Errorf("Expected ETag %s, got %s", tt.wantETag, got)
+			}
+			if got := w.Header().Values("Vary"); len(got) != 1 || got[0] != "Accept-Encoding" {
+				t.Errorf("Expected Vary: Accept-Encoding once, got %v", got)
+			}
+
+			body := w.Body.String()
+			if tt.wantEncoding == "gzip" {
+				gr, err := gzip.NewReader(w.Body)
+				if err != nil {
+					t.Fatalf("Invalid gzip body: %v", err)
+				}
+				b, _ := io.ReadAll(gr)
+				body = string(b)
+			}
+			if body != `{"item":1}` {
+				t.Errorf("Expected the JSON body, got %q", body)
+			}
+		})
+	}
+}
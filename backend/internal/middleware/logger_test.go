@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func loggerRouter(buf *bytes.Buffer) *gin.Engine {
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+	router := gin.New()
+	router.Use(RequestID(), RequestLogger(logger))
+	router.GET("/users/:id", func(c *gin.Context) {
+		c.Set(userIDKey, 42)
+		c.String(http.StatusOK, RequestIDFromContext(c.Request.Context()))
+	})
+	return router
+}
+
+func TestRequestID(t *testing.T) {
+	router := loggerRouter(&bytes.Buffer{})
+
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "generated", incoming: "", keep: false},
+		{name: "propagated", incoming: "abc-123", keep: true},
+		{name: "rejected with spaces", incoming: "bad id", keep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			id := w.Header().Get(RequestIDHeader)
+			if id == "" {
+				t.Fatal("Expected X-Request-ID response header")
+			}
+			if tt.keep && id != tt.incoming {
+				t.Errorf("Expected request ID %q, got %q", tt.incoming, id)
+			}
+			if !tt.keep && id == tt.incoming {
+				t.Errorf("Expected a generated request ID, got %q", id)
+			}
+			if w.Body.String() != id {
+				t.Errorf("Handler saw request ID %q, header has %q", w.Body.String(), id)
+			}
+		})
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	router := loggerRouter(&buf)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("Expected one JSON log record, got %q: %v", buf.String(), err)
+	}
+
+	expected := map[string]any{
+		"msg":        "request",
+		"level":      "INFO",
+		"method":     "GET",
+		"route":      "/users/:id",
+		"path":       "/users/7",
+		"status":     float64(200),
+		"bytes":      float64(5),
+		"request_id": "req-1",
+		"user_id":    float64(42),
+	}
+	for key, want := range expected {
+		if record[key] != want {
+			t.Errorf("Expected %s=%v, got %v", key, want, record[key])
+		}
+	}
+	if _, ok := record["latency_ms"]; !ok {
+		t.Error("Expected latency_ms field")
+	}
+}
+
+func TestRequestLoggerLevels(t *testing.T) {
+	var buf bytes.Buffer
+	router := loggerRouter(&buf)
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	var record map[string]any
+	json.Unmarshal(buf.Bytes(), &record)
+	if record["level"] != "WARN" || record["route"] != "unmatched" {
+		t.Errorf("Expected WARN for unmatched route, got level=%v route=%v", record["level"], record["route"])
+	}
+}
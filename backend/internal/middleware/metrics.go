@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/metrics"
+)
+
+// knownMethods bounds the method label to standard HTTP methods
+var knownMethods = map[string]bool{
+	http.MethodGet: true, http.MethodHead: true, http.MethodPost: true, http.MethodPut: true,
+	http.MethodPatch: true, http.MethodDelete: true, http.MethodOptions: true,
+}
+
+// Metrics records request counts, latency, response size and in-flight
+// requests. Requests are labelled by c.FullPath() so path parameters don't
+// create a series per ID.
+func Metrics(m *metrics.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		if !knownMethods[method] {
+			method = "OTHER"
+		}
+
+		inFlight := m.InFlight.WithLabelValues(method, route)
+		inFlight.Inc()
+		start := time.Now()
+
+		c.Next()
+
+		inFlight.Dec()
+		m.Requests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		m.RequestDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+		m.ResponseSize.WithLabelValues(method, route).Observe(float64(max(c.Writer.Size(), 0)))
+	}
+}
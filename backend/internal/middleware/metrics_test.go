@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/metrics"
+)
+
+func TestMetrics(t *testing.T) {
+	m := metrics.New()
+	router := gin.New()
+	router.Use(Metrics(m))
+	router.GET("/users/:id", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/metrics", gin.WrapH(m.Handler()))
+
+	for _, path := range []string{"/users/1", "/users/2", "/users/3", "/nope"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.Requests.WithLabelValues("GET", "/users/:id", "200")); got != 3 {
+		t.Errorf("Expected 3 requests for /users/:id, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.Requests.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("Expected 1 unmatched request, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.InFlight.WithLabelValues("GET", "/users/:id")); got != 0 {
+		t.Errorf("Expected no requests in flight, got %v", got)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := w.Body.String()
+	for _, want := range []string{
+		`backend_http_requests_total{method="GET",route="/users/:id",status="200"} 3`,
+		"backend_http_request_duration_seconds_bucket",
+		"go_goroutines",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected exposition to contain %q", want)
+		}
+	}
+	if strings.Contains(body, `route="/users/1"`) {
+		t.Error("Raw paths must not be used as labels")
+	}
+}
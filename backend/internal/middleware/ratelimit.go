@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/apperr"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/ratelimit"
+)
+
+// RateLimit enforces the named token-bucket policy. Clients are keyed by IP,
+// or by user ID for "user" policies (falling back to IP before Auth has run).
+// Every response carries X-RateLimit-Limit/-Remaining/-Reset; rejected
+// requests get 429 with Retry-After. Store errors let the request through.
+func RateLimit(store ratelimit.Store, name string, policy config.RateLimitPolicy) gin.HandlerFunc {
+	limit := ratelimit.Every(policy.Requests, policy.Per, policy.Burst)
+
+	return func(c *gin.Context) {
+		key := name + ":ip:" + c.ClientIP()
+		if policy.Key == config.RateLimitByUser {
+			if claims, ok := GetClaims(c); ok {
+				key = fmt.Sprintf("%s:user:%d", name, claims.UserID)
+			}
+		}
+
+		result, err := store.Take(c.Request.Context(), key, limit)
+		if err != nil {
+			LoggerFromContext(c.Request.Context()).Error("rate limit store failed", "policy", name, "error", err)
+			c.Next()
+			return
+		}
+
+		h := c.Writer.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			apperr.Abort(c, apperr.New(apperr.CodeRateLimited, ""))
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds d up to whole seconds for HTTP headers
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/auth"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/ratelimit"
+)
+
+func TestRateLimitByIP(t *testing.T) {
+	router := gin.New()
+	policy := config.RateLimitPolicy{Requests: 1, Per: time.Minute, Burst: 2, Key: config.RateLimitByIP}
+	router.GET("/ping", RateLimit(ratelimit.NewMemoryStore(time.Minute), "api", policy), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	request := func(ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	for i, wantRemaining := range []string{"1", "0"} {
+		w := request("10.0.0.1")
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i, w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("Request %d: expected remaining %s, got %s", i, wantRemaining, got)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "2" {
+			t.Errorf("Request %d: expected limit 2, got %s", i, got)
+		}
+	}
+
+	w := request("10.0.0.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Expected Retry-After 60, got %q", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Reset"); got != "120" {
+		t.Errorf("Expected reset in 120s, got %q", got)
+	}
+
+	if w := request("10.0.0.2"); w.Code != http.StatusOK {
+		t.Errorf("Expected other IPs to be unaffected, got %d", w.Code)
+	}
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	router, tokens := authRouter(t)
+	policy := config.RateLimitPolicy{Requests: 1, Per: time.Hour, Burst: 1, Key: config.RateLimitByUser}
+	limited := router.Group("/limited", Auth(tokens), RateLimit(ratelimit.NewMemoryStore(time.Minute), "user", policy))
+	limited.GET("", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(userID int64) int {
+		token, _, _ := tokens.Issue(auth.Claims{UserID: userID})
+		req := httptest.NewRequest(http.MethodGet, "/limited", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := request(1); code != http.StatusOK {
+		t.Fatalf("Expected first request to pass, got %d", code)
+	}
+	if code := request(1); code != http.StatusTooManyRequests {
+		t.Errorf("Expected user 1 to be limited, got %d", code)
+	}
+	if code := request(2); code != http.StatusOK {
+		t.Errorf("Expected user 2 to have its own bucket from the same IP, got %d", code)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	router := gin.New()
+	policy := config.RateLimitPolicy{Requests: 1, Per: time.Minute, Key: config.RateLimitByIP}
+	router.GET("/ping", RateLimit(failingStore{}, "api", policy), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected store errors to let requests through, got %d", w.Code)
+	}
+}
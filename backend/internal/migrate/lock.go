@@ -0,0 +1,95 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// lockPollInterval is how often a waiting SQLite migrator retries the lock
+const lockPollInterval = 100 * time.Millisecond
+
+// lock blocks until this process holds the migration lock or ctx is done.
+// Postgres uses a session advisory lock; SQLite, which has none, uses a
+// single-row lock table.
+func (m *Migrator) lock(ctx context.Context) (func(), error) {
+	if m.db.Dialect == database.Postgres {
+		return m.advisoryLock(ctx)
+	}
+	return m.tableLock(ctx)
+}
+
+func (m *Migrator) advisoryLock(ctx context.Context) (func(), error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: lock: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("migrate: lock: %w", err)
+	}
+	return func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+		conn.Close()
+	}, nil
+}
+
+func (m *Migrator) tableLock(ctx context.Context) (func(), error) {
+	if err := m.ensureLockTable(ctx); err != nil {
+		return nil, err
+	}
+	for {
+		res, err := m.db.ExecContext(ctx,
+			"INSERT INTO "+lockTable+" (id, locked_at) VALUES (1, ?) ON CONFLICT (id) DO NOTHING", time.Now().UTC())
+		if err != nil {
+			return nil, fmt.Errorf("migrate: lock: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			var lockedAt time.Time
+			m.db.QueryRowContext(context.Background(), "SELECT locked_at FROM "+lockTable+" WHERE id = 1").Scan(&lockedAt)
+			return nil, fmt.Errorf("migrate: waiting for lock taken at %s (run \"migrate unlock\" if its migrator crashed): %w",
+				lockedAt.Format(time.RFC3339), ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+	return func() {
+		m.db.ExecContext(context.Background(), "DELETE FROM "+lockTable+" WHERE id = 1")
+	}, nil
+}
+
+// ensureLockTable creates the SQLite lock table if needed
+func (m *Migrator) ensureLockTable(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+lockTable+` (
+		id        INTEGER PRIMARY KEY CHECK (id = 1),
+		locked_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("migrate: create lock table: %w", err)
+	}
+	return nil
+}
+
+// Unlock releases a migration lock left behind by a migrator that crashed
+// and reports whether there was one. Only the SQLite lock table can be left
+// behind: Postgres releases advisory locks when the holding session ends.
+// Never run it while another migrator may still be working.
+func (m *Migrator) Unlock(ctx context.Context) (bool, error) {
+	if m.db.Dialect == database.Postgres {
+		return false, nil
+	}
+	if err := m.ensureLockTable(ctx); err != nil {
+		return false, err
+	}
+	res, err := m.db.ExecContext(ctx, "DELETE FROM "+lockTable+" WHERE id = 1")
+	if err != nil {
+		return false, fmt.Errorf("migrate: unlock: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
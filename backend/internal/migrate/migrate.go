@@ -0,0 +1,387 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// Predefined errors
+var (
+	ErrChecksumMismatch = errors.New("migrate: applied migration has been modified")
+	ErrMissingMigration = errors.New("migrate: applied migration is missing from source")
+	ErrUnknownVersion   = errors.New("migrate: unknown version")
+	ErrNoDownMigration  = errors.New("migrate: migration has no down script")
+)
+
+const (
+	schemaTable = "schema_migrations"
+	lockTable   = "schema_migrations_lock"
+
+	// advisoryLockKey identifies the migration lock among Postgres advisory locks
+	advisoryLockKey int64 = 0x6d696772617465
+)
+
+// Direction tells whether a migration was applied or rolled back
+type Direction string
+
+// Migration directions
+const (
+	Up   Direction = "up"
+	Down Direction = "down"
+)
+
+// Step records one migration run by the Migrator
+type Step struct {
+	Migration
+	Direction Direction
+}
+
+// State describes how a migration relates to the database
+type State string
+
+// Migration states reported by Status
+const (
+	StatePending  State = "pending"
+	StateApplied  State = "applied"
+	StateModified State = "modified"
+	StateMissing  State = "missing"
+)
+
+// Status describes one migration known to the source or the database
+type Status struct {
+	Version   int64
+	Name      string
+	State     State
+	AppliedAt time.Time
+}
+
+// applied is a row of the schema table
+type applied struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// Migrator applies versioned migrations and records them in a schema table
+type Migrator struct {
+	db         *database.DB
+	migrations []Migration
+}
+
+// New loads the migrations in fsys for db's dialect
+func New(db *database.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys, db.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Migrations returns the known migrations ordered by version
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// Up applies every pending migration in version order
+func (m *Migrator) Up(ctx context.Context) ([]Step, error) {
+	return m.run(ctx, func(done map[int64]applied) ([]Step, error) {
+		return m.applyPending(ctx, done, -1)
+	})
+}
+
+// Down rolls back the n most recently applied migrations
+func (m *Migrator) Down(ctx context.Context, n int) ([]Step, error) {
+	return m.run(ctx, func(done map[int64]applied) ([]Step, error) {
+		versions := appliedVersions(done)
+		if n > len(versions) {
+			n = len(versions)
+		}
+		return m.rollback(ctx, versions[len(versions)-n:])
+	})
+}
+
+// Redo rolls back the latest migration and applies it again
+func (m *Migrator) Redo(ctx context.Context) ([]Step, error) {
+	return m.run(ctx, func(done map[int64]applied) ([]Step, error) {
+		versions := appliedVersions(done)
+		if len(versions) == 0 {
+			return nil, nil
+		}
+		latest := versions[len(versions)-1]
+		steps, err := m.rollback(ctx, []int64{latest})
+		if err != nil {
+			return steps, err
+		}
+		mig, _ := m.find(latest)
+		if err := m.apply(ctx, mig); err != nil {
+			return steps, err
+		}
+		return append(steps, Step{Migration: mig, Direction: Up}), nil
+	})
+}
+
+// Goto migrates up or down until version is the latest applied migration.
+// Version 0 rolls back everything.
+func (m *Migrator) Goto(ctx context.Context, version int64) ([]Step, error) {
+	if _, ok := m.find(version); !ok && version != 0 {
+		return nil, fmt.Errorf("%w %d", ErrUnknownVersion, version)
+	}
+	return m.run(ctx, func(done map[int64]applied) ([]Step, error) {
+		var newer []int64
+		for _, v := range appliedVersions(done) {
+			if v > version {
+				newer = append(newer, v)
+			}
+		}
+		steps, err := m.rollback(ctx, newer)
+		if err != nil {
+			return steps, err
+		}
+		for _, v := range newer {
+			delete(done, v)
+		}
+		more, err := m.applyPending(ctx, done, version)
+		return append(steps, more...), err
+	})
+}
+
+// Status reports every migration in the source and the database. It only
+// reads: before the first migration runs nothing is applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	exists, err := m.schemaExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int64]applied)
+	if exists {
+		if done, err = m.applied(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Version: mig.Version, Name: mig.Name, State: StatePending}
+		if row, ok := done[mig.Version]; ok {
+			s.State, s.AppliedAt = StateApplied, row.AppliedAt
+			if row.Checksum != mig.Checksum {
+				s.State = StateModified
+			}
+		}
+		statuses = append(statuses, s)
+	}
+	for _, row := range done {
+		if _, ok := m.find(row.Version); !ok {
+			statuses = append(statuses, Status{Version: row.Version, Name: row.Name, State: StateMissing, AppliedAt: row.AppliedAt})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// Version returns the latest applied version and the number of pending
+// migrations. Like Status it runs no DDL, so readiness probes can poll it.
+func (m *Migrator) Version(ctx context.Context) (int64, int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	var version int64
+	pending := 0
+	for _, s := range statuses {
+		switch s.State {
+		case StatePending:
+			pending++
+		default:
+			version = s.Version
+		}
+	}
+	return version, pending, nil
+}
+
+// run takes the migration lock, verifies applied migrations and calls fn.
+// The schema table is created under the lock so concurrent migrators never
+// race on the DDL.
+func (m *Migrator) run(ctx context.Context, fn func(map[int64]applied) ([]Step, error)) ([]Step, error) {
+	unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := m.ensureSchema(ctx); err != nil {
+		return nil, err
+	}
+
+	done, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.verify(done); err != nil {
+		return nil, err
+	}
+	return fn(done)
+}
+
+// verify checks that every applied migration still exists unchanged
+func (m *Migrator) verify(done map[int64]applied) error {
+	for _, v := range appliedVersions(done) {
+		mig, ok := m.find(v)
+		if !ok {
+			return fmt.Errorf("%w: version %d (%s)", ErrMissingMigration, v, done[v].Name)
+		}
+		if mig.Checksum != done[v].Checksum {
+			return fmt.Errorf("%w: version %d (%s)", ErrChecksumMismatch, v, mig.Name)
+		}
+	}
+	return nil
+}
+
+// applyPending applies unapplied migrations up to limit (-1 for all)
+func (m *Migrator) applyPending(ctx context.Context, done map[int64]applied, limit int64) ([]Step, error) {
+	var steps []Step
+	for _, mig := range m.migrations {
+		if limit >= 0 && mig.Version > limit {
+			break
+		}
+		if _, ok := done[mig.Version]; ok {
+			continue
+		}
+		if err := m.apply(ctx, mig); err != nil {
+			return steps, err
+		}
+		steps = append(steps, Step{Migration: mig, Direction: Up})
+	}
+	return steps, nil
+}
+
+// rollback reverts the given versions, newest first
+func (m *Migrator) rollback(ctx context.Context, versions []int64) ([]Step, error) {
+	var steps []Step
+	for i := len(versions) - 1; i >= 0; i-- {
+		mig, _ := m.find(versions[i])
+		if err := m.revert(ctx, mig); err != nil {
+			return steps, err
+		}
+		steps = append(steps, Step{Migration: mig, Direction: Down})
+	}
+	return steps, nil
+}
+
+// apply runs a migration's up script and records it in one transaction
+func (m *Migrator) apply(ctx context.Context, mig Migration) error {
+	return m.inTx(ctx, mig, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, m.db.Rebind(
+			"INSERT INTO "+schemaTable+" (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"),
+			mig.Version, mig.Name, mig.Checksum, time.Now().UTC())
+		return err
+	})
+}
+
+// revert runs a migration's down script and forgets it in one transaction
+func (m *Migrator) revert(ctx context.Context, mig Migration) error {
+	if mig.Down == "" {
+		return fmt.Errorf("%w: version %d (%s)", ErrNoDownMigration, mig.Version, mig.Name)
+	}
+	return m.inTx(ctx, mig, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, m.db.Rebind("DELETE FROM "+schemaTable+" WHERE version = ?"), mig.Version)
+		return err
+	})
+}
+
+func (m *Migrator) inTx(ctx context.Context, mig Migration, fn func(*sql.Tx) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("migrate: begin: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migrate: version %d (%s): %w", mig.Version, mig.Name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("migrate: version %d (%s): commit: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+// ensureSchema creates the schema table if needed
+func (m *Migrator) ensureSchema(ctx context.Context) error {
+	_, err := m.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+schemaTable+` (
+		version    BIGINT PRIMARY KEY,
+		name       TEXT NOT NULL,
+		checksum   TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("migrate: create schema table: %w", err)
+	}
+	return nil
+}
+
+// schemaExists reports whether the schema table has been created
+func (m *Migrator) schemaExists(ctx context.Context) (bool, error) {
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?"
+	if m.db.Dialect == database.Postgres {
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = $1"
+	}
+	var n int
+	if err := m.db.QueryRowContext(ctx, query, schemaTable).Scan(&n); err != nil {
+		return false, fmt.Errorf("migrate: read schema table: %w", err)
+	}
+	return n > 0, nil
+}
+
+// applied returns the rows of the schema table keyed by version
+func (m *Migrator) applied(ctx context.Context) (map[int64]applied, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+schemaTable)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: read schema table: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int64]applied)
+	for rows.Next() {
+		var a applied
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, fmt.Errorf("migrate: read schema table: %w", err)
+		}
+		done[a.Version] = a
+	}
+	return done, rows.Err()
+}
+
+// find returns the migration with the given version
+func (m *Migrator) find(version int64) (Migration, bool) {
+	i := sort.Search(len(m.migrations), func(i int) bool {
+		return m.migrations[i].Version >= version
+	})
+	if i < len(m.migrations) && m.migrations[i].Version == version {
+		return m.migrations[i], true
+	}
+	return Migration{}, false
+}
+
+// appliedVersions returns the applied versions in ascending order
+func appliedVersions(done map[int64]applied) []int64 {
+	versions := make([]int64, 0, len(done))
+	for v := range done {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
@@ -0,0 +1,274 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/config"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+func testDB(t *testing.T) *database.DB {
+	t.Helper()
+	db, err := database.Open(config.DatabaseConfig{
+		URL:          "sqlite:" + filepath.Join(t.TempDir(), "migrate.db"),
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testSource() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_users.up.sql":            {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);")},
+		"0001_users.down.sql":          {Data: []byte("DROP TABLE users;")},
+		"0002_posts.up.sql":            {Data: []byte("CREATE TABLE posts (id BIGSERIAL PRIMARY KEY);")},
+		"0002_posts.sqlite.up.sql":     {Data: []byte("CREATE TABLE posts (id INTEGER PRIMARY KEY);")},
+		"0002_posts.down.sql":          {Data: []byte("DROP TABLE posts;")},
+		"0003_tags.up.sql":             {Data: []byte("CREATE TABLE tags (name TEXT PRIMARY KEY);")},
+		"0003_tags.down.sql":           {Data: []byte("DROP TABLE tags;")},
+		"0004_indexes.postgres.up.sql": {Data: []byte("CREATE INDEX CONCURRENTLY x ON tags (name);")},
+	}
+}
+
+func tableExists(t *testing.T, db *database.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n); err != nil {
+		t.Fatalf("Failed to query sqlite_master: %v", err)
+	}
+	return n == 1
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testSource(), database.SQLite)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("Expected 3 sqlite migrations, got %d", len(migrations))
+	}
+	if migrations[1].Up != "CREATE TABLE posts (id INTEGER PRIMARY KEY);" {
+		t.Errorf("Expected dialect-specific up script, got %q", migrations[1].Up)
+	}
+
+	migrations, err = Load(testSource(), database.Postgres)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(migrations) != 4 || migrations[1].Up != "CREATE TABLE posts (id BIGSERIAL PRIMARY KEY);" {
+		t.Errorf("Unexpected postgres migrations: %+v", migrations)
+	}
+
+	_, err = Load(fstest.MapFS{"users.sql": {Data: []byte("")}}, database.SQLite)
+	if err == nil {
+		t.Error("Expected error for invalid file name, got none")
+	}
+}
+
+func TestUpDownRedo(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	m, err := New(db, testSource())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	steps, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+	if len(steps) != 3 {
+		t.Errorf("Expected 3 steps, got %d", len(steps))
+	}
+	if !tableExists(t, db, "tags") {
+		t.Error("Expected tags table after Up()")
+	}
+
+	steps, err = m.Up(ctx)
+	if err != nil || len(steps) != 0 {
+		t.Errorf("Expected second Up() to be a no-op, got %d steps, err %v", len(steps), err)
+	}
+
+	steps, err = m.Down(ctx, 2)
+	if err != nil {
+		t.Fatalf("Down() failed: %v", err)
+	}
+	if len(steps) != 2 || steps[0].Version != 3 || steps[1].Version != 2 {
+		t.Errorf("Expected versions 3 then 2 rolled back, got %+v", steps)
+	}
+	if tableExists(t, db, "posts") {
+		t.Error("Expected posts table to be dropped")
+	}
+
+	version, pending, err := m.Version(ctx)
+	if err != nil {
+		t.Fatalf("Version() failed: %v", err)
+	}
+	if version != 1 || pending != 2 {
+		t.Errorf("Expected version 1 with 2 pending, got %d with %d", version, pending)
+	}
+
+	steps, err = m.Redo(ctx)
+	if err != nil {
+		t.Fatalf("Redo() failed: %v", err)
+	}
+	if len(steps) != 2 || steps[0].Direction != Down || steps[1].Direction != Up {
+		t.Errorf("Expected down+up steps, got %+v", steps)
+	}
+}
+
+func TestGoto(t *testing.T) {
+	ctx := context.Background()
+	m, err := New(testDB(t), testSource())
+	if err != nil {
+		t.Fatalf("New() failed: %v", err)
+	}
+
+	if _, err := m.Goto(ctx, 2); err != nil {
+		t.Fatalf("Goto(2) failed: %v", err)
+	}
+	if version, _, _ := m.Version(ctx); version != 2 {
+		t.Errorf("Expected version 2, got %d", version)
+	}
+
+	if _, err := m.Goto(ctx, 0); err != nil {
+		t.Fatalf("Goto(0) failed: %v", err)
+	}
+	if version, _, _ := m.Version(ctx); version != 0 {
+		t.Errorf("Expected version 0, got %d", version)
+	}
+
+	if _, err := m.Goto(ctx, 42); !errors.Is(err, ErrUnknownVersion) {
+		t.Errorf("Expected ErrUnknownVersion, got %v", err)
+	}
+}
+
+func TestVersionIsReadOnly(t *testing.T) {
+	db := testDB(t)
+	m, _ := New(db, testSource())
+
+	version, pending, err := m.Version(context.Background())
+	if err != nil {
+		t.Fatalf("Version() failed: %v", err)
+	}
+	if version != 0 || pending != 3 {
+		t.Errorf("Expected version 0 with 3 pending, got %d with %d", version, pending)
+	}
+	if tableExists(t, db, schemaTable) {
+		t.Error("Expected Version() not to create the schema table")
+	}
+}
+
+func TestChecksumDrift(t *testing.T) {
+	ctx := context.Background()
+	db := testDB(t)
+	source := testSource()
+	m, _ := New(db, source)
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() failed: %v", err)
+	}
+
+	source["0001_users.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY);")}
+	m, _ = New(db, source)
+
+	if _, err := m.Up(ctx); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() failed: %v", err)
+	}
+	if statuses[0].State != StateModified {
+		t.Errorf("Expected version 1 to be modified, got %s", statuses[0].State)
+	}
+
+	delete(source, "0003_tags.up.sql")
+	delete(source, "0003_tags.down.sql")
+	m, _ = New(db, source)
+	statuses, _ = m.Status(ctx)
+	if statuses[len(statuses)-1].State != StateMissing {
+		t.Errorf("Expected version 3 to be missing, got %s", statuses[len(statuses)-1].State)
+	}
+}
+
+func TestLockExcludesConcurrentMigrators(t *testing.T) {
+	db := testDB(t)
+	m, _ := New(db, testSource())
+	if err := m.ensureSchema(context.Background()); err != nil {
+		t.Fatalf("ensureSchema() failed: %v", err)
+	}
+
+	unlock, err := m.lock(context.Background())
+	if err != nil {
+		t.Fatalf("lock() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	if _, err := m.Up(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Up() to wait for the lock, got %v", err)
+	}
+
+	unlock()
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Errorf("Up() after unlock failed: %v", err)
+	}
+}
+
+func TestUnlockStaleLock(t *testing.T) {
+	db := testDB(t)
+	m, _ := New(db, testSource())
+
+	// A migrator that crashed never ran its unlock; the lock is taken before
+	// the schema table exists
+	if _, err := m.lock(context.Background()); err != nil {
+		t.Fatalf("lock() failed: %v", err)
+	}
+	if tableExists(t, db, schemaTable) {
+		t.Error("Expected the schema table to be created under the lock, not before it")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+	defer cancel()
+	if _, err := m.Up(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected Up() to wait for the stale lock, got %v", err)
+	}
+
+	released, err := m.Unlock(context.Background())
+	if err != nil || !released {
+		t.Fatalf("Expected Unlock() to release the lock, got %v, %v", released, err)
+	}
+	if released, _ := m.Unlock(context.Background()); released {
+		t.Error("Expected a second Unlock() to find no lock")
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Errorf("Up() after Unlock() failed: %v", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "0007_existing.up.sql"), nil, 0o644)
+
+	up, down, err := Create(dir, "Add Habits table")
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if filepath.Base(up) != "0008_add_habits_table.up.sql" {
+		t.Errorf("Unexpected up file %s", up)
+	}
+	if filepath.Base(down) != "0008_add_habits_table.down.sql" {
+		t.Errorf("Unexpected down file %s", down)
+	}
+	if _, _, err := Create(dir, "!!"); err == nil {
+		t.Error("Expected error for empty name, got none")
+	}
+}
@@ -0,0 +1,131 @@
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// fileName matches VERSION_NAME[.DIALECT].(up|down).sql
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+?)(?:\.(postgres|sqlite))?\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Load reads the migrations in fsys for the given dialect, ordered by version.
+// A dialect-specific file (0002_users.sqlite.up.sql) takes precedence over the
+// portable one (0002_users.up.sql).
+func Load(fsys fs.FS, dialect database.Dialect) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("migrate: read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	specific := make(map[string]bool)
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migrate: invalid migration file name %q", entry.Name())
+		}
+		fileDialect := match[3]
+		if fileDialect != "" && database.Dialect(fileDialect) != dialect {
+			continue
+		}
+
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migrate: version %d used by both %q and %q", version, m.Name, match[2])
+		}
+
+		key := match[1] + "." + match[4]
+		if specific[key] {
+			continue
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("migrate: read %s: %w", entry.Name(), err)
+		}
+		specific[key] = fileDialect != ""
+		if match[4] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migrate: version %d (%s) has no up migration", m.Version, m.Name)
+		}
+		m.Checksum = checksum(m.Up)
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// checksum fingerprints migration SQL so edits to applied files are detected
+func checksum(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create writes an empty up/down migration pair to dir, numbered after the
+// highest existing version, and returns the paths of the new files.
+func Create(dir, name string) (string, string, error) {
+	name = strings.Trim(strings.ToLower(regexp.MustCompile(`[^A-Za-z0-9]+`).ReplaceAllString(name, "_")), "_")
+	if name == "" {
+		return "", "", fmt.Errorf("migrate: migration name is required")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("migrate: read %s: %w", dir, err)
+	}
+	var next int64 = 1
+	for _, entry := range entries {
+		if match := fileName.FindStringSubmatch(entry.Name()); match != nil {
+			if v, _ := strconv.ParseInt(match[1], 10, 64); v >= next {
+				next = v + 1
+			}
+		}
+	}
+
+	base := fmt.Sprintf("%04d_%s", next, name)
+	up := filepath.Join(dir, base+".up.sql")
+	down := filepath.Join(dir, base+".down.sql")
+	if err := os.WriteFile(up, []byte("-- "+base+" (up)\n"), 0o644); err != nil {
+		return "", "", fmt.Errorf("migrate: %w", err)
+	}
+	if err := os.WriteFile(down, []byte("-- "+base+" (down)\n"), 0o644); err != nil {
+		return "", "", fmt.Errorf("migrate: %w", err)
+	}
+	return up, down, nil
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"time"
+)
+
+// DateLayout is the encoding of a Date
+const DateLayout = "2006-01-02"
+
+// Date is a calendar day without a time zone, encoded as YYYY-MM-DD. The
+// zero value is the zero date. Dates are comparable with ==.
+type Date struct {
+	t time.Time // midnight UTC
+}
+
+// NewDate returns the given calendar day, normalising overflowing values
+// like time.Date does
+func NewDate(year int, month time.Month, day int) Date {
+	return Date{t: time.Date(year, month, day, 0, 0, 0, 0, time.UTC)}
+}
+
+// DateOf returns the calendar day of t in t's location
+func DateOf(t time.Time) Date {
+	y, m, d := t.Date()
+	return NewDate(y, m, d)
+}
+
+// ParseDate parses a YYYY-MM-DD date
+func ParseDate(s string) (Date, error) {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD", s)
+	}
+	return Date{t: t}, nil
+}
+
+// String formats the date as YYYY-MM-DD
+func (d Date) String() string {
+	return d.t.Format(DateLayout)
+}
+
+// IsZero reports whether d is the zero date
+func (d Date) IsZero() bool {
+	return d.t.IsZero()
+}
+
+// AddDays returns the date n days after d
+func (d Date) AddDays(n int) Date {
+	return Date{t: d.t.AddDate(0, 0, n)}
+}
+
+// Weekday returns the day of the week of d
+func (d Date) Weekday() time.Weekday {
+	return d.t.Weekday()
+}
+
+// Before reports whether d is before u
+func (d Date) Before(u Date) bool {
+	return d.t.Before(u.t)
+}
+
+// After reports whether d is after u
+func (d Date) After(u Date) bool {
+	return d.t.After(u.t)
+}
+
+// DaysSince returns the number of days from u to d
+func (d Date) DaysSince(u Date) int {
+	return int(d.t.Sub(u.t).Hours() / 24)
+}
+
+// In returns the start of d in loc
+func (d Date) In(loc *time.Location) time.Time {
+	y, m, day := d.t.Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, loc)
+}
+
+// MarshalText encodes the date as YYYY-MM-DD
+func (d Date) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText decodes a YYYY-MM-DD date
+func (d *Date) UnmarshalText(b []byte) error {
+	parsed, err := ParseDate(string(b))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// UnmarshalParam decodes a YYYY-MM-DD query or form parameter
+func (d *Date) UnmarshalParam(param string) error {
+	return d.UnmarshalText([]byte(param))
+}
+
+// Scan reads a DATE column, which drivers return as time.Time or text
+func (d *Date) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*d = DateOf(v)
+		return nil
+	case string:
+		return d.UnmarshalText([]byte(v))
+	case []byte:
+		return d.UnmarshalText(v)
+	default:
+		return fmt.Errorf("models: cannot scan %T into Date", src)
+	}
+}
+
+// Value stores the date as YYYY-MM-DD
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
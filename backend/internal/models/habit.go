@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// Habit schedule kinds
+const (
+	ScheduleDaily    = "daily"
+	ScheduleWeekly   = "weekly"
+	ScheduleWeekdays = "weekdays"
+)
+
+// weekdayNames are the weekday names used in schedules, indexed by time.Weekday
+var weekdayNames = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// WeekdayName returns the schedule name of a weekday, e.g. "mon"
+func WeekdayName(d time.Weekday) string {
+	return weekdayNames[d]
+}
+
+// ParseWeekday parses a schedule weekday name
+func ParseWeekday(name string) (time.Weekday, bool) {
+	for i, n := range weekdayNames {
+		if n == name {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+// HabitSchedule says when a habit is due: every day, a number of times per
+// week on any days, or on specific weekdays
+type HabitSchedule struct {
+	Kind         string   `json:"kind" binding:"required,oneof=daily weekly weekdays"`
+	TimesPerWeek int      `json:"times_per_week,omitempty" binding:"required_if=Kind weekly,omitempty,min=1,max=7" doc:"required for weekly schedules"`
+	Weekdays     []string `json:"weekdays,omitempty" binding:"required_if=Kind weekdays,omitempty,max=7,unique,dive,oneof=mon tue wed thu fri sat sun" doc:"required for weekdays schedules" example:"mon"`
+}
+
+// Habit is a recurring activity a user tracks
+type Habit struct {
+	ID          int64         `json:"id" example:"7"`
+	UserID      int64         `json:"-"`
+	Name        string        `json:"name" example:"Meditate"`
+	Description string        `json:"description"`
+	Schedule    HabitSchedule `json:"schedule"`
+	StartDate   Date          `json:"start_date" format:"date" doc:"first day the habit is due"`
+	Archived    bool          `json:"archived"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+}
+
+// CheckIn records that a habit was done on a day
+type CheckIn struct {
+	HabitID   int64     `json:"habit_id" example:"7"`
+	Date      Date      `json:"date" format:"date"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
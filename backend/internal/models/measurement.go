@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Metric types
+const (
+	MetricWater     = "water"
+	MetricSleep     = "sleep"
+	MetricHeartRate = "heart_rate"
+	MetricSteps     = "steps"
+	MetricMood      = "mood"
+)
+
+// RollupInterval is the granularity of stored rollups. Every UTC offset in
+// use is a multiple of 15 minutes, so rollups combine into hours, days,
+// weeks and months in any time zone.
+const RollupInterval = 15 * time.Minute
+
+// metricSpec describes how a metric is measured
+type metricSpec struct {
+	// unit is the canonical unit values are stored in
+	unit string
+	// units maps accepted units to their factor to the canonical unit
+	units    map[string]float64
+	min, max float64
+}
+
+var metricSpecs = map[string]metricSpec{
+	MetricWater:     {unit: "ml", units: map[string]float64{"ml": 1, "l": 1000, "fl_oz": 29.5735}, min: 0, max: 10000},
+	MetricSleep:     {unit: "min", units: map[string]float64{"min": 1, "h": 60, "s": 1.0 / 60}, min: 0, max: 1440},
+	MetricHeartRate: {unit: "bpm", units: map[string]float64{"bpm": 1}, min: 20, max: 300},
+	MetricSteps:     {unit: "count", units: map[string]float64{"count": 1}, min: 0, max: 100000},
+	MetricMood:      {unit: "score", units: map[string]float64{"score": 1}, min: 1, max: 10},
+}
+
+// MetricUnit returns the canonical unit of a metric and whether it exists
+func MetricUnit(metric string) (string, bool) {
+	spec, ok := metricSpecs[metric]
+	return spec.unit, ok
+}
+
+// NormalizeMeasurement converts value from unit to the metric's canonical
+// unit and checks it is plausible. An empty unit means the canonical one.
+func NormalizeMeasurement(metric string, value float64, unit string) (float64, error) {
+	spec, ok := metricSpecs[metric]
+	if !ok {
+		return 0, fmt.Errorf("unknown metric %q", metric)
+	}
+	if unit == "" {
+		unit = spec.unit
+	}
+	factor, ok := spec.units[unit]
+	if !ok {
+		return 0, fmt.Errorf("unit %q is not valid for %s", unit, metric)
+	}
+	v := value * factor
+	if v < spec.min || v > spec.max {
+		return 0, fmt.Errorf("must be between %g and %g %s", spec.min, spec.max, spec.unit)
+	}
+	return v, nil
+}
+
+// Measurement is one reading of a metric, stored in the canonical unit
+type Measurement struct {
+	ID         int64
+	UserID     int64
+	ClientID   string
+	Metric     string
+	Value      float64
+	RecordedAt time.Time
+	CreatedAt  time.Time
+}
+
+// Rollup aggregates the measurements of a metric in the RollupInterval
+// starting at Start
+type Rollup struct {
+	Metric string
+	Start  time.Time
+	Count  int64
+	Sum    float64
+	Min    float64
+	Max    float64
+}
+
+// Add folds o into r
+func (r *Rollup) Add(o Rollup) {
+	if r.Count == 0 {
+		r.Min, r.Max = o.Min, o.Max
+	} else {
+		r.Min, r.Max = min(r.Min, o.Min), max(r.Max, o.Max)
+	}
+	r.Count += o.Count
+	r.Sum += o.Sum
+}
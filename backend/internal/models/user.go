@@ -0,0 +1,54 @@
+// Package models defines the domain types shared by stores and handlers.
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// User is a registered account
+type User struct {
+	ID           int64      `json:"id" example:"42"`
+	Email        string     `json:"email" format:"email"`
+	Name         string     `json:"name" example:"Ada Lovelace"`
+	PasswordHash string     `json:"-"`
+	Roles        []string   `json:"roles" example:"user"`
+	TimeZone     string     `json:"timezone" example:"Europe/Moscow" doc:"IANA time zone used for calendar days"`
+	DisabledAt   *time.Time `json:"disabled_at,omitempty" doc:"when an operator disabled the account; disabled accounts cannot sign in"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// HasRole reports whether the user has role
+func (u *User) HasRole(role string) bool {
+	return slices.Contains(u.Roles, role)
+}
+
+// Disabled reports whether an operator disabled the account
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+// Location returns the user's time zone, falling back to UTC
+func (u *User) Location() *time.Location {
+	if loc, err := time.LoadLocation(u.TimeZone); err == nil && u.TimeZone != "" {
+		return loc
+	}
+	return time.UTC
+}
+
+// RefreshToken is a long-lived credential exchanged for access tokens.
+// Only a hash of the token is stored.
+type RefreshToken struct {
+	ID        int64
+	UserID    int64
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// Active reports whether the token can still be used at now
+func (t *RefreshToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
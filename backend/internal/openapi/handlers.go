@@ -0,0 +1,56 @@
+package openapi
+
+import (
+	"embed"
+	"html"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed swagger.html
+var swaggerPage string
+
+// swaggerUI holds the Swagger UI release the page runs, see
+// swagger-ui/README.md
+//
+//go:embed swagger-ui/swagger-ui.css swagger-ui/swagger-ui-bundle.js
+var swaggerUI embed.FS
+
+// JSONHandler serves the generated document
+func (s *Spec) JSONHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.Document())
+	}
+}
+
+// UIHandler serves a Swagger UI page that loads the document from specURL
+// and Swagger UI itself from assetsURL, where AssetsHandler must be mounted
+func UIHandler(title, specURL, assetsURL string) gin.HandlerFunc {
+	page := strings.NewReplacer(
+		"{{TITLE}}", html.EscapeString(title),
+		"{{SPEC_URL}}", html.EscapeString(specURL),
+		"{{ASSETS_URL}}", html.EscapeString(strings.TrimSuffix(assetsURL, "/")),
+	).Replace(swaggerPage)
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
+
+// AssetsHandler serves the embedded Swagger UI files for UIHandler. It must
+// be mounted on a route with a :file parameter, such as /docs/assets/:file.
+func AssetsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("file")
+		b, err := swaggerUI.ReadFile("swagger-ui/" + name)
+		if err != nil {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		c.Header("Cache-Control", "public, max-age=86400")
+		c.Data(http.StatusOK, mime.TypeByExtension(path.Ext(name)), b)
+	}
+}
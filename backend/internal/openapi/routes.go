@@ -0,0 +1,101 @@
+package openapi
+
+import (
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Routes registers Gin routes and documents them in the same call, so the
+// spec cannot drift from the router
+type Routes struct {
+	group *gin.RouterGroup
+	spec  *Spec
+	auth  bool
+	tags  []string
+}
+
+// Wrap returns a documenting wrapper around group
+func (s *Spec) Wrap(group *gin.RouterGroup) *Routes {
+	return &Routes{group: group, spec: s}
+}
+
+// Group creates a sub-group; operations inherit its tags and auth requirement
+func (r *Routes) Group(relativePath string, handlers ...gin.HandlerFunc) *Routes {
+	return &Routes{
+		group: r.group.Group(relativePath, handlers...),
+		spec:  r.spec,
+		auth:  r.auth,
+		tags:  r.tags,
+	}
+}
+
+// WithAuth marks every operation of the returned routes as requiring a bearer token
+func (r *Routes) WithAuth() *Routes {
+	clone := *r
+	clone.auth = true
+	return &clone
+}
+
+// WithTags sets the default tags of the returned routes
+func (r *Routes) WithTags(tags ...string) *Routes {
+	clone := *r
+	clone.tags = tags
+	return &clone
+}
+
+// Use adds middleware to the underlying group
+func (r *Routes) Use(middleware ...gin.HandlerFunc) *Routes {
+	r.group.Use(middleware...)
+	return r
+}
+
+// Handle registers and documents a route
+func (r *Routes) Handle(method, relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	if r.auth {
+		op.Auth = true
+	}
+	if op.Tags == nil {
+		op.Tags = r.tags
+	}
+	r.group.Handle(method, relativePath, handlers...)
+	r.spec.Add(method, joinPaths(r.group.BasePath(), relativePath), op)
+}
+
+// GET registers and documents a GET route
+func (r *Routes) GET(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodGet, relativePath, op, handlers...)
+}
+
+// POST registers and documents a POST route
+func (r *Routes) POST(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPost, relativePath, op, handlers...)
+}
+
+// PUT registers and documents a PUT route
+func (r *Routes) PUT(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPut, relativePath, op, handlers...)
+}
+
+// PATCH registers and documents a PATCH route
+func (r *Routes) PATCH(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPatch, relativePath, op, handlers...)
+}
+
+// DELETE registers and documents a DELETE route
+func (r *Routes) DELETE(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodDelete, relativePath, op, handlers...)
+}
+
+// joinPaths mirrors how Gin joins group and route paths
+func joinPaths(base, relative string) string {
+	if relative == "" {
+		return base
+	}
+	joined := path.Join(base, relative)
+	if relative[len(relative)-1] == '/' && joined[len(joined)-1] != '/' {
+		return joined + "/"
+	}
+	return joined
+}
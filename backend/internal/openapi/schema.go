@@ -0,0 +1,262 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+	marshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textType       = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// componentName strips characters that are not allowed in component keys,
+// e.g. from generic instantiations
+var componentName = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// schemaFor returns the schema of t, registering named structs as components.
+//
+// Struct fields follow encoding/json naming. These tags refine the schema:
+//
+//	doc:"..."          description
+//	example:"..."      example value
+//	enum:"a,b"         allowed values
+//	format:"email"     string format
+//	binding:"..."      gin validation: required, email, min=, max=, oneof=
+func (s *Spec) schemaFor(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+
+	var schema *Schema
+	switch {
+	case t == timeType:
+		schema = &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		schema = &Schema{Type: "integer", Format: "int64", Description: "duration in nanoseconds"}
+	case t == rawMessageType, t.Kind() == reflect.Interface:
+		schema = &Schema{}
+	case t.Implements(marshalerType) || reflect.PointerTo(t).Implements(marshalerType):
+		schema = &Schema{}
+		if t.Kind() == reflect.String {
+			schema.Type = "string"
+		}
+	case t.Implements(textType) || reflect.PointerTo(t).Implements(textType):
+		// encoding/json encodes text marshalers as strings
+		schema = &Schema{Type: "string"}
+	default:
+		schema = s.kindSchema(t)
+	}
+
+	if nullable && schema.Ref == "" {
+		schema.Nullable = true
+	}
+	return schema
+}
+
+func (s *Spec) kindSchema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.structSchema(t)
+		}
+		return s.component(t)
+	default:
+		return &Schema{}
+	}
+}
+
+// component registers a named struct once and returns a reference to it
+func (s *Spec) component(t reflect.Type) *Schema {
+	name := componentName.ReplaceAllString(t.Name(), "_")
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := s.doc.Components.Schemas[name]; ok {
+		return ref
+	}
+	// Reserve the name first so recursive types terminate
+	s.doc.Components.Schemas[name] = &Schema{}
+	*s.doc.Components.Schemas[name] = *s.structSchema(t)
+	return ref
+}
+
+// structSchema describes the JSON encoding of a struct
+func (s *Spec) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		name, skip := jsonName(field)
+		if skip {
+			continue
+		}
+
+		// Embedded structs without a JSON name are flattened like encoding/json does
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded := s.structSchema(ft)
+				for k, v := range embedded.Properties {
+					schema.Properties[k] = v
+				}
+				schema.Required = append(schema.Required, embedded.Required...)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := s.schemaFor(field.Type)
+		if prop.Ref != "" {
+			// Siblings of $ref are ignored by OpenAPI 3.0, so wrap the reference
+			if doc := field.Tag.Get("doc"); doc != "" {
+				prop = &Schema{Description: doc, AllOf: []*Schema{prop}}
+			}
+		} else {
+			applyFieldTags(prop, field)
+		}
+		schema.Properties[name] = prop
+
+		if isRequired(field) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+// jsonName returns the name from the json struct tag and whether the field is skipped
+func jsonName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ := strings.Cut(tag, ",")
+	return name, false
+}
+
+// isRequired reports whether a field is marked required for binding
+func isRequired(field reflect.StructField) bool {
+	if field.Tag.Get("required") == "true" {
+		return true
+	}
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFieldTags copies documentation and validation tags onto a schema
+func applyFieldTags(schema *Schema, field reflect.StructField) {
+	if doc := field.Tag.Get("doc"); doc != "" {
+		schema.Description = doc
+	}
+	if format := field.Tag.Get("format"); format != "" {
+		schema.Format = format
+	}
+	if example, ok := field.Tag.Lookup("example"); ok {
+		schema.Example = typedValue(schema.Type, example)
+	}
+	if enum := field.Tag.Get("enum"); enum != "" {
+		for _, v := range strings.Split(enum, ",") {
+			schema.Enum = append(schema.Enum, typedValue(schema.Type, v))
+		}
+	}
+
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		key, value, _ := strings.Cut(rule, "=")
+		switch key {
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "oneof":
+			schema.Enum = nil
+			for _, v := range strings.Fields(value) {
+				schema.Enum = append(schema.Enum, typedValue(schema.Type, v))
+			}
+		case "min", "gte":
+			setBound(schema, value, true)
+		case "max", "lte":
+			setBound(schema, value, false)
+		}
+	}
+}
+
+// setBound applies min/max rules as length limits for strings and value
+// limits for numbers
+func setBound(schema *Schema, value string, lower bool) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		length := int(n)
+		if lower {
+			schema.MinLength = &length
+		} else {
+			schema.MaxLength = &length
+		}
+	case "integer", "number":
+		if lower {
+			schema.Minimum = &n
+		} else {
+			schema.Maximum = &n
+		}
+	}
+}
+
+// typedValue converts a tag value to the JSON type of the schema
+func typedValue(schemaType, value string) any {
+	switch schemaType {
+	case "integer":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
@@ -0,0 +1,235 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version is the OpenAPI specification version produced
+const Version = "3.0.3"
+
+// BearerAuth names the JWT security scheme
+const BearerAuth = "bearerAuth"
+
+// pathParam matches Gin :param and *wildcard segments
+var pathParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+// Operation documents one route. Request, Query and response values are
+// example instances of Go types (e.g. RegisterRequest{}); their schemas are
+// derived by reflection.
+type Operation struct {
+	ID          string
+	Summary     string
+	Description string
+	Tags        []string
+	// Request is the JSON request body type
+	Request any
+	// Query is a struct whose `form` tags describe query parameters
+	Query any
+	// Responses maps status codes to body types; nil means no body
+	Responses map[int]any
+	// ContentType overrides application/json for successful responses
+	ContentType string
+	// Auth marks the route as requiring a bearer token
+	Auth bool
+	// Hidden keeps an infrastructure route out of the document while still
+	// counting it as documented
+	Hidden bool
+}
+
+// mediaTyper is implemented by body types with their own media type, such
+// as problem details
+type mediaTyper interface {
+	MediaType() string
+}
+
+// Spec collects documented operations and renders an OpenAPI document
+type Spec struct {
+	mu         sync.Mutex
+	doc        Document
+	documented map[string]bool
+}
+
+// New creates an empty specification
+func New(info Info) *Spec {
+	return &Spec{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    info,
+			Paths:   make(map[string]*PathItem),
+			Components: Components{
+				Schemas: make(map[string]*Schema),
+				SecuritySchemes: map[string]*SecurityScheme{
+					BearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				},
+			},
+		},
+		documented: make(map[string]bool),
+	}
+}
+
+// Add documents the operation served at method and Gin path
+func (s *Spec) Add(method, path string, op Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.documented[routeKey(method, path)] = true
+	if op.Hidden {
+		return
+	}
+
+	openAPIPath := pathParam.ReplaceAllString(path, "{$1}")
+	item, ok := s.doc.Paths[openAPIPath]
+	if !ok {
+		item = &PathItem{}
+		s.doc.Paths[openAPIPath] = item
+	}
+	operation := s.operation(method, path, op)
+	switch method {
+	case http.MethodGet:
+		item.Get = operation
+	case http.MethodPut:
+		item.Put = operation
+	case http.MethodPost:
+		item.Post = operation
+	case http.MethodDelete:
+		item.Delete = operation
+	case http.MethodOptions:
+		item.Options = operation
+	case http.MethodHead:
+		item.Head = operation
+	case http.MethodPatch:
+		item.Patch = operation
+	}
+}
+
+// operation converts an Operation into its OpenAPI form
+func (s *Spec) operation(method, path string, op Operation) *OperationObject {
+	o := &OperationObject{
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Tags:        op.Tags,
+		Responses:   make(map[string]*ResponseObject),
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+		o.Parameters = append(o.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	if op.Query != nil {
+		o.Parameters = append(o.Parameters, s.queryParameters(reflect.TypeOf(op.Query))...)
+	}
+
+	if op.Request != nil {
+		o.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				"application/json": {Schema: s.schemaFor(reflect.TypeOf(op.Request))},
+			},
+		}
+	}
+
+	for status, body := range op.Responses {
+		resp := &ResponseObject{Description: http.StatusText(status)}
+		if body != nil {
+			contentType := "application/json"
+			if mt, ok := body.(mediaTyper); ok {
+				contentType = mt.MediaType()
+			} else if op.ContentType != "" && status < 300 {
+				contentType = op.ContentType
+			}
+			resp.Content = map[string]MediaType{
+				contentType: {Schema: s.schemaFor(reflect.TypeOf(body))},
+			}
+		}
+		o.Responses[strconv.Itoa(status)] = resp
+	}
+	if len(o.Responses) == 0 {
+		o.Responses["200"] = &ResponseObject{Description: http.StatusText(http.StatusOK)}
+	}
+
+	if op.Auth {
+		o.Security = []map[string][]string{{BearerAuth: {}}}
+	}
+	return o
+}
+
+// queryParameters describes the `form`-tagged fields of a query struct
+func (s *Spec) queryParameters(t reflect.Type) []Parameter {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		schema := s.schemaFor(field.Type)
+		applyFieldTags(schema, field)
+		params = append(params, Parameter{
+			Name:        name,
+			In:          "query",
+			Description: schema.Description,
+			Required:    isRequired(field),
+			Schema:      schema,
+		})
+	}
+	return params
+}
+
+// Document returns the generated OpenAPI document
+func (s *Spec) Document() *Document {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc := s.doc
+	return &doc
+}
+
+// Missing returns the registered routes that were never documented
+func (s *Spec) Missing(routes gin.RoutesInfo) []gin.RouteInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var missing []gin.RouteInfo
+	for _, route := range routes {
+		if !s.documented[routeKey(route.Method, route.Path)] {
+			missing = append(missing, route)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		return missing[i].Path+missing[i].Method < missing[j].Path+missing[j].Method
+	})
+	return missing
+}
+
+// Check returns an error listing undocumented routes
+func (s *Spec) Check(routes gin.RoutesInfo) error {
+	missing := s.Missing(routes)
+	if len(missing) == 0 {
+		return nil
+	}
+	lines := make([]string, len(missing))
+	for i, route := range missing {
+		lines[i] = fmt.Sprintf("  %s %s", route.Method, route.Path)
+	}
+	return fmt.Errorf("openapi: %d route(s) missing from the spec:\n%s", len(missing), strings.Join(lines, "\n"))
+}
+
+func routeKey(method, path string) string {
+	return method + " " + path
+}
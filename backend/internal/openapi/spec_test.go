@@ -0,0 +1,209 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type testAddress struct {
+	City string `json:"city" doc:"City name" example:"Innopolis"`
+}
+
+type testUser struct {
+	ID        int64        `json:"id"`
+	Email     string       `json:"email" binding:"required,email"`
+	Name      string       `json:"name" binding:"required,min=2,max=50"`
+	Role      string       `json:"role" binding:"oneof=user admin"`
+	Age       *int         `json:"age,omitempty" binding:"min=0"`
+	Tags      []string     `json:"tags"`
+	CreatedAt time.Time    `json:"created_at"`
+	Address   *testAddress `json:"address,omitempty" doc:"Home address"`
+	Password  string       `json:"-"`
+	testEmbedded
+}
+
+type testEmbedded struct {
+	Version int `json:"version"`
+}
+
+type testListQuery struct {
+	Limit int    `form:"limit" doc:"Page size" binding:"max=100"`
+	Q     string `form:"q"`
+}
+
+func TestSchemaReflection(t *testing.T) {
+	spec := New(Info{Title: "test", Version: "1"})
+	spec.Add(http.MethodPost, "/users", Operation{
+		Request:   testUser{},
+		Responses: map[int]any{http.StatusCreated: testUser{}},
+	})
+
+	doc := spec.Document()
+	user, ok := doc.Components.Schemas["testUser"]
+	if !ok {
+		t.Fatalf("Expected testUser component, got %v", doc.Components.Schemas)
+	}
+
+	if _, ok := user.Properties["Password"]; ok {
+		t.Error("Expected json:\"-\" field to be skipped")
+	}
+	if _, ok := user.Properties["version"]; !ok {
+		t.Error("Expected embedded struct fields to be flattened")
+	}
+	if got := user.Properties["email"].Format; got != "email" {
+		t.Errorf("Expected email format, got %q", got)
+	}
+	if got := user.Properties["created_at"].Format; got != "date-time" {
+		t.Errorf("Expected date-time format, got %q", got)
+	}
+	if name := user.Properties["name"]; name.MinLength == nil || *name.MinLength != 2 || name.MaxLength == nil || *name.MaxLength != 50 {
+		t.Errorf("Expected name length 2..50, got %+v", name)
+	}
+	if got := user.Properties["role"].Enum; len(got) != 2 || got[0] != "user" {
+		t.Errorf("Expected role enum [user admin], got %v", got)
+	}
+	if age := user.Properties["age"]; !age.Nullable || age.Minimum == nil || *age.Minimum != 0 {
+		t.Errorf("Expected nullable age with minimum 0, got %+v", age)
+	}
+	if got := user.Properties["tags"]; got.Type != "array" || got.Items.Type != "string" {
+		t.Errorf("Expected string array, got %+v", got)
+	}
+	if got := strings.Join(user.Required, ","); got != "email,name" {
+		t.Errorf("Expected required email,name, got %q", got)
+	}
+
+	address := user.Properties["address"]
+	if address.Description != "Home address" || len(address.AllOf) != 1 || address.AllOf[0].Ref != "#/components/schemas/testAddress" {
+		t.Errorf("Expected documented reference to testAddress, got %+v", address)
+	}
+	if city := doc.Components.Schemas["testAddress"].Properties["city"]; city.Example != "Innopolis" {
+		t.Errorf("Expected city example, got %v", city.Example)
+	}
+}
+
+func TestOperationParameters(t *testing.T) {
+	spec := New(Info{Title: "test", Version: "1"})
+	spec.Add(http.MethodGet, "/users/:id/files/*path", Operation{
+		Query: testListQuery{},
+		Auth:  true,
+	})
+
+	item, ok := spec.Document().Paths["/users/{id}/files/{path}"]
+	if !ok || item.Get == nil {
+		t.Fatalf("Expected converted path, got %v", spec.Document().Paths)
+	}
+	op := item.Get
+
+	var names []string
+	for _, p := range op.Parameters {
+		names = append(names, p.In+":"+p.Name)
+	}
+	if got := strings.Join(names, ","); got != "path:id,path:path,query:limit,query:q" {
+		t.Errorf("Expected path and query parameters, got %q", got)
+	}
+	if limit := op.Parameters[2]; limit.Description != "Page size" || *limit.Schema.Maximum != 100 {
+		t.Errorf("Expected documented limit with maximum 100, got %+v", limit)
+	}
+	if len(op.Security) != 1 {
+		t.Errorf("Expected bearer security requirement, got %v", op.Security)
+	}
+	if _, ok := op.Responses["200"]; !ok {
+		t.Errorf("Expected default 200 response, got %v", op.Responses)
+	}
+}
+
+func TestRoutesAndMissing(t *testing.T) {
+	router := gin.New()
+	spec := New(Info{Title: "test", Version: "1"})
+	routes := spec.Wrap(&router.RouterGroup)
+
+	api := routes.Group("/api/v1").WithTags("users")
+	api.GET("/users/:id", Operation{Summary: "Get user"}, func(c *gin.Context) {})
+	api.WithAuth().DELETE("/users/:id", Operation{}, func(c *gin.Context) {})
+	routes.GET("/internal", Operation{Hidden: true}, func(c *gin.Context) {})
+	router.GET("/undocumented", func(c *gin.Context) {})
+
+	doc := spec.Document()
+	item := doc.Paths["/api/v1/users/{id}"]
+	if item == nil || item.Get == nil || item.Delete == nil {
+		t.Fatalf("Expected GET and DELETE on /api/v1/users/{id}, got %+v", item)
+	}
+	if got := item.Get.Tags; len(got) != 1 || got[0] != "users" {
+		t.Errorf("Expected inherited tags, got %v", got)
+	}
+	if item.Get.Security != nil || item.Delete.Security == nil {
+		t.Error("Expected only the DELETE operation to require auth")
+	}
+	if _, ok := doc.Paths["/internal"]; ok {
+		t.Error("Expected hidden route to be left out of the document")
+	}
+
+	missing := spec.Missing(router.Routes())
+	if len(missing) != 1 || missing[0].Path != "/undocumented" {
+		t.Errorf("Expected only /undocumented to be missing, got %v", missing)
+	}
+	if err := spec.Check(router.Routes()); err == nil || !strings.Contains(err.Error(), "GET /undocumented") {
+		t.Errorf("Expected Check to list the undocumented route, got %v", err)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	spec := New(Info{Title: "test", Version: "1"})
+	router := gin.New()
+	routes := spec.Wrap(&router.RouterGroup)
+	routes.GET("/openapi.json", Operation{Hidden: true}, spec.JSONHandler())
+	routes.GET("/docs", Operation{Hidden: true}, UIHandler("<API>", "/openapi.json", "/docs/assets/"))
+	routes.GET("/docs/assets/:file", Operation{Hidden: true}, AssetsHandler())
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var doc Document
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Expected JSON document, got %v", err)
+	}
+	if doc.OpenAPI != Version {
+		t.Errorf("Expected openapi %s, got %s", Version, doc.OpenAPI)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	body := w.Body.String()
+	if !strings.Contains(body, "&lt;API&gt;") || !strings.Contains(body, "/openapi.json") {
+		t.Errorf("Expected escaped title and spec URL in page, got %q", body)
+	}
+	if strings.Contains(body, "https://") {
+		t.Errorf("Expected the page to load nothing from other origins, got %q", body)
+	}
+
+	// The page loads Swagger UI from the embedded copy
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{"/docs/assets/swagger-ui.css", http.StatusOK, "text/css"},
+		{"/docs/assets/swagger-ui-bundle.js", http.StatusOK, "javascript"},
+		{"/docs/assets/README.md", http.StatusNotFound, ""},
+		{"/docs/assets/..", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		if tt.status == http.StatusOK && !strings.Contains(body, tt.path) {
+			t.Errorf("Expected the page to load %s, got %q", tt.path, body)
+		}
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status || !strings.Contains(w.Header().Get("Content-Type"), tt.contentType) {
+			t.Errorf("%s: expected %d %s, got %d %s", tt.path, tt.status, tt.contentType, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in process memory. Buckets that have been idle
+// for longer than the idle timeout and have refilled are evicted during
+// periodic sweeps, so memory is bounded by the number of active clients.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	idle      time.Duration
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	Bucket
+	fullAt time.Time
+}
+
+// NewMemoryStore creates an in-memory store evicting buckets idle for
+// longer than idle
+func NewMemoryStore(idle time.Duration) *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*memoryBucket),
+		idle:    idle,
+		now:     time.Now,
+	}
+}
+
+// Take takes a token from the bucket stored under key
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.lastSweep) >= s.idle {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{}
+		s.buckets[key] = b
+	}
+	result := limit.Take(&b.Bucket, now)
+	b.fullAt = limit.FullAt(b.Bucket)
+	return result, nil
+}
+
+// Len returns the number of buckets held
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+// sweep drops buckets that are full again and were idle long enough
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if now.Sub(b.Updated) >= s.idle && !now.Before(b.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,90 @@
+// Package ratelimit implements token-bucket rate limiting with pluggable
+// bucket storage.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit describes a token bucket: it holds at most Burst tokens and refills
+// at Rate tokens per second. Each request takes one token.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Every returns a limit allowing n requests per interval with the given burst.
+// A burst below one defaults to n.
+func Every(n int, interval time.Duration, burst int) Limit {
+	if burst < 1 {
+		burst = n
+	}
+	return Limit{Rate: float64(n) / interval.Seconds(), Burst: burst}
+}
+
+// Result is the outcome of taking a token
+type Result struct {
+	Allowed bool
+	// Limit is the bucket capacity
+	Limit int
+	// Remaining is the number of whole tokens left
+	Remaining int
+	// RetryAfter is how long until a token is available; zero when allowed
+	RetryAfter time.Duration
+	// ResetAfter is how long until the bucket is full again
+	ResetAfter time.Duration
+}
+
+// Store keeps buckets by key. Implementations must be safe for concurrent
+// use; a shared store (e.g. Redis) lets several replicas enforce one limit.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Bucket is the persisted state of one token bucket
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Take refills b up to now and takes one token if available. A zero bucket
+// starts full. Stores call it while holding the bucket exclusively.
+func (l Limit) Take(b *Bucket, now time.Time) Result {
+	burst := float64(l.Burst)
+	if b.Updated.IsZero() {
+		b.Tokens = burst
+	} else if elapsed := now.Sub(b.Updated).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(burst, b.Tokens+elapsed*l.Rate)
+	}
+	b.Updated = now
+
+	result := Result{Limit: l.Burst}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.durationFor(1 - b.Tokens)
+	}
+	result.Remaining = int(b.Tokens)
+	result.ResetAfter = l.durationFor(burst - b.Tokens)
+	return result
+}
+
+// FullAt returns when b will be full again, after which it is
+// indistinguishable from a new bucket and may be dropped
+func (l Limit) FullAt(b Bucket) time.Time {
+	return b.Updated.Add(l.durationFor(float64(l.Burst) - b.Tokens))
+}
+
+// durationFor returns how long refilling the given number of tokens takes
+func (l Limit) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	if l.Rate <= 0 {
+		return math.MaxInt64
+	}
+	return time.Duration(math.Ceil(tokens / l.Rate * float64(time.Second)))
+}
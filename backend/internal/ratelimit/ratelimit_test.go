@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimitTake(t *testing.T) {
+	limit := Every(2, time.Second, 3)
+	start := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	var b Bucket
+
+	for i := 0; i < 3; i++ {
+		if r := limit.Take(&b, start); !r.Allowed || r.Remaining != 2-i {
+			t.Fatalf("Request %d: expected allowed with %d remaining, got %+v", i, 2-i, r)
+		}
+	}
+
+	r := limit.Take(&b, start)
+	if r.Allowed {
+		t.Fatal("Expected empty bucket to deny")
+	}
+	if r.RetryAfter != 500*time.Millisecond {
+		t.Errorf("Expected retry after 500ms, got %v", r.RetryAfter)
+	}
+	if r.ResetAfter != 1500*time.Millisecond {
+		t.Errorf("Expected reset after 1.5s, got %v", r.ResetAfter)
+	}
+	if r.Limit != 3 {
+		t.Errorf("Expected limit 3, got %d", r.Limit)
+	}
+
+	if r := limit.Take(&b, start.Add(500*time.Millisecond)); !r.Allowed || r.Remaining != 0 {
+		t.Errorf("Expected refilled token to be allowed, got %+v", r)
+	}
+	if r := limit.Take(&b, start.Add(time.Hour)); !r.Allowed || r.Remaining != 2 {
+		t.Errorf("Expected refill to stop at burst, got %+v", r)
+	}
+}
+
+func TestEveryDefaultsBurst(t *testing.T) {
+	limit := Every(60, time.Minute, 0)
+	if limit.Burst != 60 || limit.Rate != 1 {
+		t.Errorf("Expected 1/s with burst 60, got %+v", limit)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(time.Minute)
+	store.now = func() time.Time { return now }
+	limit := Every(1, time.Second, 1)
+	ctx := context.Background()
+
+	if r, _ := store.Take(ctx, "a", limit); !r.Allowed {
+		t.Fatal("Expected first request to be allowed")
+	}
+	if r, _ := store.Take(ctx, "a", limit); r.Allowed {
+		t.Fatal("Expected second request to be denied")
+	}
+	if r, _ := store.Take(ctx, "b", limit); !r.Allowed {
+		t.Fatal("Expected keys to have separate buckets")
+	}
+	if store.Len() != 2 {
+		t.Fatalf("Expected 2 buckets, got %d", store.Len())
+	}
+
+	now = now.Add(2 * time.Minute)
+	store.Take(ctx, "c", limit)
+	if store.Len() != 1 {
+		t.Errorf("Expected idle buckets to be evicted, got %d buckets", store.Len())
+	}
+}
+
+func TestMemoryStoreKeepsRefillingBuckets(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore(time.Second)
+	store.now = func() time.Time { return now }
+	slow := Every(1, time.Hour, 5)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		store.Take(ctx, "a", slow)
+	}
+	now = now.Add(time.Minute)
+	store.Take(ctx, "b", slow)
+	if r, _ := store.Take(ctx, "a", slow); r.Allowed {
+		t.Error("Expected an idle but still draining bucket to survive eviction")
+	}
+}
+
+func TestMemoryStoreConcurrent(t *testing.T) {
+	store := NewMemoryStore(time.Minute)
+	limit := Every(1, time.Hour, 50)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, _ := store.Take(context.Background(), "shared", limit)
+			if r.Allowed {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 50 {
+		t.Errorf("Expected exactly 50 allowed requests, got %d", allowed)
+	}
+}
@@ -0,0 +1,91 @@
+// Package series combines metric rollups into hourly, daily, weekly or
+// monthly buckets of a time zone.
+package series
+
+import (
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+)
+
+// Bucket sizes
+const (
+	Hour  = "hour"
+	Day   = "day"
+	Week  = "week"
+	Month = "month"
+)
+
+// Point aggregates the measurements of one bucket
+type Point struct {
+	Start time.Time `json:"start" doc:"start of the bucket in the user's time zone"`
+	Count int64     `json:"count"`
+	Sum   float64   `json:"sum"`
+	Avg   float64   `json:"avg"`
+	Min   float64   `json:"min"`
+	Max   float64   `json:"max"`
+}
+
+// Floor returns the start of the bucket containing t in loc. Weeks start on
+// Monday.
+func Floor(t time.Time, bucket string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch bucket {
+	case Hour:
+		// Subtracting keeps both repeated hours of a DST change distinct
+		return t.Add(-time.Duration(t.Minute())*time.Minute - time.Duration(t.Second())*time.Second - time.Duration(t.Nanosecond()))
+	case Week:
+		y, m, d := t.Date()
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	case Month:
+		y, m, _ := t.Date()
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	default:
+		y, m, d := t.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+}
+
+// Next returns the start of the bucket following the one that starts at start
+func Next(start time.Time, bucket string) time.Time {
+	switch bucket {
+	case Hour:
+		return start.Add(time.Hour)
+	case Week:
+		return start.AddDate(0, 0, 7)
+	case Month:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Aggregate combines rollups, sorted by start, into buckets of loc. Buckets
+// without measurements are omitted.
+func Aggregate(rollups []models.Rollup, bucket string, loc *time.Location) []Point {
+	points := []Point{}
+	var current models.Rollup
+	var start time.Time
+	flush := func() {
+		if current.Count == 0 {
+			return
+		}
+		points = append(points, Point{
+			Start: start,
+			Count: current.Count,
+			Sum:   current.Sum,
+			Avg:   current.Sum / float64(current.Count),
+			Min:   current.Min,
+			Max:   current.Max,
+		})
+	}
+	for _, r := range rollups {
+		if s := Floor(r.Start, bucket, loc); !s.Equal(start) {
+			flush()
+			current, start = models.Rollup{}, s
+		}
+		current.Add(r)
+	}
+	flush()
+	return points
+}
@@ -0,0 +1,81 @@
+package series
+
+import (
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q) failed: %v", name, err)
+	}
+	return loc
+}
+
+func TestFloor(t *testing.T) {
+	kolkata := mustLoad(t, "Asia/Kolkata")
+	berlin := mustLoad(t, "Europe/Berlin")
+	// 2025-03-12 is a Wednesday
+	at := time.Date(2025, 3, 12, 20, 40, 0, 0, time.UTC)
+
+	tests := []struct {
+		bucket string
+		loc    *time.Location
+		want   time.Time
+	}{
+		{Hour, kolkata, time.Date(2025, 3, 13, 2, 0, 0, 0, kolkata)},
+		{Day, kolkata, time.Date(2025, 3, 13, 0, 0, 0, 0, kolkata)},
+		{Day, berlin, time.Date(2025, 3, 12, 0, 0, 0, 0, berlin)},
+		{Week, berlin, time.Date(2025, 3, 10, 0, 0, 0, 0, berlin)},
+		{Month, berlin, time.Date(2025, 3, 1, 0, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.bucket+" "+tt.loc.String(), func(t *testing.T) {
+			if got := Floor(at, tt.bucket, tt.loc); !got.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNextAcrossDST(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	// Clocks go forward on 30 March 2025, so that day has 23 hours
+	day := time.Date(2025, 3, 30, 0, 0, 0, 0, berlin)
+	if got := Next(day, Day); got.Sub(day) != 23*time.Hour {
+		t.Errorf("Expected a 23 hour day, got %v", got.Sub(day))
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	kolkata := mustLoad(t, "Asia/Kolkata")
+	// Midnight in Kolkata is 18:30 UTC, between two hourly UTC boundaries
+	rollups := []models.Rollup{
+		{Start: time.Date(2025, 3, 10, 18, 0, 0, 0, time.UTC), Count: 2, Sum: 10, Min: 4, Max: 6},
+		{Start: time.Date(2025, 3, 10, 18, 30, 0, 0, time.UTC), Count: 1, Sum: 3, Min: 3, Max: 3},
+		{Start: time.Date(2025, 3, 10, 23, 45, 0, 0, time.UTC), Count: 1, Sum: 9, Min: 9, Max: 9},
+	}
+
+	got := Aggregate(rollups, Day, kolkata)
+	want := []Point{
+		{Start: time.Date(2025, 3, 10, 0, 0, 0, 0, kolkata), Count: 2, Sum: 10, Avg: 5, Min: 4, Max: 6},
+		{Start: time.Date(2025, 3, 11, 0, 0, 0, 0, kolkata), Count: 2, Sum: 12, Avg: 6, Min: 3, Max: 9},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d points, got %+v", len(want), got)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || got[i].Count != want[i].Count || got[i].Sum != want[i].Sum ||
+			got[i].Avg != want[i].Avg || got[i].Min != want[i].Min || got[i].Max != want[i].Max {
+			t.Errorf("Expected %+v, got %+v", want[i], got[i])
+		}
+	}
+
+	if got := Aggregate(nil, Hour, kolkata); len(got) != 0 {
+		t.Errorf("Expected no points, got %+v", got)
+	}
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+)
+
+const habitColumns = "id, user_id, name, description, schedule_kind, times_per_week, weekdays, start_date, archived, created_at, updated_at"
+
+// CreateHabit inserts a new habit
+func (s *sqlStore) CreateHabit(ctx context.Context, h *models.Habit) error {
+	now := s.now().UTC()
+	id, err := s.insert(ctx,
+		`INSERT INTO habits (user_id, name, description, schedule_kind, times_per_week, weekdays, start_date, archived, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		h.UserID, h.Name, h.Description, h.Schedule.Kind, h.Schedule.TimesPerWeek, strings.Join(h.Schedule.Weekdays, " "),
+		h.StartDate, h.Archived, now, now)
+	if err != nil {
+		return fmt.Errorf("store: create habit: %w", err)
+	}
+	h.ID, h.CreatedAt, h.UpdatedAt = id, now, now
+	return nil
+}
+
+// GetHabit returns the user's habit with the given ID
+func (s *sqlStore) GetHabit(ctx context.Context, userID, id int64) (*models.Habit, error) {
+	rows, err := s.db.QueryContext(ctx, s.q("SELECT "+habitColumns+" FROM habits WHERE id = ? AND user_id = ?"), id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: get habit: %w", err)
+	}
+	habits, err := scanHabits(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(habits) == 0 {
+		return nil, ErrNotFound
+	}
+	return habits[0], nil
+}
+
+// ListHabits returns the user's habits, oldest first
+func (s *sqlStore) ListHabits(ctx context.Context, userID int64, includeArchived bool) ([]*models.Habit, error) {
+	query := "SELECT " + habitColumns + " FROM habits WHERE user_id = ?"
+	if !includeArchived {
+		query += " AND NOT archived"
+	}
+	rows, err := s.db.QueryContext(ctx, s.q(query+" ORDER BY id"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("store: list habits: %w", err)
+	}
+	return scanHabits(rows)
+}
+
+// UpdateHabit saves changes to an existing habit
+func (s *sqlStore) UpdateHabit(ctx context.Context, h *models.Habit) error {
+	now := s.now().UTC()
+	res, err := s.db.ExecContext(ctx,
+		s.q(`UPDATE habits SET name = ?, description = ?, schedule_kind = ?, times_per_week = ?, weekdays = ?,
+		start_date = ?, archived = ?, updated_at = ? WHERE id = ? AND user_id = ?`),
+		h.Name, h.Description, h.Schedule.Kind, h.Schedule.TimesPerWeek, strings.Join(h.Schedule.Weekdays, " "),
+		h.StartDate, h.Archived, now, h.ID, h.UserID)
+	if err != nil {
+		return fmt.Errorf("store: update habit: %w", err)
+	}
+	if err := expectRow(res); err != nil {
+		return err
+	}
+	h.UpdatedAt = now
+	return nil
+}
+
+// DeleteHabit removes a habit; check-ins cascade
+func (s *sqlStore) DeleteHabit(ctx context.Context, userID, id int64) error {
+	res, err := s.db.ExecContext(ctx, s.q("DELETE FROM habits WHERE id = ? AND user_id = ?"), id, userID)
+	if err != nil {
+		return fmt.Errorf("store: delete habit: %w", err)
+	}
+	return expectRow(res)
+}
+
+// CreateCheckIn records a habit check-in
+func (s *sqlStore) CreateCheckIn(ctx context.Context, c *models.CheckIn) error {
+	now := s.now().UTC()
+	_, err := s.db.ExecContext(ctx,
+		s.q("INSERT INTO habit_checkins (habit_id, date, note, created_at) VALUES (?, ?, ?, ?)"),
+		c.HabitID, c.Date, c.Note, now)
+	if err != nil {
+		if s.isUniqueViolation(err) {
+			return fmt.Errorf("%w: already checked in on %s", ErrConflict, c.Date)
+		}
+		return fmt.Errorf("store: create check-in: %w", err)
+	}
+	c.CreatedAt = now
+	return nil
+}
+
+// DeleteCheckIn removes the check-in of a habit on date
+func (s *sqlStore) DeleteCheckIn(ctx context.Context, habitID int64, date models.Date) error {
+	res, err := s.db.ExecContext(ctx, s.q("DELETE FROM habit_checkins WHERE habit_id = ? AND date = ?"), habitID, date)
+	if err != nil {
+		return fmt.Errorf("store: delete check-in: %w", err)
+	}
+	return expectRow(res)
+}
+
+// ListCheckIns returns a habit's check-ins in a date range
+func (s *sqlStore) ListCheckIns(ctx context.Context, habitID int64, from, to models.Date) ([]*models.CheckIn, error) {
+	rows, err := s.db.QueryContext(ctx,
+		s.q("SELECT habit_id, date, note, created_at FROM habit_checkins WHERE habit_id = ? AND date >= ? AND date <= ? ORDER BY date"),
+		habitID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("store: list check-ins: %w", err)
+	}
+	defer rows.Close()
+
+	var checkIns []*models.CheckIn
+	for rows.Next() {
+		var c models.CheckIn
+		if err := rows.Scan(&c.HabitID, &c.Date, &c.Note, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("store: scan check-in: %w", err)
+		}
+		c.CreatedAt = c.CreatedAt.UTC()
+		checkIns = append(checkIns, &c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: list check-ins: %w", err)
+	}
+	return checkIns, nil
+}
+
+// scanHabits reads rows selected with habitColumns and closes them
+func scanHabits(rows *sql.Rows) ([]*models.Habit, error) {
+	defer rows.Close()
+	var habits []*models.Habit
+	for rows.Next() {
+		var h models.Habit
+		var weekdays string
+		err := rows.Scan(&h.ID, &h.UserID, &h.Name, &h.Description, &h.Schedule.Kind, &h.Schedule.TimesPerWeek,
+			&weekdays, &h.StartDate, &h.Archived, &h.CreatedAt, &h.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("store: scan habit: %w", err)
+		}
+		h.Schedule.Weekdays = strings.Fields(weekdays)
+		h.CreatedAt, h.UpdatedAt = h.CreatedAt.UTC(), h.UpdatedAt.UTC()
+		habits = append(habits, &h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: scan habits: %w", err)
+	}
+	return habits, nil
+}
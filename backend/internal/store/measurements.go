@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+)
+
+// AddMeasurements inserts new measurements and updates their rollups
+func (s *sqlStore) AddMeasurements(ctx context.Context, userID int64, ms []*models.Measurement) (int, error) {
+	now := s.now().UTC()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("store: add measurements: %w", err)
+	}
+	defer tx.Rollback()
+
+	insert := s.q(`INSERT INTO measurements (user_id, client_id, metric, value, recorded_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?) ON CONFLICT (user_id, client_id) DO NOTHING`)
+	type bucket struct {
+		metric string
+		start  time.Time
+	}
+	deltas := make(map[bucket]*models.Rollup)
+	inserted := 0
+	for _, m := range ms {
+		recordedAt := m.RecordedAt.UTC()
+		res, err := tx.ExecContext(ctx, insert, userID, m.ClientID, m.Metric, m.Value, recordedAt, now)
+		if err != nil {
+			return 0, fmt.Errorf("store: add measurement: %w", err)
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return 0, fmt.Errorf("store: add measurement: %w", err)
+		} else if n == 0 {
+			continue
+		}
+		m.UserID, m.CreatedAt = userID, now
+		inserted++
+
+		key := bucket{metric: m.Metric, start: recordedAt.Truncate(models.RollupInterval)}
+		if deltas[key] == nil {
+			deltas[key] = &models.Rollup{Metric: key.metric, Start: key.start}
+		}
+		deltas[key].Add(models.Rollup{Count: 1, Sum: m.Value, Min: m.Value, Max: m.Value})
+	}
+
+	least, greatest := "MIN", "MAX"
+	if s.dialect == database.Postgres {
+		least, greatest = "LEAST", "GREATEST"
+	}
+	upsert := s.q(`INSERT INTO metric_rollups (user_id, metric, bucket_start, value_count, value_sum, value_min, value_max)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, metric, bucket_start) DO UPDATE SET
+			value_count = metric_rollups.value_count + excluded.value_count,
+			value_sum = metric_rollups.value_sum + excluded.value_sum,
+			value_min = ` + least + `(metric_rollups.value_min, excluded.value_min),
+			value_max = ` + greatest + `(metric_rollups.value_max, excluded.value_max)`)
+
+	// A fixed order keeps concurrent batches from deadlocking on Postgres
+	rollups := make([]*models.Rollup, 0, len(deltas))
+	for _, r := range deltas {
+		rollups = append(rollups, r)
+	}
+	slices.SortFunc(rollups, func(a, b *models.Rollup) int {
+		if c := a.Start.Compare(b.Start); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Metric, b.Metric)
+	})
+	for _, r := range rollups {
+		if _, err := tx.ExecContext(ctx, upsert, userID, r.Metric, r.Start, r.Count, r.Sum, r.Min, r.Max); err != nil {
+			return 0, fmt.Errorf("store: update rollup: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("store: add measurements: %w", err)
+	}
+	return inserted, nil
+}
+
+// ListRollups returns the rollups of a metric in a time range
+func (s *sqlStore) ListRollups(ctx context.Context, userID int64, metric string, from, to time.Time) ([]models.Rollup, error) {
+	rows, err := s.db.QueryContext(ctx, s.q(`SELECT bucket_start, value_count, value_sum, value_min, value_max
+		FROM metric_rollups WHERE user_id = ? AND metric = ? AND bucket_start >= ? AND bucket_start < ?
+		ORDER BY bucket_start`), userID, metric, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("store: list rollups: %w", err)
+	}
+	defer rows.Close()
+
+	var rollups []models.Rollup
+	for rows.Next() {
+		r := models.Rollup{Metric: metric}
+		if err := rows.Scan(&r.Start, &r.Count, &r.Sum, &r.Min, &r.Max); err != nil {
+			return nil, fmt.Errorf("store: scan rollup: %w", err)
+		}
+		r.Start = r.Start.UTC()
+		rollups = append(rollups, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: list rollups: %w", err)
+	}
+	return rollups, nil
+}
@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// uniqueViolation is the Postgres SQLSTATE for unique constraint failures
+const uniqueViolation = "23505"
+
+// Postgres stores models in PostgreSQL
+type Postgres struct {
+	sqlStore
+}
+
+// NewPostgres creates a store on a Postgres connection pool
+func NewPostgres(db *sql.DB) *Postgres {
+	s := &Postgres{}
+	s.sqlStore = sqlStore{
+		db:      db,
+		dialect: database.Postgres,
+		insert: func(ctx context.Context, query string, args ...any) (int64, error) {
+			var id int64
+			err := db.QueryRowContext(ctx, database.Postgres.Rebind(query)+" RETURNING id", args...).Scan(&id)
+			return id, err
+		},
+		isUniqueViolation: func(err error) bool {
+			var pqErr *pq.Error
+			return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+		},
+		now: time.Now,
+	}
+	return s
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+)
+
+// sqlStore holds the queries shared by the Postgres and SQLite stores.
+// Queries use ? placeholders and are rebound for the dialect.
+type sqlStore struct {
+	db      *sql.DB
+	dialect database.Dialect
+	// insert runs an INSERT and returns the generated id
+	insert func(ctx context.Context, query string, args ...any) (int64, error)
+	// isUniqueViolation reports whether err is a unique constraint failure
+	isUniqueViolation func(err error) bool
+	now               func() time.Time
+}
+
+const userColumns = "id, email, name, password_hash, roles, timezone, disabled_at, created_at, updated_at"
+
+func (s *sqlStore) q(query string) string {
+	return s.dialect.Rebind(query)
+}
+
+// CreateUser inserts a new user
+func (s *sqlStore) CreateUser(ctx context.Context, u *models.User) error {
+	now := s.now().UTC()
+	if u.TimeZone == "" {
+		u.TimeZone = "UTC"
+	}
+	id, err := s.insert(ctx,
+		"INSERT INTO users (email, name, password_hash, roles, timezone, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		u.Email, u.Name, u.PasswordHash, strings.Join(u.Roles, " "), u.TimeZone, now, now)
+	if err != nil {
+		if s.isUniqueViolation(err) {
+			return fmt.Errorf("%w: email %q is already registered", ErrConflict, u.Email)
+		}
+		return fmt.Errorf("store: create user: %w", err)
+	}
+	u.ID, u.CreatedAt, u.UpdatedAt = id, now, now
+	return nil
+}
+
+// GetUser returns the user with the given ID
+func (s *sqlStore) GetUser(ctx context.Context, id int64) (*models.User, error) {
+	row := s.db.QueryRowContext(ctx, s.q("SELECT "+userColumns+" FROM users WHERE id = ?"), id)
+	return scanUser(row)
+}
+
+// GetUserByEmail returns the user registered with email
+func (s *sqlStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	row := s.db.QueryRowContext(ctx, s.q("SELECT "+userColumns+" FROM users WHERE email = ?"), email)
+	return scanUser(row)
+}
+
+// UpdateUser saves changes to an existing user
+func (s *sqlStore) UpdateUser(ctx context.Context, u *models.User) error {
+	now := s.now().UTC()
+	var disabledAt any
+	if u.DisabledAt != nil {
+		disabledAt = u.DisabledAt.UTC()
+	}
+	res, err := s.db.ExecContext(ctx,
+		s.q("UPDATE users SET email = ?, name = ?, password_hash = ?, roles = ?, timezone = ?, disabled_at = ?, updated_at = ? WHERE id = ?"),
+		u.Email, u.Name, u.PasswordHash, strings.Join(u.Roles, " "), u.TimeZone, disabledAt, now, u.ID)
+	if err != nil {
+		if s.isUniqueViolation(err) {
+			return fmt.Errorf("%w: email %q is already registered", ErrConflict, u.Email)
+		}
+		return fmt.Errorf("store: update user: %w", err)
+	}
+	if err := expectRow(res); err != nil {
+		return err
+	}
+	u.UpdatedAt = now
+	return nil
+}
+
+// ListUsers returns every user ordered by ID
+func (s *sqlStore) ListUsers(ctx context.Context) ([]*models.User, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("store: list users: %w", err)
+	}
+	defer rows.Close()
+	var users []*models.User
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("store: list users: %w", err)
+	}
+	return users, nil
+}
+
+// DeleteUser removes a user; refresh tokens cascade
+func (s *sqlStore) DeleteUser(ctx context.Context, id int64) error {
+	res, err := s.db.ExecContext(ctx, s.q("DELETE FROM users WHERE id = ?"), id)
+	if err != nil {
+		return fmt.Errorf("store: delete user: %w", err)
+	}
+	return expectRow(res)
+}
+
+// CreateRefreshToken stores a hashed refresh token
+func (s *sqlStore) CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error {
+	now := s.now().UTC()
+	id, err := s.insert(ctx,
+		"INSERT INTO refresh_tokens (user_id, token_hash, expires_at, created_at) VALUES (?, ?, ?, ?)",
+		t.UserID, t.TokenHash, t.ExpiresAt.UTC(), now)
+	if err != nil {
+		return fmt.Errorf("store: create refresh token: %w", err)
+	}
+	t.ID, t.CreatedAt = id, now
+	return nil
+}
+
+// GetRefreshToken looks a refresh token up by its hash
+func (s *sqlStore) GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var t models.RefreshToken
+	var revokedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx,
+		s.q("SELECT id, user_id, token_hash, expires_at, revoked_at, created_at FROM refresh_tokens WHERE token_hash = ?"),
+		tokenHash).Scan(&t.ID, &t.UserID, &t.TokenHash, &t.ExpiresAt, &revokedAt, &t.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: get refresh token: %w", err)
+	}
+	t.ExpiresAt, t.CreatedAt = t.ExpiresAt.UTC(), t.CreatedAt.UTC()
+	if revokedAt.Valid {
+		at := revokedAt.Time.UTC()
+		t.RevokedAt = &at
+	}
+	return &t, nil
+}
+
+// RevokeRefreshToken revokes a token unless it already was
+func (s *sqlStore) RevokeRefreshToken(ctx context.Context, id int64, at time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx,
+		s.q("UPDATE refresh_tokens SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL"), at.UTC(), id)
+	if err != nil {
+		return false, fmt.Errorf("store: revoke refresh token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("store: revoke refresh token: %w", err)
+	}
+	return n == 1, nil
+}
+
+// RevokeUserRefreshTokens revokes every active token of a user
+func (s *sqlStore) RevokeUserRefreshTokens(ctx context.Context, userID int64, at time.Time) error {
+	_, err := s.db.ExecContext(ctx,
+		s.q("UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL"), at.UTC(), userID)
+	if err != nil {
+		return fmt.Errorf("store: revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredRefreshTokens removes tokens that expired before the given time
+func (s *sqlStore) DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.q("DELETE FROM refresh_tokens WHERE expires_at < ?"), before.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("store: delete expired refresh tokens: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("store: delete expired refresh tokens: %w", err)
+	}
+	return n, nil
+}
+
+// scanner is implemented by *sql.Row and *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanUser reads a row selected with userColumns
+func scanUser(row scanner) (*models.User, error) {
+	var u models.User
+	var roles string
+	var disabledAt sql.NullTime
+	err := row.Scan(&u.ID, &u.Email, &u.Name, &u.PasswordHash, &roles, &u.TimeZone, &disabledAt, &u.CreatedAt, &u.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("store: scan user: %w", err)
+	}
+	u.Roles = strings.Fields(roles)
+	u.CreatedAt, u.UpdatedAt = u.CreatedAt.UTC(), u.UpdatedAt.UTC()
+	if disabledAt.Valid {
+		t := disabledAt.Time.UTC()
+		u.DisabledAt = &t
+	}
+	return &u, nil
+}
+
+// expectRow returns ErrNotFound when a statement affected no rows
+func expectRow(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("store: rows affected: %w", err)
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
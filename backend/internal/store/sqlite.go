@@ -0,0 +1,38 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+)
+
+// SQLite stores models in an SQLite database
+type SQLite struct {
+	sqlStore
+}
+
+// NewSQLite creates a store on an SQLite connection pool
+func NewSQLite(db *sql.DB) *SQLite {
+	s := &SQLite{}
+	s.sqlStore = sqlStore{
+		db:      db,
+		dialect: database.SQLite,
+		insert: func(ctx context.Context, query string, args ...any) (int64, error) {
+			res, err := db.ExecContext(ctx, query, args...)
+			if err != nil {
+				return 0, err
+			}
+			return res.LastInsertId()
+		},
+		// Matched by message: the sqlite3.Error type only exists in cgo
+		// builds, and the server binary is built without cgo
+		isUniqueViolation: func(err error) bool {
+			return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+		},
+		now: time.Now,
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+// Package store persists domain models in Postgres or SQLite.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+)
+
+// Predefined errors
+var (
+	ErrNotFound = errors.New("store: not found")
+	ErrConflict = errors.New("store: conflict")
+)
+
+// UserStore persists user accounts. Emails are compared as stored, so
+// callers normalise them first.
+type UserStore interface {
+	// CreateUser inserts u and sets its ID and timestamps; a taken email
+	// returns ErrConflict
+	CreateUser(ctx context.Context, u *models.User) error
+	GetUser(ctx context.Context, id int64) (*models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	// ListUsers returns every user ordered by ID
+	ListUsers(ctx context.Context) ([]*models.User, error)
+	// UpdateUser saves the email, name, password hash, roles, time zone and
+	// disabled time of u
+	UpdateUser(ctx context.Context, u *models.User) error
+	// DeleteUser removes the user together with its refresh tokens
+	DeleteUser(ctx context.Context, id int64) error
+}
+
+// RefreshTokenStore persists hashed refresh tokens
+type RefreshTokenStore interface {
+	CreateRefreshToken(ctx context.Context, t *models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	// RevokeRefreshToken marks the token revoked and reports whether this
+	// call revoked it, so concurrent rotations of one token cannot both win
+	RevokeRefreshToken(ctx context.Context, id int64, at time.Time) (bool, error)
+	RevokeUserRefreshTokens(ctx context.Context, userID int64, at time.Time) error
+	// DeleteExpiredRefreshTokens removes tokens that expired before the given
+	// time and returns how many were removed
+	DeleteExpiredRefreshTokens(ctx context.Context, before time.Time) (int64, error)
+}
+
+// HabitStore persists habits and their check-ins. Habits are looked up by
+// owner so one user cannot reach another's habits.
+type HabitStore interface {
+	// CreateHabit inserts h and sets its ID and timestamps
+	CreateHabit(ctx context.Context, h *models.Habit) error
+	GetHabit(ctx context.Context, userID, id int64) (*models.Habit, error)
+	ListHabits(ctx context.Context, userID int64, includeArchived bool) ([]*models.Habit, error)
+	// UpdateHabit saves the name, description, schedule, start date and
+	// archived flag of h
+	UpdateHabit(ctx context.Context, h *models.Habit) error
+	// DeleteHabit removes the habit together with its check-ins
+	DeleteHabit(ctx context.Context, userID, id int64) error
+	// CreateCheckIn records a check-in; a second one for the same day
+	// returns ErrConflict
+	CreateCheckIn(ctx context.Context, c *models.CheckIn) error
+	DeleteCheckIn(ctx context.Context, habitID int64, date models.Date) error
+	// ListCheckIns returns the check-ins between from and to inclusive,
+	// oldest first
+	ListCheckIns(ctx context.Context, habitID int64, from, to models.Date) ([]*models.CheckIn, error)
+}
+
+// MeasurementStore persists wellness measurements together with rollups
+// per RollupInterval, so series are read without scanning raw rows
+type MeasurementStore interface {
+	// AddMeasurements inserts measurements, skipping those whose client ID
+	// the user already sent, and folds the new ones into the rollups in the
+	// same transaction. It returns how many were inserted.
+	AddMeasurements(ctx context.Context, userID int64, ms []*models.Measurement) (int, error)
+	// ListRollups returns the user's rollups of a metric starting within
+	// [from, to), oldest first
+	ListRollups(ctx context.Context, userID int64, metric string, from, to time.Time) ([]models.Rollup, error)
+}
+
+// Store combines every store interface
+type Store interface {
+	UserStore
+	RefreshTokenStore
+	HabitStore
+	MeasurementStore
+}
+
+// New returns the store implementation for the connection's dialect
+func New(db *database.DB) Store {
+	if db.Dialect == database.Postgres {
+		return NewPostgres(db.DB)
+	}
+	return NewSQLite(db.DB)
+}
@@ -0,0 +1,298 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/database/dbtest"
+	"github.com/timur-harin/sum25-go-flutter-course/backend/internal/models"
+)
+
+// forEachDialect runs fn against SQLite and, when configured, Postgres
+func forEachDialect(t *testing.T, fn func(t *testing.T, s Store)) {
+	dbs := map[string]func(testing.TB) *database.DB{
+		"sqlite":   dbtest.SQLite,
+		"postgres": dbtest.Postgres,
+	}
+	for name, open := range dbs {
+		t.Run(name, func(t *testing.T) {
+			fn(t, New(open(t)))
+		})
+	}
+}
+
+// uniqueEmail avoids collisions in shared Postgres databases
+func uniqueEmail(name string) string {
+	return fmt.Sprintf("%s-%d@example.com", name, time.Now().UnixNano())
+}
+
+func TestUserStore(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		u := &models.User{Email: uniqueEmail("ada"), Name: "Ada", PasswordHash: "hash", Roles: []string{"user", "admin"}}
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser() failed: %v", err)
+		}
+		if u.ID == 0 || u.CreatedAt.IsZero() {
+			t.Fatalf("Expected ID and timestamps to be set, got %+v", u)
+		}
+
+		dup := &models.User{Email: u.Email, Name: "Other", PasswordHash: "hash"}
+		if err := s.CreateUser(ctx, dup); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict for duplicate email, got %v", err)
+		}
+
+		got, err := s.GetUserByEmail(ctx, u.Email)
+		if err != nil {
+			t.Fatalf("GetUserByEmail() failed: %v", err)
+		}
+		if got.ID != u.ID || got.Name != "Ada" || !got.HasRole("admin") {
+			t.Errorf("Expected stored user, got %+v", got)
+		}
+		if !got.CreatedAt.Equal(u.CreatedAt) {
+			t.Errorf("Expected created_at %v, got %v", u.CreatedAt, got.CreatedAt)
+		}
+
+		if got.TimeZone != "UTC" {
+			t.Errorf("Expected default time zone UTC, got %q", got.TimeZone)
+		}
+
+		u.Name = "Ada Lovelace"
+		u.Roles = []string{"user"}
+		u.TimeZone = "Europe/London"
+		if err := s.UpdateUser(ctx, u); err != nil {
+			t.Fatalf("UpdateUser() failed: %v", err)
+		}
+		got, _ = s.GetUser(ctx, u.ID)
+		if got.Name != "Ada Lovelace" || got.HasRole("admin") || got.TimeZone != "Europe/London" || got.Disabled() {
+			t.Errorf("Expected updated user, got %+v", got)
+		}
+
+		disabledAt := time.Date(2025, 7, 1, 9, 30, 0, 0, time.UTC)
+		u.DisabledAt = &disabledAt
+		if err := s.UpdateUser(ctx, u); err != nil {
+			t.Fatalf("UpdateUser() failed: %v", err)
+		}
+		users, err := s.ListUsers(ctx)
+		if err != nil {
+			t.Fatalf("ListUsers() failed: %v", err)
+		}
+		var listed *models.User
+		for _, candidate := range users {
+			if candidate.ID == u.ID {
+				listed = candidate
+			}
+		}
+		if listed == nil || listed.DisabledAt == nil || !listed.DisabledAt.Equal(disabledAt) {
+			t.Errorf("Expected the disabled user in the list, got %+v", listed)
+		}
+
+		if err := s.DeleteUser(ctx, u.ID); err != nil {
+			t.Fatalf("DeleteUser() failed: %v", err)
+		}
+		if _, err := s.GetUser(ctx, u.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound after delete, got %v", err)
+		}
+		if err := s.DeleteUser(ctx, u.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+		}
+		if err := s.UpdateUser(ctx, u); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound updating a deleted user, got %v", err)
+		}
+	})
+}
+
+func TestRefreshTokenStore(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		u := &models.User{Email: uniqueEmail("tokens"), Name: "T", PasswordHash: "hash"}
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser() failed: %v", err)
+		}
+
+		now := time.Now().UTC()
+		hash := fmt.Sprintf("hash-%d", now.UnixNano())
+		token := &models.RefreshToken{UserID: u.ID, TokenHash: hash, ExpiresAt: now.Add(time.Hour)}
+		if err := s.CreateRefreshToken(ctx, token); err != nil {
+			t.Fatalf("CreateRefreshToken() failed: %v", err)
+		}
+
+		got, err := s.GetRefreshToken(ctx, hash)
+		if err != nil {
+			t.Fatalf("GetRefreshToken() failed: %v", err)
+		}
+		if got.UserID != u.ID || !got.Active(now) {
+			t.Errorf("Expected active token of user %d, got %+v", u.ID, got)
+		}
+
+		if revoked, err := s.RevokeRefreshToken(ctx, got.ID, now); err != nil || !revoked {
+			t.Fatalf("Expected first revoke to succeed, got %v, %v", revoked, err)
+		}
+		if revoked, _ := s.RevokeRefreshToken(ctx, got.ID, now); revoked {
+			t.Error("Expected second revoke to report false")
+		}
+		got, _ = s.GetRefreshToken(ctx, hash)
+		if got.Active(now) {
+			t.Error("Expected revoked token to be inactive")
+		}
+
+		other := &models.RefreshToken{UserID: u.ID, TokenHash: hash + "-2", ExpiresAt: now.Add(time.Hour)}
+		s.CreateRefreshToken(ctx, other)
+		if err := s.RevokeUserRefreshTokens(ctx, u.ID, now); err != nil {
+			t.Fatalf("RevokeUserRefreshTokens() failed: %v", err)
+		}
+		if got, _ := s.GetRefreshToken(ctx, other.TokenHash); got.Active(now) {
+			t.Error("Expected all user tokens to be revoked")
+		}
+
+		expired := &models.RefreshToken{UserID: u.ID, TokenHash: hash + "-3", ExpiresAt: now.Add(-time.Hour)}
+		s.CreateRefreshToken(ctx, expired)
+		if n, err := s.DeleteExpiredRefreshTokens(ctx, now); err != nil || n < 1 {
+			t.Fatalf("Expected expired token to be deleted, got %d, %v", n, err)
+		}
+		if _, err := s.GetRefreshToken(ctx, expired.TokenHash); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected expired token to be gone, got %v", err)
+		}
+		if _, err := s.GetRefreshToken(ctx, other.TokenHash); err != nil {
+			t.Errorf("Expected unexpired token to be kept, got %v", err)
+		}
+
+		s.DeleteUser(ctx, u.ID)
+		if _, err := s.GetRefreshToken(ctx, hash); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected tokens to be deleted with the user, got %v", err)
+		}
+	})
+}
+
+func TestHabitStore(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		u := &models.User{Email: uniqueEmail("habits"), Name: "Ada", PasswordHash: "hash"}
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser() failed: %v", err)
+		}
+
+		start := models.NewDate(2025, 3, 3)
+		h := &models.Habit{
+			UserID:    u.ID,
+			Name:      "Run",
+			Schedule:  models.HabitSchedule{Kind: models.ScheduleWeekdays, Weekdays: []string{"mon", "thu"}},
+			StartDate: start,
+		}
+		if err := s.CreateHabit(ctx, h); err != nil {
+			t.Fatalf("CreateHabit() failed: %v", err)
+		}
+		got, err := s.GetHabit(ctx, u.ID, h.ID)
+		if err != nil {
+			t.Fatalf("GetHabit() failed: %v", err)
+		}
+		if got.StartDate != start || len(got.Schedule.Weekdays) != 2 || got.Schedule.Weekdays[1] != "thu" {
+			t.Errorf("Expected stored habit, got %+v", got)
+		}
+		if _, err := s.GetHabit(ctx, u.ID+1, h.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for another user's habit, got %v", err)
+		}
+
+		h.Archived = true
+		if err := s.UpdateHabit(ctx, h); err != nil {
+			t.Fatalf("UpdateHabit() failed: %v", err)
+		}
+		if list, _ := s.ListHabits(ctx, u.ID, false); len(list) != 0 {
+			t.Errorf("Expected archived habit to be hidden, got %d habits", len(list))
+		}
+		if list, _ := s.ListHabits(ctx, u.ID, true); len(list) != 1 {
+			t.Errorf("Expected archived habit when included, got %d habits", len(list))
+		}
+
+		for _, day := range []int{3, 6, 10} {
+			if err := s.CreateCheckIn(ctx, &models.CheckIn{HabitID: h.ID, Date: models.NewDate(2025, 3, day)}); err != nil {
+				t.Fatalf("CreateCheckIn() failed: %v", err)
+			}
+		}
+		if err := s.CreateCheckIn(ctx, &models.CheckIn{HabitID: h.ID, Date: start}); !errors.Is(err, ErrConflict) {
+			t.Errorf("Expected ErrConflict for a second check-in on a day, got %v", err)
+		}
+		checkIns, err := s.ListCheckIns(ctx, h.ID, models.NewDate(2025, 3, 4), models.NewDate(2025, 3, 10))
+		if err != nil {
+			t.Fatalf("ListCheckIns() failed: %v", err)
+		}
+		if len(checkIns) != 2 || checkIns[0].Date != models.NewDate(2025, 3, 6) {
+			t.Errorf("Expected check-ins on 6 and 10 March, got %+v", checkIns)
+		}
+
+		if err := s.DeleteCheckIn(ctx, h.ID, start); err != nil {
+			t.Fatalf("DeleteCheckIn() failed: %v", err)
+		}
+		if err := s.DeleteCheckIn(ctx, h.ID, start); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+		}
+
+		if err := s.DeleteHabit(ctx, u.ID, h.ID); err != nil {
+			t.Fatalf("DeleteHabit() failed: %v", err)
+		}
+		if _, err := s.GetHabit(ctx, u.ID, h.ID); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound after delete, got %v", err)
+		}
+		s.DeleteUser(ctx, u.ID)
+	})
+}
+
+func TestMeasurementStore(t *testing.T) {
+	forEachDialect(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		u := &models.User{Email: uniqueEmail("metrics"), Name: "Ada", PasswordHash: "hash"}
+		if err := s.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser() failed: %v", err)
+		}
+		defer s.DeleteUser(ctx, u.ID)
+
+		base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+		batch := []*models.Measurement{
+			{ClientID: "a", Metric: models.MetricHeartRate, Value: 60, RecordedAt: base.Add(time.Minute)},
+			{ClientID: "b", Metric: models.MetricHeartRate, Value: 90, RecordedAt: base.Add(14 * time.Minute)},
+			{ClientID: "c", Metric: models.MetricHeartRate, Value: 75, RecordedAt: base.Add(20 * time.Minute)},
+			{ClientID: "d", Metric: models.MetricSteps, Value: 500, RecordedAt: base},
+		}
+		n, err := s.AddMeasurements(ctx, u.ID, batch)
+		if err != nil {
+			t.Fatalf("AddMeasurements() failed: %v", err)
+		}
+		if n != 4 {
+			t.Errorf("Expected 4 inserted, got %d", n)
+		}
+
+		// Resending "b" is ignored; "e" lands in the first bucket
+		n, err = s.AddMeasurements(ctx, u.ID, []*models.Measurement{
+			{ClientID: "b", Metric: models.MetricHeartRate, Value: 90, RecordedAt: base.Add(14 * time.Minute)},
+			{ClientID: "e", Metric: models.MetricHeartRate, Value: 50, RecordedAt: base.Add(5 * time.Minute)},
+		})
+		if err != nil {
+			t.Fatalf("AddMeasurements() failed: %v", err)
+		}
+		if n != 1 {
+			t.Errorf("Expected duplicate to be skipped, got %d inserted", n)
+		}
+
+		rollups, err := s.ListRollups(ctx, u.ID, models.MetricHeartRate, base, base.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("ListRollups() failed: %v", err)
+		}
+		want := []models.Rollup{
+			{Metric: models.MetricHeartRate, Start: base, Count: 3, Sum: 200, Min: 50, Max: 90},
+			{Metric: models.MetricHeartRate, Start: base.Add(15 * time.Minute), Count: 1, Sum: 75, Min: 75, Max: 75},
+		}
+		if len(rollups) != len(want) {
+			t.Fatalf("Expected %d rollups, got %+v", len(want), rollups)
+		}
+		for i := range want {
+			if !rollups[i].Start.Equal(want[i].Start) || rollups[i].Count != want[i].Count || rollups[i].Sum != want[i].Sum ||
+				rollups[i].Min != want[i].Min || rollups[i].Max != want[i].Max {
+				t.Errorf("Expected rollup %+v, got %+v", want[i], rollups[i])
+			}
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time with
+//
+//	go build -ldflags "-X github.com/timur-harin/sum25-go-flutter-course/backend/internal/version.Version=v1.2.3"
+var (
+	Version = ""
+	Commit  = ""
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build information, preferring ldflags values and falling
+// back to the module and VCS data embedded by the Go toolchain
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, GoVersion: runtime.Version()}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			if s.Key == "vcs.revision" && info.Commit == "" {
+				info.Commit = s.Value
+			}
+		}
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	return info
+}
@@ -0,0 +1,2 @@
+-- Nothing to undo for the baseline.
+SELECT 1;
@@ -0,0 +1,3 @@
+-- Baseline: marks the database as managed by cmd/migrate.
+-- Domain tables are introduced by the migrations that follow.
+SELECT 1;
@@ -0,0 +1,19 @@
+CREATE TABLE jobs (
+    id           BIGSERIAL PRIMARY KEY,
+    kind         TEXT        NOT NULL,
+    unique_key   TEXT        UNIQUE,
+    payload      TEXT        NOT NULL DEFAULT '{}',
+    schedule     TEXT        NOT NULL DEFAULT '',
+    timezone     TEXT        NOT NULL DEFAULT 'UTC',
+    status       TEXT        NOT NULL DEFAULT 'scheduled',
+    run_at       TIMESTAMPTZ NOT NULL,
+    attempts     INTEGER     NOT NULL DEFAULT 0,
+    max_attempts INTEGER     NOT NULL,
+    last_error   TEXT,
+    locked_by    TEXT,
+    locked_until TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL,
+    updated_at   TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX jobs_due_idx ON jobs (run_at) WHERE status IN ('scheduled', 'running');
@@ -0,0 +1,3 @@
+DROP TABLE habit_checkins;
+DROP TABLE habits;
+ALTER TABLE users DROP COLUMN timezone;
@@ -0,0 +1,25 @@
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+
+CREATE TABLE habits (
+    id             INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id        INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name           TEXT      NOT NULL,
+    description    TEXT      NOT NULL DEFAULT '',
+    schedule_kind  TEXT      NOT NULL,
+    times_per_week INTEGER   NOT NULL DEFAULT 0,
+    weekdays       TEXT      NOT NULL DEFAULT '',
+    start_date     DATE      NOT NULL,
+    archived       BOOLEAN   NOT NULL DEFAULT FALSE,
+    created_at     TIMESTAMP NOT NULL,
+    updated_at     TIMESTAMP NOT NULL
+);
+
+CREATE INDEX habits_user_id_idx ON habits (user_id);
+
+CREATE TABLE habit_checkins (
+    habit_id   INTEGER   NOT NULL REFERENCES habits (id) ON DELETE CASCADE,
+    date       DATE      NOT NULL,
+    note       TEXT      NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (habit_id, date)
+);
@@ -0,0 +1,24 @@
+CREATE TABLE measurements (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT           NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_id   TEXT             NOT NULL,
+    metric      TEXT             NOT NULL,
+    value       DOUBLE PRECISION NOT NULL,
+    recorded_at TIMESTAMPTZ      NOT NULL,
+    created_at  TIMESTAMPTZ      NOT NULL,
+    UNIQUE (user_id, client_id)
+);
+
+CREATE INDEX measurements_user_metric_idx ON measurements (user_id, metric, recorded_at);
+
+-- Aggregates per 15-minute UTC bucket, maintained on insert
+CREATE TABLE metric_rollups (
+    user_id      BIGINT           NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    metric       TEXT             NOT NULL,
+    bucket_start TIMESTAMPTZ      NOT NULL,
+    value_count  BIGINT           NOT NULL,
+    value_sum    DOUBLE PRECISION NOT NULL,
+    value_min    DOUBLE PRECISION NOT NULL,
+    value_max    DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (user_id, metric, bucket_start)
+);
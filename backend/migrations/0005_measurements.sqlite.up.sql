@@ -0,0 +1,24 @@
+CREATE TABLE measurements (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id     INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    client_id   TEXT      NOT NULL,
+    metric      TEXT      NOT NULL,
+    value       REAL      NOT NULL,
+    recorded_at TIMESTAMP NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    UNIQUE (user_id, client_id)
+);
+
+CREATE INDEX measurements_user_metric_idx ON measurements (user_id, metric, recorded_at);
+
+-- Aggregates per 15-minute UTC bucket, maintained on insert
+CREATE TABLE metric_rollups (
+    user_id      INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    metric       TEXT      NOT NULL,
+    bucket_start TIMESTAMP NOT NULL,
+    value_count  INTEGER   NOT NULL,
+    value_sum    REAL      NOT NULL,
+    value_min    REAL      NOT NULL,
+    value_max    REAL      NOT NULL,
+    PRIMARY KEY (user_id, metric, bucket_start)
+);
@@ -0,0 +1,13 @@
+-- Responses of requests sent with an Idempotency-Key; status 0 marks a key
+-- claimed by a request that is still running
+CREATE TABLE idempotency_keys (
+    idempotency_key TEXT PRIMARY KEY,
+    fingerprint     TEXT      NOT NULL,
+    status          INTEGER   NOT NULL DEFAULT 0,
+    headers         TEXT      NOT NULL DEFAULT '',
+    body            BLOB,
+    expires_at      TIMESTAMP NOT NULL,
+    created_at      TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idempotency_keys_expires_idx ON idempotency_keys (expires_at);
@@ -0,0 +1,28 @@
+-- Tamper-evident log of security-relevant actions. Each row's hash covers
+-- its content and the previous row's hash; rows cannot be changed.
+CREATE TABLE audit_events (
+    id          BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL,
+    action      TEXT        NOT NULL,
+    actor_id    BIGINT,
+    target_type TEXT        NOT NULL DEFAULT '',
+    target_id   TEXT        NOT NULL DEFAULT '',
+    ip          TEXT        NOT NULL DEFAULT '',
+    user_agent  TEXT        NOT NULL DEFAULT '',
+    request_id  TEXT        NOT NULL DEFAULT '',
+    details     TEXT        NOT NULL DEFAULT '',
+    prev_hash   TEXT        NOT NULL UNIQUE,
+    hash        TEXT        NOT NULL
+);
+
+CREATE INDEX audit_events_action_idx ON audit_events (action, occurred_at);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor_id, occurred_at);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,29 @@
+-- Tamper-evident log of security-relevant actions. Each row's hash covers
+-- its content and the previous row's hash; rows cannot be changed.
+CREATE TABLE audit_events (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    occurred_at TIMESTAMP NOT NULL,
+    action      TEXT      NOT NULL,
+    actor_id    INTEGER,
+    target_type TEXT      NOT NULL DEFAULT '',
+    target_id   TEXT      NOT NULL DEFAULT '',
+    ip          TEXT      NOT NULL DEFAULT '',
+    user_agent  TEXT      NOT NULL DEFAULT '',
+    request_id  TEXT      NOT NULL DEFAULT '',
+    details     TEXT      NOT NULL DEFAULT '',
+    prev_hash   TEXT      NOT NULL UNIQUE,
+    hash        TEXT      NOT NULL
+);
+
+CREATE INDEX audit_events_action_idx ON audit_events (action, occurred_at);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor_id, occurred_at);
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TRIGGER audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+    SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
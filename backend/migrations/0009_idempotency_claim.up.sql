@@ -0,0 +1,3 @@
+-- Token of the request holding a key while status is 0, so a request whose
+-- claim expired and was taken over cannot save or release the new claim
+ALTER TABLE idempotency_keys ADD COLUMN claim TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,11 @@
+// Package migrations embeds the versioned SQL schema migrations applied by
+// cmd/migrate. Files are named VERSION_NAME[.DIALECT].(up|down).sql; run
+// `go run cmd/migrate/main.go create NAME` to add a new pair.
+package migrations
+
+import "embed"
+
+// FS holds every migration file compiled into the binary
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,219 @@
+package taskmanager
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// History errors
+var (
+	ErrNothingToUndo      = errors.New("nothing to undo")
+	ErrNothingToRedo      = errors.New("nothing to redo")
+	ErrHistoryUnavailable = errors.New("history does not reach back that far")
+)
+
+// Every change made through AddTask, UpdateTask, DeleteTask and the relation
+// methods is kept as a batch of ops in the history, which is saved along
+// with the tasks. Undo reverts the latest change by recording a new batch
+// that restores the tasks as they were; Redo reverts that in turn. A new
+// change empties the redo stack, as in any editor. Only the latest
+// maxHistory ops are kept, so older changes can no longer be undone.
+
+// Undo reverts the latest change that was not undone yet
+func (tm *TaskManager) Undo() error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if len(tm.undo) == 0 {
+		return ErrNothingToUndo
+	}
+	batch, err := tm.revert(tm.undo[len(tm.undo)-1])
+	if batch != 0 {
+		tm.undo = tm.undo[:len(tm.undo)-1]
+		tm.redo = append(tm.redo, batch)
+	}
+	return err
+}
+
+// Redo applies the latest undone change again
+func (tm *TaskManager) Redo() error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if len(tm.redo) == 0 {
+		return ErrNothingToRedo
+	}
+	batch, err := tm.revert(tm.redo[len(tm.redo)-1])
+	if batch != 0 {
+		tm.redo = tm.redo[:len(tm.redo)-1]
+		tm.undo = append(tm.undo, batch)
+	}
+	return err
+}
+
+// CanUndo reports whether Undo has a change to revert
+func (tm *TaskManager) CanUndo() bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return len(tm.undo) > 0
+}
+
+// CanRedo reports whether Redo has a change to apply again
+func (tm *TaskManager) CanRedo() bool {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	return len(tm.redo) > 0
+}
+
+// History returns the changes to task id that are still kept, oldest first.
+// Deleted tasks keep their history, so it shows what Undo can bring back.
+func (tm *TaskManager) History(id int) ([]Op, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	var result []Op
+	for _, op := range tm.history {
+		if op.taskID() == id {
+			result = append(result, op.clone())
+		}
+	}
+	if result == nil {
+		if _, exists := tm.tasks[id]; !exists {
+			return nil, ErrTaskNotFound
+		}
+		result = []Op{}
+	}
+	return result, nil
+}
+
+// At rebuilds the tasks as they were at t by reverting, newest first, the
+// changes made after t. The result is an in-memory TaskManager with the
+// history up to then, so it can be queried, or changed without affecting tm.
+// It fails with ErrHistoryUnavailable when t is older than the history,
+// which keeps only the latest changes and none from before it existed.
+func (tm *TaskManager) At(t time.Time) (*TaskManager, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	keep := len(tm.history)
+	for i, op := range tm.history {
+		if op.Time.After(t) {
+			keep = i
+			break
+		}
+	}
+	// Before the first kept change the tasks are only known when it was the
+	// first change ever made
+	if keep == 0 {
+		switch {
+		case len(tm.history) == 0 && tm.seq != 0:
+			return nil, ErrHistoryUnavailable
+		case len(tm.history) > 0 && tm.history[0].Seq != 1:
+			return nil, fmt.Errorf("%w: it starts at %s", ErrHistoryUnavailable, tm.history[0].Time.Format(time.RFC3339))
+		}
+	}
+
+	past := NewTaskManager()
+	for id, task := range tm.tasks {
+		past.tasks[id] = task
+	}
+	for i := len(tm.history) - 1; i >= keep; i-- {
+		inverse, err := tm.history[i].inverse()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrHistoryUnavailable, err)
+		}
+		apply(past.tasks, &past.nextID, inverse)
+	}
+	past.history = slices.Clone(tm.history[:keep])
+	for id := range past.tasks {
+		past.nextID = max(past.nextID, id+1)
+	}
+	for _, op := range past.history {
+		past.nextID = max(past.nextID, op.taskID()+1)
+		past.seq = op.Seq
+	}
+	past.rebuildStacks()
+	return past, nil
+}
+
+// revert records a new batch that undoes batch and returns it. The caller
+// holds tm.mu.
+func (tm *TaskManager) revert(batch uint64) (uint64, error) {
+	var ops []Op
+	for i := len(tm.history) - 1; i >= 0 && tm.history[i].batch() >= batch; i-- {
+		op := tm.history[i]
+		if op.batch() != batch {
+			continue
+		}
+		inverse, err := op.inverse()
+		if err != nil {
+			return 0, err
+		}
+		ops = append(ops, inverse)
+	}
+	if len(ops) == 0 {
+		return 0, fmt.Errorf("taskmanager: change %d is not in the history", batch)
+	}
+	return tm.record(ops, batch)
+}
+
+// inverse returns the op that undoes op
+func (op Op) inverse() (Op, error) {
+	switch {
+	case op.Type == OpAdd:
+		return Op{Type: OpDelete, ID: op.Task.ID}, nil
+	case op.Before == nil:
+		// Logged before changes kept the previous task
+		return Op{}, fmt.Errorf("taskmanager: change %d cannot be undone", op.Seq)
+	case op.Type == OpUpdate:
+		before := *op.Before
+		return Op{Type: OpUpdate, Task: &before}, nil
+	case op.Type == OpDelete:
+		before := *op.Before
+		return Op{Type: OpAdd, Task: &before}, nil
+	}
+	return Op{}, fmt.Errorf("taskmanager: unknown op type %q", op.Type)
+}
+
+// trimHistory drops the oldest batches until at most maxHistory ops are
+// left, along with the undo and redo entries for them. The caller holds
+// tm.mu or owns tm.
+func (tm *TaskManager) trimHistory() {
+	if len(tm.history) <= maxHistory {
+		return
+	}
+	cut := len(tm.history) - maxHistory
+	// Keep batches whole
+	for cut < len(tm.history) && tm.history[cut].batch() == tm.history[cut-1].batch() {
+		cut++
+	}
+	tm.history = tm.history[cut:]
+	first := tm.seq + 1
+	if len(tm.history) > 0 {
+		first = tm.history[0].batch()
+	}
+	dropped := func(batch uint64) bool { return batch < first }
+	tm.undo = slices.DeleteFunc(tm.undo, dropped)
+	tm.redo = slices.DeleteFunc(tm.redo, dropped)
+}
+
+// rebuildStacks restores the undo and redo stacks from the history, which
+// records what each undo and redo reverted. The caller holds tm.mu or owns
+// tm.
+func (tm *TaskManager) rebuildStacks() {
+	tm.undo, tm.redo = nil, nil
+	for i, op := range tm.history {
+		if i > 0 && tm.history[i-1].batch() == op.batch() {
+			continue
+		}
+		switch {
+		case op.Reverts == 0:
+			tm.undo = append(tm.undo, op.batch())
+			tm.redo = nil
+		case len(tm.undo) > 0 && tm.undo[len(tm.undo)-1] == op.Reverts:
+			tm.undo = tm.undo[:len(tm.undo)-1]
+			tm.redo = append(tm.redo, op.batch())
+		case len(tm.redo) > 0 && tm.redo[len(tm.redo)-1] == op.Reverts:
+			tm.redo = tm.redo[:len(tm.redo)-1]
+			tm.undo = append(tm.undo, op.batch())
+		}
+	}
+}
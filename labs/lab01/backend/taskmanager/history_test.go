@@ -0,0 +1,291 @@
+package taskmanager
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// titles returns the titles of the tasks of tm in ID order
+func titles(tm *TaskManager) []string {
+	result := []string{}
+	for _, t := range tm.ListTasks(nil) {
+		result = append(result, t.Title)
+	}
+	return result
+}
+
+func TestUndoRedo(t *testing.T) {
+	tm := NewTaskManager()
+	if err := tm.Undo(); err != ErrNothingToUndo {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+	if err := tm.Redo(); err != ErrNothingToRedo {
+		t.Errorf("Expected ErrNothingToRedo, got %v", err)
+	}
+
+	a, _ := tm.AddTask("A", "")
+	tm.AddTask("B", "")
+	tm.UpdateTask(a.ID, "A2", "", true)
+	tm.DeleteTask(a.ID)
+
+	steps := []struct {
+		name    string
+		do      func() error
+		want    []string
+		wantErr error
+	}{
+		{name: "undo delete", do: tm.Undo, want: []string{"A2", "B"}},
+		{name: "undo update", do: tm.Undo, want: []string{"A", "B"}},
+		{name: "undo add", do: tm.Undo, want: []string{"A"}},
+		{name: "redo add", do: tm.Redo, want: []string{"A", "B"}},
+		{name: "redo update", do: tm.Redo, want: []string{"A2", "B"}},
+		{name: "undo update again", do: tm.Undo, want: []string{"A", "B"}},
+		{name: "new change", do: func() error { _, err := tm.AddTask("C", ""); return err }, want: []string{"A", "B", "C"}},
+		{name: "redo after a change", do: tm.Redo, want: []string{"A", "B", "C"}, wantErr: ErrNothingToRedo},
+		{name: "undo the new change", do: tm.Undo, want: []string{"A", "B"}},
+	}
+	for _, step := range steps {
+		if err := step.do(); err != step.wantErr {
+			t.Fatalf("%s: expected error %v, got %v", step.name, step.wantErr, err)
+		}
+		if got := titles(tm); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: expected %v, got %v", step.name, step.want, got)
+		}
+	}
+
+	// The restored task is exactly the one that was there, ID included
+	if got, _ := tm.GetTask(a.ID); got.Title != "A" || got.Done {
+		t.Errorf("Expected task A to be open again, got %+v", got)
+	}
+	if task, _ := tm.AddTask("D", ""); task.ID != 4 {
+		t.Errorf("Expected undone IDs not to be reused, got ID %d", task.ID)
+	}
+}
+
+func TestUndoCompoundChange(t *testing.T) {
+	tm := NewTaskManager()
+	rule, _ := ParseRule("FREQ=DAILY")
+	task, _ := tm.AddTask("Stretch", "", WithRecurrence(rule, time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)))
+	tm.UpdateTask(task.ID, task.Title, "", true)
+	if n := len(tm.ListTasks(nil)); n != 2 {
+		t.Fatalf("Expected the next occurrence to be added, got %d tasks", n)
+	}
+
+	// Finishing an occurrence and adding the next one is one change
+	if err := tm.Undo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tasks := tm.ListTasks(nil)
+	if len(tasks) != 1 || tasks[0].Done || tasks[0].Recurrence.NextID != 0 {
+		t.Errorf("Expected only the open first occurrence, got %+v", tasks)
+	}
+	if err := tm.Redo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n := len(tm.ListTasks(nil)); n != 2 {
+		t.Errorf("Expected 2 tasks after redo, got %d", n)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	tm := NewTaskManager()
+	a, _ := tm.AddTask("A", "")
+	b, _ := tm.AddTask("B", "")
+	tm.UpdateTask(a.ID, "A2", "", false)
+	tm.AddBlocker(a.ID, b.ID)
+	tm.DeleteTask(a.ID)
+	tm.Undo()
+
+	ops, err := tm.History(a.ID)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var types []string
+	for _, op := range ops {
+		types = append(types, op.Type)
+	}
+	if want := []string{OpAdd, OpUpdate, OpUpdate, OpDelete, OpAdd}; !reflect.DeepEqual(types, want) {
+		t.Errorf("Expected %v, got %v", want, types)
+	}
+	if ops[1].Before.Title != "A" || ops[1].Task.Title != "A2" {
+		t.Errorf("Expected the update to keep both versions, got %+v", ops[1])
+	}
+	if ops[4].Reverts != ops[3].Batch {
+		t.Errorf("Expected the restore to revert batch %d, got %d", ops[3].Batch, ops[4].Reverts)
+	}
+	for i := 1; i < len(ops); i++ {
+		if ops[i].Seq <= ops[i-1].Seq || ops[i].Time.Before(ops[i-1].Time) {
+			t.Errorf("Expected history in order, got %+v before %+v", ops[i-1], ops[i])
+		}
+	}
+
+	if ops, _ := tm.History(b.ID); len(ops) != 1 {
+		t.Errorf("Expected 1 change to B, got %d", len(ops))
+	}
+	if _, err := tm.History(99); err != ErrTaskNotFound {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestAt(t *testing.T) {
+	tm := NewTaskManager()
+	a, _ := tm.AddTask("A", "")
+	tm.AddTask("B", "")
+	time.Sleep(time.Millisecond)
+	mid := time.Now()
+	time.Sleep(time.Millisecond)
+	tm.UpdateTask(a.ID, "A2", "", true)
+	tm.DeleteTask(2)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want []string
+	}{
+		{name: "before anything", at: mid.Add(-time.Hour), want: []string{}},
+		{name: "in between", at: mid, want: []string{"A", "B"}},
+		{name: "now", at: time.Now(), want: []string{"A2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			past, err := tm.At(tt.at)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := titles(past); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// The rebuilt manager is independent and keeps counting IDs
+	past, _ := tm.At(mid)
+	if err := past.Undo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := titles(tm); !reflect.DeepEqual(got, []string{"A2"}) {
+		t.Errorf("Expected the original to be unchanged, got %v", got)
+	}
+	if task, _ := past.AddTask("C", ""); task.ID != 3 {
+		t.Errorf("Expected ID 3, got %d", task.ID)
+	}
+}
+
+func TestAtBeforeHistory(t *testing.T) {
+	// A snapshot saved before the history existed has tasks but no changes
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	snapshot := `{"seq":2,"next_id":3,"tasks":[{"id":1,"title":"A"},{"id":2,"title":"B"}]}`
+	if err := os.WriteFile(path, []byte(snapshot), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tm := reopen(t, nil, path)
+	if _, err := tm.At(time.Now()); !errors.Is(err, ErrHistoryUnavailable) {
+		t.Errorf("Expected ErrHistoryUnavailable without any history, got %v", err)
+	}
+
+	before := time.Now()
+	time.Sleep(time.Millisecond)
+	tm.DeleteTask(1)
+	time.Sleep(time.Millisecond)
+	mid := time.Now()
+	time.Sleep(time.Millisecond)
+	tm.AddTask("C", "")
+
+	past, err := tm.At(mid)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := titles(past); !reflect.DeepEqual(got, []string{"B"}) {
+		t.Errorf("Expected [B], got %v", got)
+	}
+	if _, err := tm.At(before); !errors.Is(err, ErrHistoryUnavailable) {
+		t.Errorf("Expected ErrHistoryUnavailable before the first change, got %v", err)
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	tm := NewTaskManager()
+	start := time.Now()
+	task, _ := tm.AddTask("Task", "")
+	for i := range maxHistory + 10 {
+		tm.UpdateTask(task.ID, fmt.Sprintf("Task %d", i), "", false)
+	}
+	if len(tm.history) != maxHistory {
+		t.Errorf("Expected %d ops in the history, got %d", maxHistory, len(tm.history))
+	}
+	if len(tm.undo) != maxHistory {
+		t.Errorf("Expected undo to reach back %d changes, got %d", maxHistory, len(tm.undo))
+	}
+	// The undos are history too and push out the changes they revert, so
+	// half of them can be undone
+	undone := 0
+	for tm.Undo() == nil {
+		undone++
+	}
+	if undone != maxHistory/2 {
+		t.Errorf("Expected %d changes undone, got %d", maxHistory/2, undone)
+	}
+	if got, _ := tm.GetTask(task.ID); got.Title != fmt.Sprintf("Task %d", maxHistory/2+9) {
+		t.Errorf("Expected the oldest undone change reverted, got %q", got.Title)
+	}
+	if _, err := tm.At(start); !errors.Is(err, ErrHistoryUnavailable) {
+		t.Errorf("Expected ErrHistoryUnavailable, got %v", err)
+	}
+}
+
+func TestHistoryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	tm := reopen(t, nil, path)
+	a, _ := tm.AddTask("A", "")
+	tm.AddTask("B", "")
+	tm.DeleteTask(a.ID)
+	tm.Undo()
+	tm.Undo()
+
+	// Until the next snapshot the history is read back from the log
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := store.Load()
+	store.Close()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(state.History) != 5 {
+		t.Errorf("Expected 5 changes in the log, got %d", len(state.History))
+	}
+
+	// Closing folds it into the snapshot, which must keep it across restarts
+	for i := range 2 {
+		tm = reopen(t, tm, path)
+		if got := titles(tm); !reflect.DeepEqual(got, []string{"A"}) {
+			t.Fatalf("Reopen %d: expected [A], got %v", i, got)
+		}
+		if ops, _ := tm.History(a.ID); len(ops) != 3 {
+			t.Errorf("Reopen %d: expected 3 changes to A, got %d", i, len(ops))
+		}
+		if !tm.CanUndo() || !tm.CanRedo() {
+			t.Errorf("Reopen %d: expected both undo and redo to be possible", i)
+		}
+	}
+
+	// The stacks survive the restart too
+	if err := tm.Redo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := titles(tm); !reflect.DeepEqual(got, []string{"A", "B"}) {
+		t.Errorf("Expected [A B], got %v", got)
+	}
+	if err := tm.Redo(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := titles(tm); !reflect.DeepEqual(got, []string{"B"}) {
+		t.Errorf("Expected [B], got %v", got)
+	}
+}
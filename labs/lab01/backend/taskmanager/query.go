@@ -0,0 +1,205 @@
+package taskmanager
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrInvalidQuery is returned for queries that cannot be run
+var ErrInvalidQuery = errors.New("invalid query")
+
+// SortField is a task field results can be ordered by
+type SortField string
+
+const (
+	SortByID        SortField = "id"
+	SortByTitle     SortField = "title"
+	SortByDueDate   SortField = "due_date"
+	SortByPriority  SortField = "priority"
+	SortByCreatedAt SortField = "created_at"
+	SortByUpdatedAt SortField = "updated_at"
+)
+
+// SortKey orders by one field. Tasks without a due date come last whichever
+// the direction.
+type SortKey struct {
+	Field SortField
+	Desc  bool
+}
+
+// Query selects, orders and pages tasks. Every filter that is set must
+// match; the zero Query returns all tasks ordered by ID.
+type Query struct {
+	// Done keeps only tasks with this status
+	Done *bool
+	// Tags keeps tasks carrying all of these tags
+	Tags []string
+	// Priorities keeps tasks with any of these priorities
+	Priorities []Priority
+	// Overdue keeps open tasks whose due date has passed
+	Overdue bool
+	// DueBefore keeps tasks due before this time
+	DueBefore time.Time
+	// Text keeps tasks whose title or description contains every word of
+	// it, ignoring case
+	Text string
+
+	// Sort orders the results by each key in turn, then by ID
+	Sort []SortKey
+
+	// Offset skips that many results and Limit caps how many are returned;
+	// zero means no limit
+	Offset int
+	Limit  int
+
+	// Now is the time Overdue is judged at, time.Now() when zero
+	Now time.Time
+}
+
+// ParseSort parses a comma-separated list of fields, each optionally
+// prefixed with "-" for descending order, such as "-priority,due_date"
+func ParseSort(s string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key := SortKey{Field: SortField(strings.TrimPrefix(part, "-")), Desc: strings.HasPrefix(part, "-")}
+		if compareBy(key.Field) == nil {
+			return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, key.Field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Find returns the page of tasks selected by q and how many tasks match in
+// total
+func (tm *TaskManager) Find(q Query) ([]Task, int, error) {
+	if q.Offset < 0 || q.Limit < 0 {
+		return nil, 0, fmt.Errorf("%w: offset and limit cannot be negative", ErrInvalidQuery)
+	}
+	compare, err := q.compare()
+	if err != nil {
+		return nil, 0, err
+	}
+	if q.Now.IsZero() {
+		q.Now = time.Now()
+	}
+	tags := normalizeTags(q.Tags)
+	words := strings.Fields(strings.ToLower(q.Text))
+
+	tm.mu.RLock()
+	var result []Task
+	for _, t := range tm.tasks {
+		if q.match(t, tags, words) {
+			result = append(result, t)
+		}
+	}
+	tm.mu.RUnlock()
+
+	slices.SortFunc(result, compare)
+	total := len(result)
+	result = result[min(q.Offset, total):]
+	if q.Limit > 0 && q.Limit < len(result) {
+		result = result[:q.Limit]
+	}
+	if result == nil {
+		result = []Task{}
+	}
+	// Stored tasks are never changed in place, so only the page handed out
+	// needs copying
+	for i := range result {
+		result[i] = result[i].clone()
+	}
+	return result, total, nil
+}
+
+// match reports whether t passes the filters of q. tags and words are the
+// normalized Tags and Text.
+func (q *Query) match(t Task, tags, words []string) bool {
+	if q.Done != nil && t.Done != *q.Done {
+		return false
+	}
+	for _, tag := range tags {
+		if !slices.Contains(t.Tags, tag) {
+			return false
+		}
+	}
+	if len(q.Priorities) > 0 && !slices.Contains(q.Priorities, t.Priority) {
+		return false
+	}
+	if q.Overdue && !t.Overdue(q.Now) {
+		return false
+	}
+	if !q.DueBefore.IsZero() && (t.DueDate == nil || !t.DueDate.Before(q.DueBefore)) {
+		return false
+	}
+	if len(words) > 0 {
+		text := strings.ToLower(t.Title + "\n" + t.Description)
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// compare returns the order of the results, failing on unknown fields
+func (q *Query) compare() (func(a, b Task) int, error) {
+	for _, k := range q.Sort {
+		if compareBy(k.Field) == nil {
+			return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, k.Field)
+		}
+	}
+	keys := slices.Clone(q.Sort)
+	return func(a, b Task) int {
+		for _, k := range keys {
+			// Missing due dates sort last in both directions
+			if k.Field == SortByDueDate && (a.DueDate == nil) != (b.DueDate == nil) {
+				if a.DueDate == nil {
+					return 1
+				}
+				return -1
+			}
+			c := compareBy(k.Field)(a, b)
+			if k.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.ID, b.ID)
+	}, nil
+}
+
+// compareBy returns the ascending order of field, nil for unknown fields
+func compareBy(field SortField) func(a, b Task) int {
+	switch field {
+	case SortByID:
+		return func(a, b Task) int { return cmp.Compare(a.ID, b.ID) }
+	case SortByTitle:
+		return func(a, b Task) int { return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)) }
+	case SortByDueDate:
+		return func(a, b Task) int {
+			if a.DueDate == nil || b.DueDate == nil {
+				return 0
+			}
+			return a.DueDate.Compare(*b.DueDate)
+		}
+	case SortByPriority:
+		return func(a, b Task) int { return cmp.Compare(a.Priority, b.Priority) }
+	case SortByCreatedAt:
+		return func(a, b Task) int { return a.CreatedAt.Compare(b.CreatedAt) }
+	case SortByUpdatedAt:
+		return func(a, b Task) int { return a.UpdatedAt.Compare(b.UpdatedAt) }
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package taskmanager
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTaskOptions(t *testing.T) {
+	tm := NewTaskManager()
+	due := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	task, err := tm.AddTask("Task", "", WithDueDate(due), WithPriority(PriorityHigh), WithTags(" Work", "urgent", "work", ""))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if task.DueDate == nil || !task.DueDate.Equal(due) {
+		t.Errorf("Expected due date %v, got %v", due, task.DueDate)
+	}
+	if task.Priority != PriorityHigh {
+		t.Errorf("Expected priority high, got %v", task.Priority)
+	}
+	if want := []string{"urgent", "work"}; !reflect.DeepEqual(task.Tags, want) {
+		t.Errorf("Expected tags %v, got %v", want, task.Tags)
+	}
+	if !task.UpdatedAt.Equal(task.CreatedAt) {
+		t.Errorf("Expected updated_at %v, got %v", task.CreatedAt, task.UpdatedAt)
+	}
+
+	// Fields without an option are kept; a zero due date clears it
+	if err := tm.UpdateTask(task.ID, "Task", "", true, WithDueDate(time.Time{})); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	got, _ := tm.GetTask(task.ID)
+	if got.DueDate != nil {
+		t.Errorf("Expected no due date, got %v", got.DueDate)
+	}
+	if got.Priority != PriorityHigh || len(got.Tags) != 2 {
+		t.Errorf("Expected priority and tags to be kept, got %v and %v", got.Priority, got.Tags)
+	}
+	if got.UpdatedAt.Before(task.UpdatedAt) {
+		t.Errorf("Expected updated_at to advance, got %v", got.UpdatedAt)
+	}
+
+	if _, err := tm.AddTask("Task", "", WithPriority(Priority(9))); !errors.Is(err, ErrInvalidPriority) {
+		t.Errorf("Expected ErrInvalidPriority, got %v", err)
+	}
+	if err := tm.UpdateTask(task.ID, "Task", "", true, WithPriority(-1)); !errors.Is(err, ErrInvalidPriority) {
+		t.Errorf("Expected ErrInvalidPriority, got %v", err)
+	}
+}
+
+func TestPriorityJSON(t *testing.T) {
+	b, err := json.Marshal(Task{Priority: PriorityUrgent})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var task Task
+	if err := json.Unmarshal(b, &task); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if task.Priority != PriorityUrgent {
+		t.Errorf("Expected priority urgent, got %v from %s", task.Priority, b)
+	}
+	if err := json.Unmarshal([]byte(`{"priority":"soon"}`), &task); !errors.Is(err, ErrInvalidPriority) {
+		t.Errorf("Expected ErrInvalidPriority, got %v", err)
+	}
+}
+
+func TestFind(t *testing.T) {
+	now := time.Date(2025, 7, 10, 12, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return now.AddDate(0, 0, n) }
+	tm := NewTaskManager()
+	add := func(title, description string, opts ...TaskOption) {
+		t.Helper()
+		if _, err := tm.AddTask(title, description, opts...); err != nil {
+			t.Fatalf("Failed to add task: %v", err)
+		}
+	}
+	add("Buy milk", "and bread", WithDueDate(day(-1)), WithPriority(PriorityLow), WithTags("home", "shopping"))
+	add("Write report", "quarterly numbers", WithDueDate(day(2)), WithPriority(PriorityHigh), WithTags("work"))
+	add("Call mom", "", WithPriority(PriorityMedium), WithTags("home"))
+	add("Fix bug", "the report crashes", WithDueDate(day(-3)), WithPriority(PriorityUrgent), WithTags("work"))
+	add("Plan trip", "", WithDueDate(day(2)), WithPriority(PriorityHigh))
+	tm.UpdateTask(4, "Fix bug", "the report crashes", true)
+
+	done := false
+	tests := []struct {
+		name  string
+		query Query
+		ids   []int
+		total int
+	}{
+		{
+			name:  "everything by ID",
+			query: Query{},
+			ids:   []int{1, 2, 3, 4, 5},
+			total: 5,
+		},
+		{
+			name:  "tags must all match",
+			query: Query{Tags: []string{"HOME", "shopping"}},
+			ids:   []int{1},
+			total: 1,
+		},
+		{
+			name:  "any of the priorities",
+			query: Query{Priorities: []Priority{PriorityHigh, PriorityUrgent}},
+			ids:   []int{2, 4, 5},
+			total: 3,
+		},
+		{
+			name:  "overdue skips done tasks",
+			query: Query{Overdue: true, Now: now},
+			ids:   []int{1},
+			total: 1,
+		},
+		{
+			name:  "due before",
+			query: Query{DueBefore: day(1)},
+			ids:   []int{1, 4},
+			total: 2,
+		},
+		{
+			name:  "text matches title and description",
+			query: Query{Text: "REPORT"},
+			ids:   []int{2, 4},
+			total: 2,
+		},
+		{
+			name:  "every word must match",
+			query: Query{Text: "report crashes"},
+			ids:   []int{4},
+			total: 1,
+		},
+		{
+			name:  "open tasks",
+			query: Query{Done: &done},
+			ids:   []int{1, 2, 3, 5},
+			total: 4,
+		},
+		{
+			name:  "priority descending then due date",
+			query: Query{Sort: []SortKey{{Field: SortByPriority, Desc: true}, {Field: SortByDueDate}}},
+			ids:   []int{4, 2, 5, 3, 1},
+			total: 5,
+		},
+		{
+			name:  "missing due dates last even descending",
+			query: Query{Sort: []SortKey{{Field: SortByDueDate, Desc: true}}},
+			ids:   []int{2, 5, 1, 4, 3},
+			total: 5,
+		},
+		{
+			name:  "title",
+			query: Query{Sort: []SortKey{{Field: SortByTitle}}},
+			ids:   []int{1, 3, 4, 5, 2},
+			total: 5,
+		},
+		{
+			name:  "page",
+			query: Query{Sort: []SortKey{{Field: SortByTitle}}, Offset: 1, Limit: 2},
+			ids:   []int{3, 4},
+			total: 5,
+		},
+		{
+			name:  "offset past the end",
+			query: Query{Offset: 10},
+			ids:   []int{},
+			total: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks, total, err := tm.Find(tt.query)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			ids := []int{}
+			for _, task := range tasks {
+				ids = append(ids, task.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) {
+				t.Errorf("Expected tasks %v, got %v", tt.ids, ids)
+			}
+			if total != tt.total {
+				t.Errorf("Expected total %d, got %d", tt.total, total)
+			}
+		})
+	}
+}
+
+func TestFindInvalid(t *testing.T) {
+	tm := NewTaskManager()
+	for _, q := range []Query{
+		{Offset: -1},
+		{Limit: -1},
+		{Sort: []SortKey{{Field: "color"}}},
+	} {
+		if _, _, err := tm.Find(q); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Expected ErrInvalidQuery for %+v, got %v", q, err)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	keys, err := ParseSort("-priority, due_date,")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := []SortKey{{Field: SortByPriority, Desc: true}, {Field: SortByDueDate}}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("Expected %v, got %v", want, keys)
+	}
+	if _, err := ParseSort("-color"); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("Expected ErrInvalidQuery, got %v", err)
+	}
+}
@@ -0,0 +1,500 @@
+package taskmanager
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence errors
+var (
+	ErrInvalidRule   = errors.New("invalid recurrence rule")
+	ErrNotRecurring  = errors.New("task does not recur")
+	ErrNoOccurrences = errors.New("recurrence rule has no occurrences")
+)
+
+// Frequency is the FREQ of a rule
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// horizonYears bounds the search for occurrences of rules that never or
+// hardly ever match, such as the 31st of every twelfth month from February
+const horizonYears = 200
+
+// WeekdayNum is a BYDAY entry. N picks the Nth such weekday of the month,
+// counting from the end when negative; zero means every one.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+func (w WeekdayNum) String() string {
+	if w.N == 0 {
+		return weekdayCodes[w.Day]
+	}
+	return strconv.Itoa(w.N) + weekdayCodes[w.Day]
+}
+
+// Rule is the subset of an iCalendar (RFC 5545) RRULE made of FREQ,
+// INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL. Weeks start on Monday.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	Count      int
+	Until      time.Time
+	// untilLocal marks an UNTIL without a time zone, which is read in the
+	// zone of the occurrences
+	untilLocal bool
+}
+
+// clone returns a copy of r sharing no memory with it
+func (r Rule) clone() Rule {
+	r.ByDay = slices.Clone(r.ByDay)
+	r.ByMonthDay = slices.Clone(r.ByMonthDay)
+	return r
+}
+
+// ParseRule parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE,FR;COUNT=10".
+// An "RRULE:" prefix is allowed.
+func ParseRule(s string) (Rule, error) {
+	var r Rule
+	seen := map[string]bool{}
+	for _, part := range strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "RRULE:"), ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name = strings.ToUpper(strings.TrimSpace(name))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("%w: %q is not NAME=VALUE", ErrInvalidRule, part)
+		}
+		if seen[name] {
+			return Rule{}, fmt.Errorf("%w: %s given twice", ErrInvalidRule, name)
+		}
+		seen[name] = true
+
+		var err error
+		switch name {
+		case "FREQ":
+			r.Freq = Frequency(value)
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(value)
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				w, werr := parseWeekdayNum(v)
+				if werr != nil {
+					return Rule{}, werr
+				}
+				r.ByDay = append(r.ByDay, w)
+			}
+		case "BYMONTHDAY":
+			for _, v := range strings.Split(value, ",") {
+				day, derr := strconv.Atoi(v)
+				if derr != nil {
+					return Rule{}, fmt.Errorf("%w: BYMONTHDAY %q", ErrInvalidRule, v)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, day)
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(value)
+		case "UNTIL":
+			r.Until, r.untilLocal, err = parseUntil(value)
+		default:
+			return Rule{}, fmt.Errorf("%w: %s is not supported", ErrInvalidRule, name)
+		}
+		if err != nil {
+			return Rule{}, fmt.Errorf("%w: %s %q", ErrInvalidRule, name, value)
+		}
+	}
+	if !seen["INTERVAL"] {
+		r.Interval = 1
+	}
+	if err := r.Validate(); err != nil {
+		return Rule{}, err
+	}
+	return r, nil
+}
+
+// Validate checks that r is well formed and within the supported subset
+func (r Rule) Validate() error {
+	switch r.Freq {
+	case Daily, Weekly, Monthly, Yearly:
+	case "":
+		return fmt.Errorf("%w: FREQ is required", ErrInvalidRule)
+	default:
+		return fmt.Errorf("%w: FREQ %s is not supported", ErrInvalidRule, r.Freq)
+	}
+	if r.Interval < 1 {
+		return fmt.Errorf("%w: INTERVAL must be at least 1", ErrInvalidRule)
+	}
+	if r.Freq == Yearly && (len(r.ByDay) > 0 || len(r.ByMonthDay) > 0) {
+		return fmt.Errorf("%w: BYDAY and BYMONTHDAY are not supported with FREQ=YEARLY", ErrInvalidRule)
+	}
+	if r.Freq == Weekly && len(r.ByMonthDay) > 0 {
+		return fmt.Errorf("%w: BYMONTHDAY cannot be used with FREQ=WEEKLY", ErrInvalidRule)
+	}
+	for _, w := range r.ByDay {
+		if w.Day < time.Sunday || w.Day > time.Saturday || w.N < -5 || w.N > 5 {
+			return fmt.Errorf("%w: BYDAY %v", ErrInvalidRule, w)
+		}
+		if w.N != 0 && r.Freq != Monthly {
+			return fmt.Errorf("%w: BYDAY %v needs FREQ=MONTHLY", ErrInvalidRule, w)
+		}
+	}
+	for _, day := range r.ByMonthDay {
+		if day == 0 || day < -31 || day > 31 {
+			return fmt.Errorf("%w: BYMONTHDAY %d", ErrInvalidRule, day)
+		}
+	}
+	if r.Count < 0 {
+		return fmt.Errorf("%w: COUNT must be positive", ErrInvalidRule)
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return fmt.Errorf("%w: COUNT and UNTIL cannot both be set", ErrInvalidRule)
+	}
+	return nil
+}
+
+// String returns r in RRULE syntax without the "RRULE:" prefix
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, w := range r.ByDay {
+			days[i] = w.String()
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		if r.untilLocal {
+			parts = append(parts, "UNTIL="+r.Until.Format("20060102T150405"))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+		}
+	}
+	return strings.Join(parts, ";")
+}
+
+// MarshalText encodes r in RRULE syntax
+func (r Rule) MarshalText() ([]byte, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return []byte(r.String()), nil
+}
+
+func (r *Rule) UnmarshalText(b []byte) error {
+	v, err := ParseRule(string(b))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+// Between returns the occurrences of r in [from, to) for a series starting
+// at start. Occurrences keep the wall clock time of start in its location,
+// so a daily 9:00 stays at 9:00 across daylight saving changes.
+func (r Rule) Between(start, from, to time.Time) []time.Time {
+	var result []time.Time
+	r.each(start, to, func(t time.Time) bool {
+		if !t.Before(to) {
+			return false
+		}
+		if !t.Before(from) {
+			result = append(result, t)
+		}
+		return true
+	})
+	return result
+}
+
+// After returns the first occurrence of r after t for a series starting at
+// start, and false when the series has ended
+func (r Rule) After(start, t time.Time) (time.Time, bool) {
+	var next time.Time
+	r.each(start, time.Time{}, func(o time.Time) bool {
+		if o.After(t) {
+			next = o
+			return false
+		}
+		return true
+	})
+	return next, !next.IsZero()
+}
+
+// each calls fn with the occurrences in order until fn returns false, the
+// series ends or its periods begin after limit. A zero limit means
+// horizonYears after start.
+func (r Rule) each(start, limit time.Time, fn func(time.Time) bool) {
+	loc := start.Location()
+	if limit.IsZero() {
+		limit = start.AddDate(horizonYears, 0, 0)
+	}
+	until := r.Until
+	if r.untilLocal {
+		until = wallClock(r.Until, loc)
+	}
+	y, m, d := start.Date()
+	hour, minute, sec := start.Clock()
+	interval := max(r.Interval, 1)
+
+	n := 0
+	for period := 0; ; period++ {
+		var first time.Time
+		var days []time.Time
+		switch r.Freq {
+		case Daily:
+			first = date(y, m, d+period*interval)
+			if r.matchWeekday(first) && r.matchMonthDay(first) {
+				days = append(days, first)
+			}
+		case Weekly:
+			first = date(y, m, d-(int(start.Weekday())+6)%7+period*interval*7)
+			for i := range 7 {
+				day := first.AddDate(0, 0, i)
+				if len(r.ByDay) == 0 && day.Weekday() == start.Weekday() || len(r.ByDay) > 0 && r.matchWeekday(day) {
+					days = append(days, day)
+				}
+			}
+		case Monthly:
+			first = date(y, m+time.Month(period*interval), 1)
+			for day := first; day.Month() == first.Month(); day = day.AddDate(0, 0, 1) {
+				if len(r.ByDay) == 0 && len(r.ByMonthDay) == 0 && day.Day() == d ||
+					(len(r.ByDay) > 0 || len(r.ByMonthDay) > 0) && r.matchWeekday(day) && r.matchMonthDay(day) {
+					days = append(days, day)
+				}
+			}
+		case Yearly:
+			first = date(y+period*interval, 1, 1)
+			// February 29th only occurs in leap years
+			if day := date(first.Year(), m, d); day.Day() == d {
+				days = append(days, day)
+			}
+		default:
+			return
+		}
+		if wallClock(first, loc).After(limit) {
+			return
+		}
+
+		for _, day := range days {
+			t := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, sec, start.Nanosecond(), loc)
+			if t.Before(start) {
+				continue
+			}
+			if !until.IsZero() && t.After(until) {
+				return
+			}
+			if !fn(t) {
+				return
+			}
+			if n++; r.Count > 0 && n >= r.Count {
+				return
+			}
+		}
+	}
+}
+
+// matchWeekday reports whether day matches BYDAY, or true without BYDAY
+func (r Rule) matchWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	last := date(day.Year(), day.Month()+1, 0).Day()
+	for _, w := range r.ByDay {
+		if w.Day != day.Weekday() {
+			continue
+		}
+		switch {
+		case w.N == 0,
+			w.N > 0 && (day.Day()-1)/7+1 == w.N,
+			w.N < 0 && (last-day.Day())/7+1 == -w.N:
+			return true
+		}
+	}
+	return false
+}
+
+// matchMonthDay reports whether day matches BYMONTHDAY, or true without
+// BYMONTHDAY. Negative days count from the end of the month.
+func (r Rule) matchMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := date(day.Year(), day.Month()+1, 0).Day()
+	for _, md := range r.ByMonthDay {
+		if md == day.Day() || md < 0 && last+md+1 == day.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// Recurrence makes a task repeat. Marking an occurrence done adds the next
+// one as a new task.
+type Recurrence struct {
+	Rule Rule `json:"rule"`
+	// Start is the DTSTART of the series
+	Start time.Time `json:"start"`
+	// TimeZone names the location whose wall clock the series follows
+	TimeZone string `json:"time_zone"`
+	// NextID is the task added for the next occurrence once this one was
+	// done
+	NextID int `json:"next_id,omitempty"`
+}
+
+// WithRecurrence makes the task repeat by rule from start, in the time zone
+// of start, and sets its due date to the first occurrence. The zero Rule
+// stops the task from recurring.
+func WithRecurrence(rule Rule, start time.Time) TaskOption {
+	return func(t *Task) error {
+		if rule.Freq == "" {
+			t.Recurrence = nil
+			return nil
+		}
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		first, ok := rule.After(start, start.Add(-1))
+		if !ok {
+			return ErrNoOccurrences
+		}
+		t.Recurrence = &Recurrence{Rule: rule.clone(), Start: start, TimeZone: start.Location().String()}
+		t.DueDate = &first
+		return nil
+	}
+}
+
+// Location returns the time zone of the series. A zone the system does not
+// know falls back to the offset Start was saved with.
+func (r *Recurrence) Location() *time.Location {
+	if loc, err := time.LoadLocation(r.TimeZone); err == nil {
+		return loc
+	}
+	return r.Start.Location()
+}
+
+// next returns the occurrence following t
+func (r *Recurrence) next(t time.Time) (time.Time, bool) {
+	return r.Rule.After(r.Start.In(r.Location()), t)
+}
+
+// Occurrences returns the occurrences of the series task id belongs to
+// within [from, to). They follow the wall clock of the series in loc, so a
+// task recurring at 9:00 falls at 9:00 in loc; a nil loc means the time zone
+// the series was created in.
+func (tm *TaskManager) Occurrences(id int, from, to time.Time, loc *time.Location) ([]time.Time, error) {
+	t, err := tm.GetTask(id)
+	if err != nil {
+		return nil, err
+	}
+	if t.Recurrence == nil {
+		return nil, ErrNotRecurring
+	}
+	start := t.Recurrence.Start.In(t.Recurrence.Location())
+	if loc != nil {
+		start = wallClock(start, loc)
+	}
+	return t.Recurrence.Rule.Between(start, from, to), nil
+}
+
+// spawnNext prepares the task for the occurrence after t, which has just
+// been marked done, and links t to it. The caller holds tm.mu.
+func (tm *TaskManager) spawnNext(t *Task, now time.Time) (Task, bool) {
+	after := now
+	if t.DueDate != nil {
+		after = *t.DueDate
+	}
+	due, ok := t.Recurrence.next(after)
+	if !ok {
+		return Task{}, false
+	}
+	next := *t
+	next.ID = tm.nextID
+	next.Done = false
+	next.DueDate = &due
+	next.CreatedAt, next.UpdatedAt = now, now
+	rec := *t.Recurrence
+	rec.NextID = 0
+	next.Recurrence = &rec
+
+	done := *t.Recurrence
+	done.NextID = next.ID
+	t.Recurrence = &done
+	return next, true
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("%w: BYDAY %q", ErrInvalidRule, s)
+	}
+	day := slices.Index(weekdayCodes, s[len(s)-2:])
+	if day < 0 {
+		return WeekdayNum{}, fmt.Errorf("%w: BYDAY %q", ErrInvalidRule, s)
+	}
+	w := WeekdayNum{Day: time.Weekday(day)}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 {
+			return WeekdayNum{}, fmt.Errorf("%w: BYDAY %q", ErrInvalidRule, s)
+		}
+		w.N = n
+	}
+	return w, nil
+}
+
+// parseUntil parses a UTC or floating date-time, or a date meaning the end
+// of that day
+func parseUntil(s string) (time.Time, bool, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse("20060102T150405", s); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse("20060102", s)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t.Add(24*time.Hour - time.Second), true, nil
+}
+
+// date returns midnight UTC of a calendar day, normalizing overflow
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// wallClock returns the time showing the same date and clock as t in loc
+func wallClock(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.Date()
+	hour, minute, sec := t.Clock()
+	return time.Date(y, m, d, hour, minute, sec, t.Nanosecond(), loc)
+}
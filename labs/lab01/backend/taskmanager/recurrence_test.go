@@ -0,0 +1,329 @@
+package taskmanager
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{name: "daily", input: "FREQ=DAILY", want: "FREQ=DAILY"},
+		{name: "prefix and case", input: "RRULE:freq=weekly;byday=mo,we,fr;", want: "FREQ=WEEKLY;BYDAY=MO,WE,FR"},
+		{name: "interval and count", input: "FREQ=WEEKLY;INTERVAL=2;COUNT=10", want: "FREQ=WEEKLY;INTERVAL=2;COUNT=10"},
+		{name: "ordinal weekdays", input: "FREQ=MONTHLY;BYDAY=-1FR,2MO", want: "FREQ=MONTHLY;BYDAY=-1FR,2MO"},
+		{name: "month days", input: "FREQ=MONTHLY;BYMONTHDAY=1,-1", want: "FREQ=MONTHLY;BYMONTHDAY=1,-1"},
+		{name: "utc until", input: "FREQ=DAILY;UNTIL=20250731T090000Z", want: "FREQ=DAILY;UNTIL=20250731T090000Z"},
+		{name: "floating until", input: "FREQ=DAILY;UNTIL=20250731T090000", want: "FREQ=DAILY;UNTIL=20250731T090000"},
+		{name: "date until is the end of the day", input: "FREQ=DAILY;UNTIL=20250731", want: "FREQ=DAILY;UNTIL=20250731T235959"},
+		{name: "missing freq", input: "INTERVAL=2", wantErr: true},
+		{name: "unknown freq", input: "FREQ=HOURLY", wantErr: true},
+		{name: "unsupported part", input: "FREQ=YEARLY;BYMONTH=3", wantErr: true},
+		{name: "zero interval", input: "FREQ=DAILY;INTERVAL=0", wantErr: true},
+		{name: "bad weekday", input: "FREQ=WEEKLY;BYDAY=XX", wantErr: true},
+		{name: "ordinal outside monthly", input: "FREQ=WEEKLY;BYDAY=1MO", wantErr: true},
+		{name: "month day out of range", input: "FREQ=MONTHLY;BYMONTHDAY=32", wantErr: true},
+		{name: "month day with weekly", input: "FREQ=WEEKLY;BYMONTHDAY=1", wantErr: true},
+		{name: "count and until", input: "FREQ=DAILY;COUNT=2;UNTIL=20250731", wantErr: true},
+		{name: "repeated part", input: "FREQ=DAILY;FREQ=WEEKLY", wantErr: true},
+		{name: "not name=value", input: "FREQ", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRule(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidRule) {
+					t.Errorf("Expected ErrInvalidRule, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got := r.String(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		to    string
+		want  []string
+	}{
+		{
+			name:  "every other day, three times",
+			rule:  "FREQ=DAILY;INTERVAL=2;COUNT=3",
+			start: "2025-07-01 09:00",
+			to:    "2025-08-01 00:00",
+			want:  []string{"2025-07-01 09:00", "2025-07-03 09:00", "2025-07-05 09:00"},
+		},
+		{
+			name:  "weekdays",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+			start: "2025-07-04 09:00",
+			to:    "2025-07-09 00:00",
+			want:  []string{"2025-07-04 09:00", "2025-07-07 09:00", "2025-07-08 09:00"},
+		},
+		{
+			name:  "every other week on two days",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH",
+			start: "2025-07-01 18:30",
+			to:    "2025-07-20 00:00",
+			want:  []string{"2025-07-01 18:30", "2025-07-03 18:30", "2025-07-15 18:30", "2025-07-17 18:30"},
+		},
+		{
+			name:  "weekly on the start day",
+			rule:  "FREQ=WEEKLY",
+			start: "2025-07-02 07:00",
+			to:    "2025-07-17 00:00",
+			want:  []string{"2025-07-02 07:00", "2025-07-09 07:00", "2025-07-16 07:00"},
+		},
+		{
+			name:  "31st skips short months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: "2025-01-31 12:00",
+			to:    "2025-06-01 00:00",
+			want:  []string{"2025-01-31 12:00", "2025-03-31 12:00", "2025-05-31 12:00"},
+		},
+		{
+			name:  "last day of the month",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: "2025-01-31 12:00",
+			to:    "2025-04-01 00:00",
+			want:  []string{"2025-01-31 12:00", "2025-02-28 12:00", "2025-03-31 12:00"},
+		},
+		{
+			name:  "last friday",
+			rule:  "FREQ=MONTHLY;BYDAY=-1FR",
+			start: "2025-07-01 16:00",
+			to:    "2025-10-01 00:00",
+			want:  []string{"2025-07-25 16:00", "2025-08-29 16:00", "2025-09-26 16:00"},
+		},
+		{
+			name:  "second monday",
+			rule:  "FREQ=MONTHLY;BYDAY=2MO",
+			start: "2025-07-01 10:00",
+			to:    "2025-10-01 00:00",
+			want:  []string{"2025-07-14 10:00", "2025-08-11 10:00", "2025-09-08 10:00"},
+		},
+		{
+			name:  "friday the 13th",
+			rule:  "FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+			start: "2025-01-01 00:00",
+			to:    "2026-12-31 00:00",
+			want:  []string{"2025-06-13 00:00", "2026-02-13 00:00", "2026-03-13 00:00", "2026-11-13 00:00"},
+		},
+		{
+			name:  "leap day",
+			rule:  "FREQ=YEARLY",
+			start: "2024-02-29 08:00",
+			to:    "2030-01-01 00:00",
+			want:  []string{"2024-02-29 08:00", "2028-02-29 08:00"},
+		},
+		{
+			name:  "until is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20250703T090000Z",
+			start: "2025-07-01 09:00",
+			to:    "2025-08-01 00:00",
+			want:  []string{"2025-07-01 09:00", "2025-07-02 09:00", "2025-07-03 09:00"},
+		},
+		{
+			name:  "count ends the series",
+			rule:  "FREQ=DAILY;COUNT=5",
+			start: "2025-07-01 09:00",
+			to:    "2025-08-01 00:00",
+			want:  []string{"2025-07-01 09:00", "2025-07-02 09:00", "2025-07-03 09:00", "2025-07-04 09:00", "2025-07-05 09:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := ParseRule(tt.rule)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got := []string{}
+			for _, o := range r.Between(at(tt.start), at(tt.start), at(tt.to)) {
+				got = append(got, o.Format("2006-01-02 15:04"))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// COUNT counts from the start of the series, not the window
+	r, _ := ParseRule("FREQ=DAILY;COUNT=5")
+	got := r.Between(at("2025-07-01 09:00"), at("2025-07-04 00:00"), at("2025-08-01 00:00"))
+	if len(got) != 2 {
+		t.Errorf("Expected 2 occurrences in the window, got %v", got)
+	}
+}
+
+func TestBetweenKeepsWallClock(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, _ := ParseRule("FREQ=DAILY")
+	start := time.Date(2025, 3, 29, 9, 0, 0, 0, berlin)
+	got := r.Between(start, start, start.AddDate(0, 0, 3))
+	if len(got) != 3 {
+		t.Fatalf("Expected 3 occurrences, got %v", got)
+	}
+	for _, o := range got {
+		if o.Hour() != 9 {
+			t.Errorf("Expected 09:00 in Berlin, got %v", o)
+		}
+	}
+	// Clocks moved forward on March 30th, so that day is an hour shorter
+	if d := got[2].Sub(got[1]); d != 24*time.Hour {
+		t.Errorf("Expected 24h between regular days, got %v", d)
+	}
+	if d := got[1].Sub(got[0]); d != 23*time.Hour {
+		t.Errorf("Expected 23h across the change, got %v", d)
+	}
+}
+
+func TestRecurringTask(t *testing.T) {
+	moscow, err := time.LoadLocation("Europe/Moscow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	tm := reopen(t, nil, path)
+
+	rule, _ := ParseRule("FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;COUNT=3")
+	// Saturday, so the first occurrence is on Monday
+	start := time.Date(2025, 7, 5, 9, 0, 0, 0, moscow)
+	task, err := tm.AddTask("Stretch", "", WithRecurrence(rule, start), WithTags("health"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := time.Date(2025, 7, 7, 9, 0, 0, 0, moscow); !task.DueDate.Equal(want) {
+		t.Errorf("Expected first occurrence %v, got %v", want, task.DueDate)
+	}
+
+	if err := tm.UpdateTask(task.ID, task.Title, "", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	done, _ := tm.GetTask(task.ID)
+	if done.Recurrence.NextID == 0 {
+		t.Fatal("Expected the done task to link to the next occurrence")
+	}
+	next, err := tm.GetTask(done.Recurrence.NextID)
+	if err != nil {
+		t.Fatalf("Next occurrence not found: %v", err)
+	}
+	if want := time.Date(2025, 7, 8, 9, 0, 0, 0, moscow); next.Done || !next.DueDate.Equal(want) {
+		t.Errorf("Expected an open task due %v, got done=%v due %v", want, next.Done, next.DueDate)
+	}
+	if next.Title != "Stretch" || !next.HasTag("health") {
+		t.Errorf("Expected the next occurrence to copy the task, got %+v", next)
+	}
+
+	// Reopening and finishing again must not add a second occurrence
+	tm.UpdateTask(task.ID, task.Title, "", false)
+	tm.UpdateTask(task.ID, task.Title, "", true)
+	if n := len(tm.ListTasks(nil)); n != 2 {
+		t.Errorf("Expected 2 tasks, got %d", n)
+	}
+
+	// The series survives a restart, zone included
+	tm = reopen(t, tm, path)
+	next, _ = tm.GetTask(next.ID)
+	if got := next.Recurrence.Rule.String(); got != rule.String() {
+		t.Errorf("Expected rule %s, got %s", rule, got)
+	}
+	if got := next.Recurrence.Location().String(); got != "Europe/Moscow" {
+		t.Errorf("Expected time zone Europe/Moscow, got %s", got)
+	}
+	tm.UpdateTask(next.ID, next.Title, "", true)
+	last, err := tm.GetTask(next.ID + 1)
+	if err != nil {
+		t.Fatalf("Third occurrence not found: %v", err)
+	}
+	// COUNT=3 ends the series
+	tm.UpdateTask(last.ID, last.Title, "", true)
+	if n := len(tm.ListTasks(nil)); n != 3 {
+		t.Errorf("Expected the series to end after 3 tasks, got %d", n)
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	moscow, _ := time.LoadLocation("Europe/Moscow")
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	tm := NewTaskManager()
+	rule, _ := ParseRule("FREQ=DAILY")
+	task, err := tm.AddTask("Stretch", "", WithRecurrence(rule, time.Date(2025, 7, 1, 9, 0, 0, 0, moscow)))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	plain, _ := tm.AddTask("Once", "")
+
+	from := time.Date(2025, 7, 10, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 2)
+	tests := []struct {
+		name string
+		loc  *time.Location
+		want []time.Time
+	}{
+		{
+			name: "series time zone",
+			want: []time.Time{time.Date(2025, 7, 10, 9, 0, 0, 0, moscow), time.Date(2025, 7, 11, 9, 0, 0, 0, moscow)},
+		},
+		{
+			name: "other time zone",
+			loc:  tokyo,
+			want: []time.Time{time.Date(2025, 7, 10, 9, 0, 0, 0, tokyo), time.Date(2025, 7, 11, 9, 0, 0, 0, tokyo)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tm.Occurrences(task.ID, from, to, tt.loc)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("Expected %v, got %v", tt.want[i], got[i])
+				}
+			}
+		})
+	}
+
+	if _, err := tm.Occurrences(plain.ID, from, to, nil); err != ErrNotRecurring {
+		t.Errorf("Expected ErrNotRecurring, got %v", err)
+	}
+	if _, err := tm.Occurrences(999, from, to, nil); err != ErrTaskNotFound {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+	never, _ := ParseRule("FREQ=DAILY;UNTIL=20250101T000000Z")
+	if _, err := tm.AddTask("Never", "", WithRecurrence(never, time.Date(2025, 7, 1, 9, 0, 0, 0, moscow))); err != ErrNoOccurrences {
+		t.Errorf("Expected ErrNoOccurrences, got %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package taskmanager
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Relation errors
+var (
+	ErrCycle   = errors.New("relation would create a cycle")
+	ErrBlocked = errors.New("task is blocked by open tasks")
+)
+
+// A task can have a parent, making it a subtask, and can be blocked by other
+// tasks, which must be done before it can be. Relations to deleted tasks are
+// kept but ignored: a deleted blocker no longer blocks and the subtasks of a
+// deleted task count as top-level. IDs are never reused, so such a relation
+// cannot point to another task later.
+//
+// For ordering, a task comes after its blockers and after its subtasks, and
+// these edges together must stay acyclic.
+
+// AddSubtask adds a task under parentID
+func (tm *TaskManager) AddSubtask(parentID int, title, description string, opts ...TaskOption) (Task, error) {
+	if _, err := tm.GetTask(parentID); err != nil {
+		return Task{}, err
+	}
+	return tm.AddTask(title, description, append([]TaskOption{withParent(parentID)}, opts...)...)
+}
+
+// SetParent moves task id under parentID, or to the top level when parentID
+// is 0
+func (tm *TaskManager) SetParent(id, parentID int) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	t, exists := tm.tasks[id]
+	if !exists {
+		return ErrTaskNotFound
+	}
+	if parentID != 0 {
+		if _, exists := tm.tasks[parentID]; !exists {
+			return ErrTaskNotFound
+		}
+		// The subtask comes before its parent
+		if err := tm.checkEdge(id, parentID); err != nil {
+			return err
+		}
+	}
+	if t.ParentID == parentID {
+		return nil
+	}
+	t.ParentID = parentID
+	return tm.commitRelation(t)
+}
+
+// AddBlocker makes task id wait for blockerID to be done
+func (tm *TaskManager) AddBlocker(id, blockerID int) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	t, exists := tm.tasks[id]
+	if !exists {
+		return ErrTaskNotFound
+	}
+	if _, exists := tm.tasks[blockerID]; !exists {
+		return ErrTaskNotFound
+	}
+	if err := tm.checkEdge(blockerID, id); err != nil {
+		return err
+	}
+	if slices.Contains(t.BlockedBy, blockerID) {
+		return nil
+	}
+	t.BlockedBy = append(slices.Clone(t.BlockedBy), blockerID)
+	slices.Sort(t.BlockedBy)
+	return tm.commitRelation(t)
+}
+
+// RemoveBlocker stops task id from waiting for blockerID
+func (tm *TaskManager) RemoveBlocker(id, blockerID int) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	t, exists := tm.tasks[id]
+	if !exists {
+		return ErrTaskNotFound
+	}
+	if !slices.Contains(t.BlockedBy, blockerID) {
+		return nil
+	}
+	t.BlockedBy = slices.DeleteFunc(slices.Clone(t.BlockedBy), func(b int) bool { return b == blockerID })
+	return tm.commitRelation(t)
+}
+
+// Subtasks returns the direct subtasks of id ordered by ID
+func (tm *TaskManager) Subtasks(id int) ([]Task, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	if _, exists := tm.tasks[id]; !exists {
+		return nil, ErrTaskNotFound
+	}
+	var result []Task
+	for _, t := range sortedTasks(tm.tasks) {
+		if t.ParentID == id {
+			result = append(result, t.clone())
+		}
+	}
+	return result, nil
+}
+
+// OpenBlockers returns the IDs of the tasks that keep id from being done
+func (tm *TaskManager) OpenBlockers(id int) ([]int, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	t, exists := tm.tasks[id]
+	if !exists {
+		return nil, ErrTaskNotFound
+	}
+	return tm.openBlockers(t), nil
+}
+
+// Progress returns how much of task id is done, from 0 to 100. A done task
+// is complete; otherwise it is the average progress of its subtasks, or 0
+// without subtasks.
+func (tm *TaskManager) Progress(id int) (float64, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	if _, exists := tm.tasks[id]; !exists {
+		return 0, ErrTaskNotFound
+	}
+	return tm.progress(id, tm.children()), nil
+}
+
+func (tm *TaskManager) progress(id int, children map[int][]int) float64 {
+	if tm.tasks[id].Done {
+		return 100
+	}
+	kids := children[id]
+	if len(kids) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, kid := range kids {
+		sum += tm.progress(kid, children)
+	}
+	return sum / float64(len(kids))
+}
+
+// Next returns the open tasks in an order they can be done in: every task
+// comes after its open blockers and open subtasks. Among tasks that are
+// ready at the same time, higher priority comes first, then the earlier due
+// date, then the lower ID.
+func (tm *TaskManager) Next() []Task {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+
+	// waiting counts the open prerequisites of each open task and unlocks
+	// lists the tasks each one is a prerequisite of
+	waiting := map[int]int{}
+	unlocks := map[int][]int{}
+	children := tm.children()
+	for id, t := range tm.tasks {
+		if t.Done {
+			continue
+		}
+		waiting[id] = 0
+		for _, pre := range append(slices.Clone(t.BlockedBy), children[id]...) {
+			if p, exists := tm.tasks[pre]; exists && !p.Done {
+				waiting[id]++
+				unlocks[pre] = append(unlocks[pre], id)
+			}
+		}
+	}
+
+	ready := &readyQueue{}
+	for id, n := range waiting {
+		if n == 0 {
+			ready.tasks = append(ready.tasks, tm.tasks[id])
+		}
+	}
+	heap.Init(ready)
+	result := make([]Task, 0, len(waiting))
+	for ready.Len() > 0 {
+		t := heap.Pop(ready).(Task)
+		result = append(result, t.clone())
+		for _, id := range unlocks[t.ID] {
+			if waiting[id]--; waiting[id] == 0 {
+				heap.Push(ready, tm.tasks[id])
+			}
+		}
+	}
+	return result
+}
+
+// withParent sets the parent of a new task
+func withParent(parentID int) TaskOption {
+	return func(t *Task) error {
+		t.ParentID = parentID
+		return nil
+	}
+}
+
+// commitRelation saves a change to the relations of t. The caller holds
+// tm.mu.
+func (tm *TaskManager) commitRelation(t Task) error {
+	t.UpdatedAt = time.Now()
+	return tm.commit(Op{Type: OpUpdate, Task: &t})
+}
+
+// checkEdge returns ErrCycle unless before can be made to come before after.
+// That fails when after already comes before before. The caller holds tm.mu.
+func (tm *TaskManager) checkEdge(before, after int) error {
+	if before == after {
+		return fmt.Errorf("%w: task %d cannot depend on itself", ErrCycle, before)
+	}
+	children := tm.children()
+	seen := map[int]bool{}
+	stack := []int{before}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if id == after {
+			return fmt.Errorf("%w: task %d already comes before task %d", ErrCycle, after, before)
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		stack = append(stack, tm.tasks[id].BlockedBy...)
+		stack = append(stack, children[id]...)
+	}
+	return nil
+}
+
+// openBlockers returns the blockers of t that exist and are not done. The
+// caller holds tm.mu.
+func (tm *TaskManager) openBlockers(t Task) []int {
+	var open []int
+	for _, id := range t.BlockedBy {
+		if b, exists := tm.tasks[id]; exists && !b.Done {
+			open = append(open, id)
+		}
+	}
+	return open
+}
+
+// children maps each task to its subtasks in ID order. The caller holds
+// tm.mu.
+func (tm *TaskManager) children() map[int][]int {
+	children := map[int][]int{}
+	for _, t := range sortedTasks(tm.tasks) {
+		if _, exists := tm.tasks[t.ParentID]; exists {
+			children[t.ParentID] = append(children[t.ParentID], t.ID)
+		}
+	}
+	return children
+}
+
+// readyQueue orders the tasks Next can hand out
+type readyQueue struct {
+	tasks []Task
+}
+
+func (q *readyQueue) Len() int { return len(q.tasks) }
+
+func (q *readyQueue) Less(i, j int) bool {
+	a, b := q.tasks[i], q.tasks[j]
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if (a.DueDate == nil) != (b.DueDate == nil) {
+		return a.DueDate != nil
+	}
+	if a.DueDate != nil && !a.DueDate.Equal(*b.DueDate) {
+		return a.DueDate.Before(*b.DueDate)
+	}
+	return a.ID < b.ID
+}
+
+func (q *readyQueue) Swap(i, j int) { q.tasks[i], q.tasks[j] = q.tasks[j], q.tasks[i] }
+
+func (q *readyQueue) Push(x any) { q.tasks = append(q.tasks, x.(Task)) }
+
+func (q *readyQueue) Pop() any {
+	t := q.tasks[len(q.tasks)-1]
+	q.tasks = q.tasks[:len(q.tasks)-1]
+	return t
+}
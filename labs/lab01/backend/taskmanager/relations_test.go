@@ -0,0 +1,179 @@
+package taskmanager
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRelationCycles(t *testing.T) {
+	tm := NewTaskManager()
+	for _, title := range []string{"A", "B", "C", "D"} {
+		tm.AddTask(title, "")
+	}
+	// B waits for A, C waits for B, D is a subtask of C
+	if err := tm.AddBlocker(2, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := tm.AddBlocker(3, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := tm.SetParent(4, 3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		do   func() error
+		want error
+	}{
+		{name: "blocked by itself", do: func() error { return tm.AddBlocker(1, 1) }, want: ErrCycle},
+		{name: "direct cycle", do: func() error { return tm.AddBlocker(1, 2) }, want: ErrCycle},
+		{name: "transitive cycle", do: func() error { return tm.AddBlocker(1, 3) }, want: ErrCycle},
+		{name: "parent of itself", do: func() error { return tm.SetParent(1, 1) }, want: ErrCycle},
+		{name: "parent under its subtask", do: func() error { return tm.SetParent(3, 4) }, want: ErrCycle},
+		{name: "subtask blocked by its parent", do: func() error { return tm.AddBlocker(4, 3) }, want: ErrCycle},
+		{name: "subtask of a task it waits for", do: func() error { return tm.SetParent(3, 1) }, want: ErrCycle},
+		{name: "unknown blocker", do: func() error { return tm.AddBlocker(1, 99) }, want: ErrTaskNotFound},
+		{name: "unknown parent", do: func() error { return tm.SetParent(1, 99) }, want: ErrTaskNotFound},
+		{name: "allowed edge", do: func() error { return tm.AddBlocker(4, 1) }},
+		{name: "repeated edge", do: func() error { return tm.AddBlocker(4, 1) }},
+		{name: "back to the top level", do: func() error { return tm.SetParent(4, 0) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.do(); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	d, _ := tm.GetTask(4)
+	if d.ParentID != 0 || !reflect.DeepEqual(d.BlockedBy, []int{1}) {
+		t.Errorf("Expected D at the top level blocked by A, got parent %d and blockers %v", d.ParentID, d.BlockedBy)
+	}
+	if err := tm.RemoveBlocker(4, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if d, _ := tm.GetTask(4); len(d.BlockedBy) != 0 {
+		t.Errorf("Expected no blockers, got %v", d.BlockedBy)
+	}
+}
+
+func TestBlockedCompletion(t *testing.T) {
+	tm := NewTaskManager()
+	a, _ := tm.AddTask("A", "")
+	b, _ := tm.AddTask("B", "")
+	c, _ := tm.AddTask("C", "")
+	tm.AddBlocker(c.ID, a.ID)
+	tm.AddBlocker(c.ID, b.ID)
+
+	err := tm.UpdateTask(c.ID, "C", "", true)
+	if !errors.Is(err, ErrBlocked) {
+		t.Fatalf("Expected ErrBlocked, got %v", err)
+	}
+	if open, _ := tm.OpenBlockers(c.ID); !reflect.DeepEqual(open, []int{a.ID, b.ID}) {
+		t.Errorf("Expected open blockers %v, got %v", []int{a.ID, b.ID}, open)
+	}
+	// Editing without finishing is still allowed
+	if err := tm.UpdateTask(c.ID, "C renamed", "", false); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	tm.UpdateTask(a.ID, "A", "", true)
+	// A deleted blocker no longer blocks
+	tm.DeleteTask(b.ID)
+	if err := tm.UpdateTask(c.ID, "C", "", true); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	tm := NewTaskManager()
+	root, _ := tm.AddTask("Move house", "")
+	pack, _ := tm.AddSubtask(root.ID, "Pack", "")
+	books, _ := tm.AddSubtask(pack.ID, "Books", "")
+	kitchen, _ := tm.AddSubtask(pack.ID, "Kitchen", "")
+	tm.AddSubtask(pack.ID, "Clothes", "")
+	van, _ := tm.AddSubtask(root.ID, "Book a van", "")
+	tm.UpdateTask(books.ID, "Books", "", true)
+	tm.UpdateTask(van.ID, "Book a van", "", true)
+
+	tests := []struct {
+		id   int
+		want float64
+	}{
+		{id: root.ID, want: (100.0/3 + 100) / 2},
+		{id: pack.ID, want: 100.0 / 3},
+		{id: van.ID, want: 100},
+		{id: kitchen.ID, want: 0},
+	}
+	for _, tt := range tests {
+		got, err := tm.Progress(tt.id)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("Expected progress %.2f for task %d, got %.2f", tt.want, tt.id, got)
+		}
+	}
+
+	subtasks, _ := tm.Subtasks(root.ID)
+	if len(subtasks) != 2 || subtasks[0].ID != pack.ID || subtasks[1].ID != van.ID {
+		t.Errorf("Expected subtasks %d and %d, got %+v", pack.ID, van.ID, subtasks)
+	}
+	if _, err := tm.Progress(99); err != ErrTaskNotFound {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+	if _, err := tm.AddSubtask(99, "Orphan", ""); err != ErrTaskNotFound {
+		t.Errorf("Expected ErrTaskNotFound, got %v", err)
+	}
+}
+
+func TestNext(t *testing.T) {
+	tm := NewTaskManager()
+	due := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	write, _ := tm.AddTask("Write report", "", WithPriority(PriorityHigh))
+	data, _ := tm.AddTask("Collect data", "", WithPriority(PriorityLow))
+	review, _ := tm.AddTask("Review", "", WithPriority(PriorityUrgent))
+	mail, _ := tm.AddTask("Answer mail", "", WithPriority(PriorityLow), WithDueDate(due))
+	tm.AddSubtask(write.ID, "Draw charts", "")
+	done, _ := tm.AddTask("Done already", "")
+	tm.UpdateTask(done.ID, done.Title, "", true)
+
+	tm.AddBlocker(write.ID, data.ID)
+	tm.AddBlocker(review.ID, write.ID)
+	// A done blocker does not hold anything back
+	tm.AddBlocker(mail.ID, done.ID)
+
+	var got []string
+	for _, task := range tm.Next() {
+		got = append(got, task.Title)
+	}
+	// Low priority tasks come first when something more important waits on
+	// them; of the two, the one with a due date wins
+	want := []string{"Answer mail", "Collect data", "Draw charts", "Write report", "Review"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestRelationsPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	tm := reopen(t, nil, path)
+	parent, _ := tm.AddTask("Parent", "")
+	child, _ := tm.AddSubtask(parent.ID, "Child", "")
+	tm.AddBlocker(parent.ID, child.ID)
+
+	tm = reopen(t, tm, path)
+	got, _ := tm.GetTask(child.ID)
+	if got.ParentID != parent.ID {
+		t.Errorf("Expected parent %d, got %d", parent.ID, got.ParentID)
+	}
+	if got, _ := tm.GetTask(parent.ID); !reflect.DeepEqual(got.BlockedBy, []int{child.ID}) {
+		t.Errorf("Expected blockers %v, got %v", []int{child.ID}, got.BlockedBy)
+	}
+}
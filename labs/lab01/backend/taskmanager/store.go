@@ -0,0 +1,284 @@
+package taskmanager
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Operation types recorded in the log
+const (
+	OpAdd    = "add"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// State is everything a TaskManager needs to resume: its tasks, the ID the
+// next task gets and the history of changes. Seq is the sequence number of
+// the last operation included.
+type State struct {
+	Seq     uint64 `json:"seq"`
+	NextID  int    `json:"next_id"`
+	Tasks   []Task `json:"tasks"`
+	History []Op   `json:"history,omitempty"`
+}
+
+// Op is one change to the tasks. Add and update carry the whole task so
+// replaying an operation never depends on the state it was applied to;
+// update and delete keep the task as it was before, so the change can be
+// undone.
+type Op struct {
+	Seq    uint64    `json:"seq"`
+	Time   time.Time `json:"time"`
+	Type   string    `json:"type"`
+	Task   *Task     `json:"task,omitempty"`
+	ID     int       `json:"id,omitempty"`
+	Before *Task     `json:"before,omitempty"`
+	// Batch is the Seq of the first op of the change this op belongs to
+	Batch uint64 `json:"batch,omitempty"`
+	// Reverts is the batch an undo or redo reverted
+	Reverts uint64 `json:"reverts,omitempty"`
+}
+
+// taskID returns the ID of the task op changes
+func (op Op) taskID() int {
+	if op.Task != nil {
+		return op.Task.ID
+	}
+	return op.ID
+}
+
+// clone returns a copy of op whose tasks share no memory with those of op
+func (op Op) clone() Op {
+	if op.Task != nil {
+		t := op.Task.clone()
+		op.Task = &t
+	}
+	if op.Before != nil {
+		before := op.Before.clone()
+		op.Before = &before
+	}
+	return op
+}
+
+// batch returns the batch of op. Ops saved before batches existed are
+// changes on their own.
+func (op Op) batch() uint64 {
+	if op.Batch == 0 {
+		return op.Seq
+	}
+	return op.Batch
+}
+
+// Store persists the tasks of a TaskManager
+type Store interface {
+	// Load returns the saved state with every logged operation applied
+	Load() (State, error)
+	// Append durably records the ops of one change before they are
+	// applied. Either all of them are kept or none.
+	Append(ops ...Op) error
+	// Snapshot saves s and drops the operations it includes
+	Snapshot(s State) error
+	Close() error
+}
+
+// FileStore keeps a JSON snapshot at its path and logs the changes since
+// that snapshot, one per line, in a file next to it with a ".log" suffix.
+// A change is a JSON object, or an array of them when it has several
+// operations.
+type FileStore struct {
+	mu   sync.Mutex
+	path string
+	log  *os.File
+}
+
+// OpenFileStore opens the snapshot at path, creating its directory and log
+// as needed
+func OpenFileStore(path string) (*FileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("taskmanager: %w", err)
+	}
+	log, err := os.OpenFile(path+".log", os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("taskmanager: open log: %w", err)
+	}
+	return &FileStore{path: path, log: log}, nil
+}
+
+// Load reads the snapshot and replays the log on top of it. A torn last
+// line, left by a crash in the middle of Append, is discarded with every
+// operation of its change.
+func (s *FileStore) Load() (State, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := State{NextID: 1}
+	b, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return State{}, fmt.Errorf("taskmanager: read snapshot: %w", err)
+	default:
+		if err := json.Unmarshal(b, &state); err != nil {
+			return State{}, fmt.Errorf("taskmanager: parse snapshot %s: %w", s.path, err)
+		}
+	}
+
+	if _, err := s.log.Seek(0, io.SeekStart); err != nil {
+		return State{}, fmt.Errorf("taskmanager: read log: %w", err)
+	}
+	tasks := make(map[int]Task, len(state.Tasks))
+	for _, t := range state.Tasks {
+		tasks[t.ID] = t
+	}
+	r := bufio.NewReader(s.log)
+	var good int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				// Torn write: cut it off so the next append starts a clean line
+				if err := s.log.Truncate(good); err != nil {
+					return State{}, fmt.Errorf("taskmanager: truncate log: %w", err)
+				}
+			}
+			break
+		}
+		if err != nil {
+			return State{}, fmt.Errorf("taskmanager: read log: %w", err)
+		}
+		ops, err := parseEntry(line)
+		if err != nil {
+			return State{}, fmt.Errorf("taskmanager: corrupt log entry at byte %d: %w", good, err)
+		}
+		good += int64(len(line))
+		for _, op := range ops {
+			// Entries the snapshot already includes remain when a crash hit
+			// between writing the snapshot and truncating the log
+			if op.Seq <= state.Seq {
+				continue
+			}
+			apply(tasks, &state.NextID, op)
+			state.Seq = op.Seq
+			state.History = append(state.History, op)
+		}
+	}
+
+	state.Tasks = sortedTasks(tasks)
+	return state, nil
+}
+
+// Append writes ops as one line and syncs it to disk
+func (s *FileStore) Append(ops ...Op) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	var entry any = ops
+	if len(ops) == 1 {
+		entry = ops[0]
+	}
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("taskmanager: encode op: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.log.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("taskmanager: append: %w", err)
+	}
+	if err := s.log.Sync(); err != nil {
+		return fmt.Errorf("taskmanager: sync log: %w", err)
+	}
+	return nil
+}
+
+// Snapshot writes st to a temporary file and renames it over the snapshot,
+// so a crash leaves either the old or the new snapshot, then empties the log
+func (s *FileStore) Snapshot(st State) error {
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("taskmanager: encode snapshot: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dir := filepath.Dir(s.path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("taskmanager: snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("taskmanager: write snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("taskmanager: sync snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("taskmanager: write snapshot: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("taskmanager: replace snapshot: %w", err)
+	}
+	syncDir(dir)
+
+	if err := s.log.Truncate(0); err != nil {
+		return fmt.Errorf("taskmanager: truncate log: %w", err)
+	}
+	return nil
+}
+
+// parseEntry decodes a line of the log: one op, or the ops of a change as an
+// array
+func parseEntry(line []byte) ([]Op, error) {
+	if b := bytes.TrimSpace(line); len(b) > 0 && b[0] == '[' {
+		var ops []Op
+		if err := json.Unmarshal(b, &ops); err != nil {
+			return nil, err
+		}
+		return ops, nil
+	}
+	var op Op
+	if err := json.Unmarshal(line, &op); err != nil {
+		return nil, err
+	}
+	return []Op{op}, nil
+}
+
+// Close closes the log
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
+
+// syncDir makes a rename in dir durable. Not every platform supports
+// syncing a directory, so failures are ignored.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// apply changes tasks and nextID as op describes
+func apply(tasks map[int]Task, nextID *int, op Op) {
+	switch op.Type {
+	case OpAdd, OpUpdate:
+		tasks[op.Task.ID] = *op.Task
+		if op.Task.ID >= *nextID {
+			*nextID = op.Task.ID + 1
+		}
+	case OpDelete:
+		delete(tasks, op.ID)
+	}
+}
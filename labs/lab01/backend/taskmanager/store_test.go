@@ -0,0 +1,255 @@
+package taskmanager
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// reopen closes tm and restores it from path
+func reopen(t *testing.T, tm *TaskManager, path string) *TaskManager {
+	t.Helper()
+	if tm != nil {
+		if err := tm.Close(); err != nil {
+			t.Fatalf("Close() failed: %v", err)
+		}
+	}
+	tm, err := NewTaskManagerFromFile(path)
+	if err != nil {
+		t.Fatalf("NewTaskManagerFromFile() failed: %v", err)
+	}
+	t.Cleanup(func() { tm.Close() })
+	return tm
+}
+
+func TestNewTaskManagerFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", "tasks.json")
+	tm := reopen(t, nil, path)
+	if len(tm.ListTasks(nil)) != 0 || tm.nextID != 1 {
+		t.Fatalf("Expected an empty manager, got %d tasks and nextID %d", len(tm.ListTasks(nil)), tm.nextID)
+	}
+
+	first, _ := tm.AddTask("Task 1", "Description 1")
+	second, _ := tm.AddTask("Task 2", "Description 2")
+	third, _ := tm.AddTask("Task 3", "Description 3")
+	tm.UpdateTask(second.ID, "Task 2 updated", "", true,
+		WithDueDate(time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)), WithPriority(PriorityHigh), WithTags("Home"))
+	tm.DeleteTask(first.ID)
+	// Deleting the newest task must not let its ID be handed out again
+	tm.DeleteTask(third.ID)
+	want := tm.ListTasks(nil)
+
+	tm = reopen(t, tm, path)
+	if got := tm.ListTasks(nil); !sameTasks(got, want) {
+		t.Errorf("Expected tasks %+v, got %+v", want, got)
+	}
+	if tm.nextID != 4 {
+		t.Errorf("Expected nextID to be 4, got %d", tm.nextID)
+	}
+	task, err := tm.AddTask("Task 4", "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if task.ID != 4 {
+		t.Errorf("Expected task ID 4, got %d", task.ID)
+	}
+}
+
+func TestReplayLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		log     string
+		tasks   []string
+		nextID  int
+		wantErr bool
+	}{
+		{
+			name:   "operations after the snapshot",
+			log:    `{"seq":3,"type":"add","task":{"id":3,"title":"C"}}` + "\n" + `{"seq":4,"type":"delete","id":1}` + "\n",
+			tasks:  []string{"B", "C"},
+			nextID: 4,
+		},
+		{
+			name:   "operations already in the snapshot are skipped",
+			log:    `{"seq":2,"type":"delete","id":2}` + "\n" + `{"seq":3,"type":"update","task":{"id":2,"title":"B2"}}` + "\n",
+			tasks:  []string{"A", "B2"},
+			nextID: 3,
+		},
+		{
+			name:   "torn last line is dropped",
+			log:    `{"seq":3,"type":"add","task":{"id":5,"title":"E"}}` + "\n" + `{"seq":4,"type":"del`,
+			tasks:  []string{"A", "B", "E"},
+			nextID: 6,
+		},
+		{
+			name:   "change of several operations",
+			log:    `[{"seq":3,"batch":3,"type":"update","task":{"id":1,"title":"A2"}},{"seq":4,"batch":3,"type":"add","task":{"id":3,"title":"C"}}]` + "\n",
+			tasks:  []string{"A2", "B", "C"},
+			nextID: 4,
+		},
+		{
+			name:   "torn change is dropped as a whole",
+			log:    `[{"seq":3,"batch":3,"type":"update","task":{"id":1,"title":"A2"}},{"seq":4,"batch":3,"type":"add","ta`,
+			tasks:  []string{"A", "B"},
+			nextID: 3,
+		},
+		{
+			name:    "corrupt line in the middle",
+			log:     `{"seq":3,"type":` + "\n" + `{"seq":4,"type":"delete","id":1}` + "\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "tasks.json")
+			snapshot := `{"seq":2,"next_id":3,"tasks":[{"id":1,"title":"A"},{"id":2,"title":"B"}]}`
+			if err := os.WriteFile(path, []byte(snapshot), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path+".log", []byte(tt.log), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			tm, err := NewTaskManagerFromFile(path)
+			if tt.wantErr {
+				if err == nil {
+					tm.Close()
+					t.Error("Expected error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer tm.Close()
+
+			var titles []string
+			for _, task := range tm.ListTasks(nil) {
+				titles = append(titles, task.Title)
+			}
+			if !reflect.DeepEqual(titles, tt.tasks) {
+				t.Errorf("Expected tasks %v, got %v", tt.tasks, titles)
+			}
+			if tm.nextID != tt.nextID {
+				t.Errorf("Expected nextID to be %d, got %d", tt.nextID, tm.nextID)
+			}
+		})
+	}
+}
+
+func TestRecoverWithoutSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	tm := reopen(t, nil, path)
+	tm.AddTask("Task 1", "")
+	tm.AddTask("Task 2", "")
+	tm.DeleteTask(2)
+
+	// A crash leaves only what was appended to the log since opening
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	state, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(state.Tasks) != 1 || state.Tasks[0].Title != "Task 1" {
+		t.Errorf("Expected only Task 1, got %+v", state.Tasks)
+	}
+	if state.NextID != 3 {
+		t.Errorf("Expected nextID to be 3, got %d", state.NextID)
+	}
+	if state.Seq != 3 {
+		t.Errorf("Expected seq 3, got %d", state.Seq)
+	}
+}
+
+func TestCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	tm := reopen(t, nil, path)
+	for i := 0; i < compactEvery+10; i++ {
+		if _, err := tm.AddTask("Task", ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	b, err := os.ReadFile(path + ".log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(b), "\n"); lines != 10 {
+		t.Errorf("Expected 10 operations in the log after compaction, got %d", lines)
+	}
+	tm = reopen(t, tm, path)
+	if got := len(tm.ListTasks(nil)); got != compactEvery+10 {
+		t.Errorf("Expected %d tasks, got %d", compactEvery+10, got)
+	}
+}
+
+// failingStore keeps tasks in memory and fails appends once fail is set
+type failingStore struct {
+	fail bool
+}
+
+func (s *failingStore) Load() (State, error) { return State{NextID: 1}, nil }
+
+func (s *failingStore) Append(ops ...Op) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+	return nil
+}
+
+func (s *failingStore) Snapshot(State) error { return nil }
+func (s *failingStore) Close() error         { return nil }
+
+func TestFailedAppendChangesNothing(t *testing.T) {
+	store := &failingStore{}
+	tm, err := NewTaskManagerWithStore(store)
+	if err != nil {
+		t.Fatalf("NewTaskManagerWithStore() failed: %v", err)
+	}
+	start := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	task, _ := tm.AddTask("Water plants", "", WithRecurrence(Rule{Freq: Daily, Interval: 1}, start))
+
+	// Completing the occurrence also adds the next one; both or neither
+	store.fail = true
+	if err := tm.UpdateTask(task.ID, task.Title, "", true); err == nil {
+		t.Fatal("Expected UpdateTask() to fail")
+	}
+	got, _ := tm.GetTask(task.ID)
+	if got.Done || got.Recurrence.NextID != 0 {
+		t.Errorf("Expected the task to stay open and unlinked, got done=%v next=%d", got.Done, got.Recurrence.NextID)
+	}
+	if n := len(tm.ListTasks(nil)); n != 1 || tm.nextID != 2 {
+		t.Errorf("Expected 1 task and nextID 2, got %d tasks and nextID %d", n, tm.nextID)
+	}
+	if ops, _ := tm.History(task.ID); len(ops) != 1 {
+		t.Errorf("Expected only the add in the history, got %d ops", len(ops))
+	}
+
+	store.fail = false
+	if err := tm.UpdateTask(task.ID, task.Title, "", true); err != nil {
+		t.Fatalf("UpdateTask() failed: %v", err)
+	}
+	got, _ = tm.GetTask(task.ID)
+	if next, err := tm.GetTask(got.Recurrence.NextID); err != nil || next.ID != 2 {
+		t.Errorf("Expected the next occurrence to be task 2, got %+v, %v", next, err)
+	}
+}
+
+// sameTasks compares tasks by their JSON encoding, which is what a store
+// keeps of them
+func sameTasks(a, b []Task) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return bytes.Equal(x, y)
+}
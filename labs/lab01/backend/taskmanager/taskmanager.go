@@ -1,29 +1,176 @@
 package taskmanager
 
 import (
+	"cmp"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
 // Predefined errors
 var (
-	ErrTaskNotFound = errors.New("task not found")
-	ErrEmptyTitle   = errors.New("title cannot be empty")
+	ErrTaskNotFound    = errors.New("task not found")
+	ErrEmptyTitle      = errors.New("title cannot be empty")
+	ErrInvalidPriority = errors.New("invalid priority")
 )
 
+// compactEvery is how many operations are logged before the log is folded
+// into a new snapshot
+const compactEvery = 1000
+
+// maxHistory is how many of the latest operations the history keeps. It
+// bounds the snapshot and how far Undo and At reach back.
+const maxHistory = 1000
+
 type Task struct {
-	ID          int
-	Title       string
-	Description string
-	Done        bool
-	CreatedAt   time.Time
+	ID          int         `json:"id"`
+	Title       string      `json:"title"`
+	Description string      `json:"description"`
+	Done        bool        `json:"done"`
+	DueDate     *time.Time  `json:"due_date,omitempty"`
+	Priority    Priority    `json:"priority"`
+	Tags        []string    `json:"tags,omitempty"`
+	Recurrence  *Recurrence `json:"recurrence,omitempty"`
+	ParentID    int         `json:"parent_id,omitempty"`
+	BlockedBy   []int       `json:"blocked_by,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// Overdue reports whether the task is open and was due before now
+func (t Task) Overdue(now time.Time) bool {
+	return !t.Done && t.DueDate != nil && t.DueDate.Before(now)
+}
+
+// clone returns a copy of t sharing no memory with it. Tasks are handed out
+// as clones so callers cannot change the stored ones behind the lock.
+func (t Task) clone() Task {
+	if t.DueDate != nil {
+		due := *t.DueDate
+		t.DueDate = &due
+	}
+	t.Tags = slices.Clone(t.Tags)
+	t.BlockedBy = slices.Clone(t.BlockedBy)
+	if t.Recurrence != nil {
+		rec := *t.Recurrence
+		rec.Rule = rec.Rule.clone()
+		t.Recurrence = &rec
+	}
+	return t
+}
+
+// HasTag reports whether the task is tagged with tag, ignoring case
+func (t Task) HasTag(tag string) bool {
+	return slices.Contains(t.Tags, normalizeTag(tag))
+}
+
+// Priority ranks tasks, higher is more important. The zero value means no
+// priority was set.
+type Priority int
+
+const (
+	PriorityNone Priority = iota
+	PriorityLow
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var priorityNames = []string{"none", "low", "medium", "high", "urgent"}
+
+func (p Priority) String() string {
+	if p.Valid() {
+		return priorityNames[p]
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
 }
 
+// Valid reports whether p is one of the defined priorities
+func (p Priority) Valid() bool {
+	return p >= PriorityNone && p <= PriorityUrgent
+}
+
+// ParsePriority returns the priority with the given name
+func ParsePriority(s string) (Priority, error) {
+	if i := slices.Index(priorityNames, strings.ToLower(strings.TrimSpace(s))); i >= 0 {
+		return Priority(i), nil
+	}
+	return PriorityNone, fmt.Errorf("%w: %q", ErrInvalidPriority, s)
+}
+
+// MarshalText encodes p by name, so JSON holds "high" rather than 3
+func (p Priority) MarshalText() ([]byte, error) {
+	if !p.Valid() {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPriority, int(p))
+	}
+	return []byte(p.String()), nil
+}
+
+func (p *Priority) UnmarshalText(b []byte) error {
+	v, err := ParsePriority(string(b))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// TaskOption sets an optional field when adding or updating a task
+type TaskOption func(*Task) error
+
+// WithDueDate sets when the task is due. The zero time removes the due date.
+func WithDueDate(due time.Time) TaskOption {
+	return func(t *Task) error {
+		if due.IsZero() {
+			t.DueDate = nil
+		} else {
+			t.DueDate = &due
+		}
+		return nil
+	}
+}
+
+// WithPriority sets the priority of the task
+func WithPriority(p Priority) TaskOption {
+	return func(t *Task) error {
+		if !p.Valid() {
+			return fmt.Errorf("%w: %d", ErrInvalidPriority, int(p))
+		}
+		t.Priority = p
+		return nil
+	}
+}
+
+// WithTags replaces the tags of the task. Tags are lowercased, trimmed and
+// deduplicated; empty ones are dropped.
+func WithTags(tags ...string) TaskOption {
+	return func(t *Task) error {
+		t.Tags = normalizeTags(tags)
+		return nil
+	}
+}
+
+// TaskManager is safe for concurrent use. With a store every change is
+// persisted before it becomes visible. Every change is also kept as an
+// event, see History, Undo and At.
 type TaskManager struct {
-	tasks  map[int]Task
-	nextID int
+	mu      sync.RWMutex
+	tasks   map[int]Task
+	nextID  int
+	store   Store
+	seq     uint64
+	pending int
+
+	history []Op
+	// undo and redo hold the batches Undo and Redo revert next, last on top
+	undo []uint64
+	redo []uint64
 }
 
+// NewTaskManager returns a task manager that keeps its tasks in memory only
 func NewTaskManager() *TaskManager {
 	return &TaskManager{
 		tasks:  make(map[int]Task),
@@ -31,59 +178,270 @@ func NewTaskManager() *TaskManager {
 	}
 }
 
-func (tm *TaskManager) AddTask(title, description string) (Task, error) {
+// NewTaskManagerFromFile restores the tasks saved at path, see FileStore
+func NewTaskManagerFromFile(path string) (*TaskManager, error) {
+	store, err := OpenFileStore(path)
+	if err != nil {
+		return nil, err
+	}
+	tm, err := NewTaskManagerWithStore(store)
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return tm, nil
+}
+
+// NewTaskManagerWithStore restores the tasks kept by store and persists
+// every later change to it
+func NewTaskManagerWithStore(store Store) (*TaskManager, error) {
+	state, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	tm := &TaskManager{
+		tasks:   make(map[int]Task, len(state.Tasks)),
+		nextID:  max(state.NextID, 1),
+		store:   store,
+		seq:     state.Seq,
+		history: state.History,
+	}
+	for _, t := range state.Tasks {
+		tm.tasks[t.ID] = t
+	}
+	tm.rebuildStacks()
+	tm.trimHistory()
+	// Start from a compact snapshot so the log only holds this run's changes
+	if err := tm.Snapshot(); err != nil {
+		return nil, err
+	}
+	return tm, nil
+}
+
+// AddTask adds an open task. Options set the due date, priority and tags.
+func (tm *TaskManager) AddTask(title, description string, opts ...TaskOption) (Task, error) {
 	if title == "" {
 		return Task{}, ErrEmptyTitle
 	}
+	now := time.Now()
 	task := Task{
-		ID:          tm.nextID,
 		Title:       title,
 		Description: description,
 		Done:        false,
-		CreatedAt:   time.Now(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := applyOptions(&task, opts); err != nil {
+		return Task{}, err
 	}
-	tm.tasks[task.ID] = task
-	tm.nextID++
-	return task, nil
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	task.ID = tm.nextID
+	if err := tm.commit(Op{Type: OpAdd, Task: &task}); err != nil {
+		return Task{}, err
+	}
+	return task.clone(), nil
 }
 
-func (tm *TaskManager) UpdateTask(id int, title, description string, done bool) error {
+// UpdateTask replaces the title, description and status of a task. Fields
+// set by options are changed too, the others are kept. A task cannot be
+// marked done while it has open blockers. Marking an occurrence of a
+// recurring task done adds the next occurrence, see Recurrence.
+func (tm *TaskManager) UpdateTask(id int, title, description string, done bool, opts ...TaskOption) error {
 	if title == "" {
 		return ErrEmptyTitle
 	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 	t, exists := tm.tasks[id]
 	if !exists {
 		return ErrTaskNotFound
 	}
+	wasDone := t.Done
+	if done && !wasDone {
+		if open := tm.openBlockers(t); len(open) > 0 {
+			return fmt.Errorf("%w: %v", ErrBlocked, open)
+		}
+	}
 	t.Title = title
 	t.Description = description
 	t.Done = done
-	tm.tasks[id] = t
-	return nil
+	if err := applyOptions(&t, opts); err != nil {
+		return err
+	}
+	t.UpdatedAt = time.Now()
+
+	var next Task
+	spawn := done && !wasDone && t.Recurrence != nil && t.Recurrence.NextID == 0
+	if spawn {
+		next, spawn = tm.spawnNext(&t, t.UpdatedAt)
+	}
+	ops := []Op{{Type: OpUpdate, Task: &t}}
+	if spawn {
+		ops = append(ops, Op{Type: OpAdd, Task: &next})
+	}
+	return tm.commit(ops...)
 }
 
 func (tm *TaskManager) DeleteTask(id int) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
 	if _, exists := tm.tasks[id]; !exists {
 		return ErrTaskNotFound
 	}
-	delete(tm.tasks, id)
-	return nil
+	return tm.commit(Op{Type: OpDelete, ID: id})
 }
 
 func (tm *TaskManager) GetTask(id int) (Task, error) {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	t, exists := tm.tasks[id]
 	if !exists {
 		return Task{}, ErrTaskNotFound
 	}
-	return t, nil
+	return t.clone(), nil
 }
 
+// ListTasks returns the tasks ordered by ID, only those with the given
+// status when filterDone is set
 func (tm *TaskManager) ListTasks(filterDone *bool) []Task {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
 	result := make([]Task, 0, len(tm.tasks))
 	for _, t := range tm.tasks {
 		if filterDone == nil || t.Done == *filterDone {
-			result = append(result, t)
+			result = append(result, t.clone())
+		}
+	}
+	slices.SortFunc(result, func(a, b Task) int { return cmp.Compare(a.ID, b.ID) })
+	return result
+}
+
+// Snapshot saves every task to the store and empties its log. Without a
+// store it does nothing.
+func (tm *TaskManager) Snapshot() error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	return tm.snapshot()
+}
+
+// Close saves a final snapshot and closes the store
+func (tm *TaskManager) Close() error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if tm.store == nil {
+		return nil
+	}
+	err := tm.snapshot()
+	if cerr := tm.store.Close(); err == nil {
+		err = cerr
+	}
+	tm.store = nil
+	return err
+}
+
+// commit persists ops as one change, which Undo reverts as a whole, and
+// applies them. The caller holds tm.mu.
+func (tm *TaskManager) commit(ops ...Op) error {
+	batch, err := tm.record(ops, 0)
+	if batch != 0 {
+		tm.undo = append(tm.undo, batch)
+		tm.redo = nil
+	}
+	return err
+}
+
+// record persists ops as one batch reverting the batch reverts, if any,
+// then applies them and adds them to the history. Nothing is applied unless
+// the whole batch was persisted. It returns the batch, or 0 when nothing
+// was recorded. The caller holds tm.mu.
+func (tm *TaskManager) record(ops []Op, reverts uint64) (uint64, error) {
+	batch := tm.seq + 1
+	now := time.Now()
+	// Each op keeps the task as the ops before it in the batch leave it
+	staged := map[int]Task{}
+	for i := range ops {
+		op := &ops[i]
+		op.Seq, op.Time, op.Batch, op.Reverts = batch+uint64(i), now, batch, reverts
+		if op.Type != OpAdd {
+			before, ok := staged[op.taskID()]
+			if !ok {
+				before = tm.tasks[op.taskID()]
+			}
+			op.Before = &before
 		}
+		if op.Task != nil {
+			staged[op.Task.ID] = *op.Task
+		} else {
+			staged[op.ID] = Task{}
+		}
+	}
+	if tm.store != nil {
+		if err := tm.store.Append(ops...); err != nil {
+			return 0, fmt.Errorf("taskmanager: %s task: %w", ops[0].Type, err)
+		}
+	}
+	for _, op := range ops {
+		tm.seq = op.Seq
+		apply(tm.tasks, &tm.nextID, op)
+		tm.history = append(tm.history, op)
+		tm.pending++
 	}
+	tm.trimHistory()
+
+	if tm.store != nil && tm.pending >= compactEvery {
+		// The changes are already in the log, so a failed compaction loses
+		// nothing and is retried after the next change
+		tm.snapshot()
+	}
+	return batch, nil
+}
+
+// snapshot is Snapshot for a caller holding tm.mu
+func (tm *TaskManager) snapshot() error {
+	if tm.store == nil {
+		return nil
+	}
+	err := tm.store.Snapshot(State{Seq: tm.seq, NextID: tm.nextID, Tasks: sortedTasks(tm.tasks), History: tm.history})
+	if err != nil {
+		return err
+	}
+	tm.pending = 0
+	return nil
+}
+
+// applyOptions applies opts to t in order
+func applyOptions(t *Task, opts []TaskOption) error {
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeTags lowercases, trims, deduplicates and sorts tags
+func normalizeTags(tags []string) []string {
+	var result []string
+	for _, tag := range tags {
+		if tag = normalizeTag(tag); tag != "" && !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+	slices.Sort(result)
 	return result
 }
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// sortedTasks returns the tasks of m ordered by ID
+func sortedTasks(m map[int]Task) []Task {
+	tasks := make([]Task, 0, len(m))
+	for _, t := range m {
+		tasks = append(tasks, t)
+	}
+	slices.SortFunc(tasks, func(a, b Task) int { return cmp.Compare(a.ID, b.ID) })
+	return tasks
+}
@@ -1,7 +1,9 @@
 package taskmanager
 
 import (
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestNewTaskManager(t *testing.T) {
@@ -304,3 +306,92 @@ func TestListTasks(t *testing.T) {
 		})
 	}
 }
+
+func TestConcurrentUse(t *testing.T) {
+	tm := NewTaskManager()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				task, err := tm.AddTask("Task", "Description")
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+					return
+				}
+				tm.UpdateTask(task.ID, task.Title, task.Description, true)
+				tm.ListTasks(nil)
+				if j%2 == 0 {
+					tm.DeleteTask(task.ID)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := len(tm.ListTasks(nil)); got != 200 {
+		t.Errorf("Expected 200 tasks, got %d", got)
+	}
+	if tm.nextID != 401 {
+		t.Errorf("Expected nextID to be 401, got %d", tm.nextID)
+	}
+}
+
+func TestReturnedTasksAreCopies(t *testing.T) {
+	tm := NewTaskManager()
+	due := time.Date(2025, 7, 1, 9, 0, 0, 0, time.UTC)
+	blocker, _ := tm.AddTask("Blocker", "")
+	added, err := tm.AddTask("Task", "", WithDueDate(due), WithTags("home"),
+		WithRecurrence(Rule{Freq: Weekly, Interval: 1, ByDay: []WeekdayNum{{Day: time.Tuesday}}}, due))
+	if err != nil {
+		t.Fatalf("AddTask() failed: %v", err)
+	}
+	if err := tm.AddBlocker(added.ID, blocker.ID); err != nil {
+		t.Fatalf("AddBlocker() failed: %v", err)
+	}
+
+	scribble := func(t Task) {
+		if t.DueDate != nil {
+			*t.DueDate = time.Time{}
+		}
+		if len(t.Tags) > 0 {
+			t.Tags[0] = "changed"
+		}
+		if len(t.BlockedBy) > 0 {
+			t.BlockedBy[0] = 42
+		}
+		if t.Recurrence != nil {
+			t.Recurrence.Rule.ByDay[0].Day = time.Sunday
+		}
+	}
+	scribble(added)
+	got, _ := tm.GetTask(added.ID)
+	scribble(got)
+	for _, t := range tm.ListTasks(nil) {
+		scribble(t)
+	}
+	found, _, _ := tm.Find(Query{})
+	for _, t := range found {
+		scribble(t)
+	}
+	for _, t := range tm.Next() {
+		scribble(t)
+	}
+	history, _ := tm.History(added.ID)
+	for _, op := range history {
+		scribble(*op.Task)
+		if op.Before != nil {
+			scribble(*op.Before)
+		}
+	}
+
+	got, _ = tm.GetTask(added.ID)
+	if !got.DueDate.Equal(due) || got.Tags[0] != "home" || got.BlockedBy[0] != blocker.ID || got.Recurrence.Rule.ByDay[0].Day != time.Tuesday {
+		t.Errorf("Expected the stored task to be unchanged, got %+v", got)
+	}
+	history, _ = tm.History(added.ID)
+	if last := history[len(history)-1]; last.Before.Tags[0] != "home" {
+		t.Errorf("Expected the history to be unchanged, got %+v", last.Before)
+	}
+}